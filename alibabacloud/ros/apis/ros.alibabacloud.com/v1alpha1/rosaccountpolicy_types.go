@@ -0,0 +1,39 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// RosAccountPolicy allows applications in namespaces to deploy to Alibaba Cloud accounts and regions
+type RosAccountPolicy struct {
+	v1.TypeMeta   `json:",inline"`
+	v1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RosAccountPolicySpec `json:"spec,omitempty"`
+}
+
+// RosAccountPolicySpec defines namespaces and the accounts and regions allowed for them
+type RosAccountPolicySpec struct {
+	// Namespaces of applications, * matches any namespace
+	Namespaces []string `json:"namespaces"`
+	// AliUids are allowed account uids, * allows any account
+	AliUids []string `json:"aliyunAccountUids"`
+	// RegionIds are allowed regions, empty allows any region
+	RegionIds []string `json:"regionIds,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+type RosAccountPolicyList struct {
+	v1.TypeMeta `json:",inline"`
+	v1.ListMeta `json:"metadata,omitempty"`
+	Items       []RosAccountPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RosAccountPolicy{}, &RosAccountPolicyList{})
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"os"
+	"time"
 
 	rosapi "github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	roscrd "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/handlers"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/policy"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/region"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/ros"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/webhook"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"github.com/oam-dev/oam-go-sdk/pkg/oam"
@@ -18,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,18 +69,74 @@ func main() {
 	flag.BoolVar(&workAsRosCrd, "ros-crd", false, "Whether this controller work as ROS or OAM CRD.")
 	var serviceUserAgent string
 	flag.StringVar(&serviceUserAgent, "service-user-agent", "", "Current service/application name which will be set to User-Agent for identification.")
+	var enableWebhook bool
+	flag.BoolVar(&enableWebhook, "enable-webhook", false, "Whether serve validating admission webhook.")
+	var webhookPort int
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the validating admission webhook binds to.")
+	var webhookCertDir string
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory that contains the webhook server key and certificate.")
+	var maxRevisions int
+	flag.IntVar(&maxRevisions, "max-revisions", 10, "Max number of revisions kept in history of each application.")
+	var rosApiMaxRetries int
+	flag.IntVar(&rosApiMaxRetries, "ros-api-max-retries", 5, "Max retries of ROS API calls on throttling and transient server errors.")
+	var rosApiQps float64
+	flag.Float64Var(&rosApiQps, "ros-api-qps", 10, "Max QPS of ROS API calls per account. Zero means no limit.")
+	var rosApiBurst int
+	flag.IntVar(&rosApiBurst, "ros-api-burst", 20, "Max burst of ROS API calls per account.")
+	var credentialType string
+	flag.StringVar(&credentialType, "credential-type", "", "Credential type: access_key, sts, ram_role_arn, ecs_ram_role or oidc_role_arn. Empty means access_key, or sts if credential secret has security token.")
+	var roleArn string
+	flag.StringVar(&roleArn, "role-arn", "", "RAM role to assume by ram_role_arn and oidc_role_arn credential types.")
+	var roleSessionName string
+	flag.StringVar(&roleSessionName, "role-session-name", "ros-oam-controller", "Session name of assumed RAM role.")
+	var rolePolicy string
+	flag.StringVar(&rolePolicy, "role-policy", "", "Policy to further restrict permissions of assumed RAM role.")
+	var ecsRamRoleName string
+	flag.StringVar(&ecsRamRoleName, "ecs-ram-role-name", "", "RAM role of ECS instance used by ecs_ram_role credential type.")
+	var oidcProviderArn string
+	flag.StringVar(&oidcProviderArn, "oidc-provider-arn", "", "OIDC provider used by oidc_role_arn credential type.")
+	var oidcTokenFile string
+	flag.StringVar(&oidcTokenFile, "oidc-token-file", "", "OIDC token file used by oidc_role_arn credential type.")
+	var credentialLookupPolicy string
+	flag.StringVar(&credentialLookupPolicy, "credential-lookup-policy", "controller", "Default namespace of credential secrets of resource identity scopes: controller or application.")
+	var credentialSecretNamespaces string
+	flag.StringVar(&credentialSecretNamespaces, "credential-secret-namespaces", "", "Comma separated namespaces allowed as secretNamespace of resource identity scopes. * allows any namespace.")
+	var credentialSecretNames string
+	flag.StringVar(&credentialSecretNames, "credential-secret-names", "", "Comma separated names of credential secrets allowed as secretName of resource identity scopes and their targets, with * as wildcard. Empty allows none. The credential secret of controller is never allowed.")
+	var scopeCredentialTypes string
+	flag.StringVar(&scopeCredentialTypes, "scope-credential-types", "", "Comma separated credential types allowed in resource identity scopes. Empty allows access_key, sts and ram_role_arn, whose credentials are read from secrets.")
+	var enforceAccountPolicy bool
+	flag.BoolVar(&enforceAccountPolicy, "enforce-account-policy", false, "Whether only deploy to accounts and regions allowed for namespaces of applications by RosAccountPolicy.")
+	var controllerAliUid string
+	flag.StringVar(&controllerAliUid, "controller-account-uid", "", "Account uid of credential of controller, which account policies check for applications without resource identity scope.")
+	var regionsRefreshInterval time.Duration
+	flag.DurationVar(&regionsRefreshInterval, "regions-refresh-interval", time.Hour, "Interval of reloading regions supported by ROS. Zero means never reload.")
+	var templateStore string
+	flag.StringVar(&templateStore, "template-store", "", "Where template bodies larger than --template-url-threshold are stored: oss or ros. Empty means template bodies are always passed inline.")
+	var templateUrlThreshold int
+	flag.IntVar(&templateUrlThreshold, "template-url-threshold", 16384, "Size in bytes of template bodies above which they are stored by --template-store and passed by url or template id.")
+	var templateOssBucket string
+	flag.StringVar(&templateOssBucket, "template-oss-bucket", "", "OSS bucket of oss template store.")
+	var templateOssEndpoint string
+	flag.StringVar(&templateOssEndpoint, "template-oss-endpoint", "", "OSS endpoint of oss template store, e.g. https://oss-cn-hangzhou.aliyuncs.com.")
+	var templateOssRegionId string
+	flag.StringVar(&templateOssRegionId, "template-oss-region-id", "", "Region of OSS bucket of oss template store. Empty means the bucket is in regions of stacks.")
 	flag.Parse()
 
 	// init controller conf
 	config.InitRosCtrlConf(
 		env, endpoint, regionId, accessKeyId, accessKeySecret,
 		credentialSecretName, leaderElectionNamespace, namespace,
-		updateApp, serviceUserAgent, dryRun, workAsRosCrd)
+		updateApp, serviceUserAgent, dryRun, workAsRosCrd, enableWebhook, maxRevisions,
+		rosApiMaxRetries, rosApiQps, rosApiBurst,
+		credentialType, roleArn, roleSessionName, rolePolicy, ecsRamRoleName, oidcProviderArn, oidcTokenFile,
+		credentialLookupPolicy, credentialSecretNamespaces, credentialSecretNames, scopeCredentialTypes, enforceAccountPolicy, controllerAliUid, regionsRefreshInterval,
+		templateStore, templateUrlThreshold, templateOssBucket, templateOssEndpoint, templateOssRegionId)
 
 	// init log
 	logging.Init()
 	logging.SetUp.Info("ROS OAM controller stating")
-	logging.SetUp.Info("Init ros-oam controller conf", "RosCtrlConf", config.RosCtrlConf)
+	logging.SetUp.Info("Init ros-oam controller conf", "RosCtrlConf", config.RosCtrlConf.Redacted())
 	if dryRun {
 		logging.SetUp.Info("============================================================")
 		logging.SetUp.Info("||                          Notice:                       ||")
@@ -81,6 +145,11 @@ func main() {
 		logging.SetUp.Info("||   DRY RUN MODE is only recommended in develop or test! ||")
 		logging.SetUp.Info("============================================================")
 	}
+	if config.RosCtrlConf.CredentialLookupPolicy != config.CREDENTIAL_LOOKUP_CONTROLLER &&
+		config.RosCtrlConf.CredentialLookupPolicy != config.CREDENTIAL_LOOKUP_APPLICATION {
+		logging.SetUp.Error(errors.New("unknown credential lookup policy"), "Invalid flag", "CredentialLookupPolicy", config.RosCtrlConf.CredentialLookupPolicy)
+		os.Exit(1)
+	}
 	// init k8s client
 	if err := k8s.Init(); err != nil {
 		logging.SetUp.Error(err, "Problem occurs during stating ros controller")
@@ -96,6 +165,8 @@ func main() {
 		LeaderElectionID:        config.RosCtrlConf.LeaderLockName,
 		LeaderElectionNamespace: config.RosCtrlConf.LeaderElectionNamespace,
 		Namespace:               config.RosCtrlConf.Namespace,
+		Port:                    webhookPort,
+		CertDir:                 webhookCertDir,
 	}
 	oam.InitMgr(ctrl.GetConfigOrDie(), options)
 	logging.SetUp.Info("Controller manager success initialized")
@@ -128,6 +199,74 @@ func main() {
 	}
 	logging.SetUp.Info("Add hooks and handlers success")
 
+	// regions supported by ROS are reloaded until controller exits. Region ids are not validated until regions
+	// are loaded, e.g. if dry run or controller has no credential of its own.
+	if !config.RosCtrlConf.DryRun {
+		controllerContext, err := appconf.NewControllerContext()
+		if err == nil {
+			err = region.Start(controllerContext.RosClient, config.RosCtrlConf.RegionsRefreshInterval, make(chan struct{}))
+		}
+		if err != nil {
+			logging.SetUp.Error(err, "Load regions err, region ids are not validated until regions are loaded")
+		} else if err := region.Validate(config.RosCtrlConf.RegionId); err != nil {
+			logging.SetUp.Error(err, "Invalid flag", "RegionId", config.RosCtrlConf.RegionId)
+			os.Exit(1)
+		}
+	}
+
+	// large template bodies are stored by template store, which is not used in dry run
+	if !config.RosCtrlConf.DryRun {
+		store, err := ros.NewTemplateStore(config.RosCtrlConf, appconf.ControllerCredential)
+		if err != nil {
+			logging.SetUp.Error(err, "Invalid flag", "TemplateStore", config.RosCtrlConf.TemplateStore)
+			os.Exit(1)
+		}
+		ros.SetTemplateStore(store)
+	}
+
+	if config.RosCtrlConf.EnableWebhook {
+		oam.GetMgr().GetWebhookServer().Register(webhook.ValidatePath, &ctrlwebhook.Admission{Handler: webhook.NewValidator()})
+		oam.GetMgr().GetWebhookServer().Register(region.Path, region.Handler())
+		logging.SetUp.Info("Validating admission webhook registered", "Path", webhook.ValidatePath, "Port", webhookPort)
+	}
+
+	// invalidate cached ROS clients and requeue applications when credential secrets change
+	var watcherOpts []k8s.SecretOption
+	if config.RosCtrlConf.CrossNamespaceSecrets() {
+		watcherOpts = append(watcherOpts, k8s.WithAllNamespaces())
+	}
+	secretWatcher := k8s.NewSecretWatcher(watcherOpts...)
+	secretWatcher.OnChange(appconf.InvalidateRosClients)
+	secretWatcher.OnChange(handlers.RequeueAppsOfSecret(oamCrdClient, rosCrdClient))
+	if err := oam.GetMgr().Add(secretWatcher); err != nil {
+		logging.SetUp.Error(err, "Add secret watcher err")
+		os.Exit(1)
+	}
+
+	// account policies are watched until controller exits
+	if config.RosCtrlConf.EnforceAccountPolicy {
+		policyClient, err := roscrd.NewForConfig(ctrl.GetConfigOrDie())
+		if err != nil {
+			logging.SetUp.Error(err, "Create ros runtime client err")
+			os.Exit(1)
+		}
+		if err := policy.Start(policyClient, make(chan struct{})); err != nil {
+			logging.SetUp.Error(err, "Start account policies err")
+			os.Exit(1)
+		}
+	}
+
+	// app stacks of legacy names are adopted only by applications of their recorded namespaces
+	if err := handlers.LoadLegacyAppStacks(oamCrdClient, rosCrdClient); err != nil {
+		logging.SetUp.Error(err, "Load legacy app stacks err")
+		os.Exit(1)
+	}
+
+	// changes of credential secrets are known before applications are reconciled
+	if err := handlers.LoadCredentialSecrets(oamCrdClient, rosCrdClient); err != nil {
+		logging.SetUp.Error(err, "Load credential secrets err, they are known once their applications are reconciled")
+	}
+
 	handlers.RecoverProgressingAppStacks(oamCrdClient, rosCrdClient)
 
 	if err := oam.Run(option); err != nil {
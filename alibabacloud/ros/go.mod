@@ -6,9 +6,11 @@ require (
 	github.com/aliyun/alibaba-cloud-sdk-go v1.60.293
 	github.com/golang/mock v1.4.3
 	github.com/oam-dev/oam-go-sdk v0.0.0-20200311031835-ce9ec52bd420
+	github.com/prometheus/client_golang v1.0.0
 	github.com/stretchr/testify v1.4.0
 	github.com/urfave/cli/v2 v2.0.0
 	go.uber.org/zap v1.10.0
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.2.4
 	k8s.io/api v0.17.0
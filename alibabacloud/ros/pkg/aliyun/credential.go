@@ -2,8 +2,10 @@ package aliyun
 
 import (
 	"errors"
+	"fmt"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"strings"
 )
 
 type AliyunCredential struct {
@@ -11,20 +13,94 @@ type AliyunCredential struct {
 	AccessKeySecret string
 	SecurityToken   string
 	Expiration      string
+	// AliUid is account the credential belongs to, empty if secret does not declare it
+	AliUid string
 }
 
 type AliyunResourceIdentity struct {
 	AppName  string `json:"appName"`
 	AliUid   string `json:"aliyunAccountUid"`
 	RegionId string `json:"regionId"`
+
+	// credential secret of the identity, ${appName}.${regionId}.${aliyunAccountUid} by default
+	SecretName string `json:"secretName,omitempty"`
+	// namespace of credential secret, which is decided by credential lookup policy by default
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+
+	// credential provider of the identity, access key of identity secret by default
+	CredentialConfig
+
+	// targets the application is deployed to, one stack per target. Empty means the region of identity only.
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// Target is a region, and optionally an account, which an application is deployed to
+type Target struct {
+	RegionId string `json:"regionId"`
+	// account of target, account of identity by default
+	AliUid string `json:"aliyunAccountUid,omitempty"`
+	// credential secret of target, ${appName}.${regionId}.${aliyunAccountUid} of target by default
+	SecretName string `json:"secretName,omitempty"`
+	// Parameters override properties of component instances, e.g. {"Vpc": {"CidrBlock": "10.0.0.0/8"}}
+	Parameters map[string]map[string]interface{} `json:"parameters,omitempty"`
 }
 
 func (ari *AliyunResourceIdentity) IdentityAsKey() string {
 	return ari.AppName + "." + ari.RegionId + "." + ari.AliUid
 }
 
-func ReadCredentialFromSecretName(secretName string) (credential *AliyunCredential, err error) {
-	secret := k8s.NewSecret(secretName)
+// CredentialSecretName returns name of credential secret of the identity
+func (ari *AliyunResourceIdentity) CredentialSecretName() string {
+	if ari.SecretName != "" {
+		return strings.ToLower(ari.SecretName)
+	}
+	return strings.ToLower(ari.IdentityAsKey())
+}
+
+// Validate returns an error if required properties of resource identity are missing, or targets are invalid.
+func (ari *AliyunResourceIdentity) Validate() error {
+	var missing []string
+	if ari.AppName == "" {
+		missing = append(missing, "appName")
+	}
+	if ari.AliUid == "" {
+		missing = append(missing, "aliyunAccountUid")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("resource identity scope misses required properties: %s", strings.Join(missing, ", "))
+	}
+
+	seen := make(map[string]bool)
+	for i, target := range ari.Targets {
+		if target.RegionId == "" {
+			return fmt.Errorf("resource identity scope misses required properties: targets[%d].regionId", i)
+		}
+		key := target.RegionId + "/" + ari.ForTarget(target).AliUid
+		if seen[key] {
+			return fmt.Errorf("resource identity scope has duplicated targets of region %s and account %s",
+				target.RegionId, ari.ForTarget(target).AliUid)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ForTarget returns identity of application deployed to target
+func (ari *AliyunResourceIdentity) ForTarget(target Target) *AliyunResourceIdentity {
+	identity := *ari
+	identity.RegionId = target.RegionId
+	identity.Targets = nil
+	if target.AliUid != "" {
+		identity.AliUid = target.AliUid
+	}
+	if target.SecretName != "" {
+		identity.SecretName = target.SecretName
+	}
+	return &identity
+}
+
+func ReadCredentialFromSecretName(secretName string, opts ...k8s.SecretOption) (credential *AliyunCredential, err error) {
+	secret := k8s.NewSecret(secretName, opts...)
 	return ReadCredentialFromSecret(secret)
 }
 
@@ -49,6 +125,7 @@ func ReadCredentialFromSecret(secret k8s.SecretInterface) (credential *AliyunCre
 		AccessKeySecret: credentialSecretData["AccessKeySecret"],
 		SecurityToken:   credentialSecretData["SecurityToken"],
 		Expiration:      credentialSecretData["Expiration"],
+		AliUid:          credentialSecretData["AliyunAccountUid"],
 	}
 	return
 }
@@ -40,6 +40,89 @@ func TestAliyunResourceIdentity_IdentityAsKey(t *testing.T) {
 	}
 }
 
+func TestAliyunResourceIdentity_CredentialSecretName(t *testing.T) {
+	tests := []struct {
+		name       string
+		secretName string
+		want       string
+	}{
+		{
+			name: "TestDefault",
+			want: "myapp.cn-beijing.123456789",
+		},
+		{
+			name:       "TestSecretName",
+			secretName: "MyCredential",
+			want:       "mycredential",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ari := &AliyunResourceIdentity{
+				AppName:    "MyApp",
+				AliUid:     "123456789",
+				RegionId:   "cn-beijing",
+				SecretName: tt.secretName,
+			}
+			assert.Equal(t, tt.want, ari.CredentialSecretName())
+		})
+	}
+}
+
+func TestAliyunResourceIdentity_ForTarget(t *testing.T) {
+	ari := &AliyunResourceIdentity{
+		AppName:  "MyApp",
+		AliUid:   "123456789",
+		RegionId: "cn-hangzhou",
+		Targets:  []Target{{RegionId: "cn-beijing"}},
+	}
+
+	identity := ari.ForTarget(Target{RegionId: "cn-beijing"})
+	assert.Equal(t, "cn-beijing", identity.RegionId)
+	assert.Equal(t, "123456789", identity.AliUid)
+	assert.Equal(t, "myapp.cn-beijing.123456789", identity.CredentialSecretName())
+	assert.Nil(t, identity.Targets)
+
+	identity = ari.ForTarget(Target{RegionId: "cn-beijing", AliUid: "987654321", SecretName: "Other"})
+	assert.Equal(t, "987654321", identity.AliUid)
+	assert.Equal(t, "other", identity.CredentialSecretName())
+	assert.Equal(t, "cn-hangzhou", ari.RegionId)
+}
+
+func TestAliyunResourceIdentity_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []Target
+		wantErr string
+	}{
+		{
+			name:    "TestTargets",
+			targets: []Target{{RegionId: "cn-beijing"}, {RegionId: "cn-beijing", AliUid: "987654321"}},
+		},
+		{
+			name:    "TestTargetWithoutRegion",
+			targets: []Target{{RegionId: "cn-beijing"}, {AliUid: "987654321"}},
+			wantErr: "resource identity scope misses required properties: targets[1].regionId",
+		},
+		{
+			name:    "TestDuplicatedTargets",
+			targets: []Target{{RegionId: "cn-beijing"}, {RegionId: "cn-beijing", AliUid: "123456789"}},
+			wantErr: "resource identity scope has duplicated targets of region cn-beijing and account 123456789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ari := &AliyunResourceIdentity{AppName: "MyApp", AliUid: "123456789", Targets: tt.targets}
+			err := ari.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
 func TestReadCredentialFromSecret(t *testing.T) {
 	type args struct {
 		data map[string]string
@@ -80,6 +163,20 @@ func TestReadCredentialFromSecret(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "TestAliUid",
+			args: args{data: map[string]string{
+				"AccessKeyId":      "id",
+				"AccessKeySecret":  "secret",
+				"AliyunAccountUid": "123",
+			}},
+			wantCredential: &AliyunCredential{
+				AccessKeyId:     "id",
+				AccessKeySecret: "secret",
+				AliUid:          "123",
+			},
+			wantErr: false,
+		},
 		{
 			name: "TestLessThanTwo",
 			args: args{data: map[string]string{
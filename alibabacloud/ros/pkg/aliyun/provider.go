@@ -0,0 +1,415 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/sts"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+)
+
+// credential types
+const (
+	AccessKeyCredential   = "access_key"
+	StsTokenCredential    = "sts"
+	RamRoleArnCredential  = "ram_role_arn"
+	EcsRamRoleCredential  = "ecs_ram_role"
+	OidcRoleArnCredential = "oidc_role_arn"
+)
+
+const (
+	DefaultRoleSessionName = "ros-oam-controller"
+	DefaultStsEndpoint     = "https://sts.aliyuncs.com"
+
+	// stsRegionId is region of STS client, which is a global service reached from any region
+	stsRegionId = "cn-hangzhou"
+)
+
+// ecsMetadataEndpoint is endpoint of metadata service of ECS instances, which serves credentials of RAM roles
+var ecsMetadataEndpoint = "http://100.100.100.200"
+
+// CredentialConfig selects credential provider and configures it. It never holds secret material,
+// access keys and security tokens are read from credential secret.
+type CredentialConfig struct {
+	// Type is one of access_key, sts, ram_role_arn, ecs_ram_role and oidc_role_arn.
+	// Empty type means access_key, or sts if credential secret has security token.
+	Type            string `json:"credentialType,omitempty"`
+	RoleArn         string `json:"roleArn,omitempty"`
+	RoleSessionName string `json:"roleSessionName,omitempty"`
+	Policy          string `json:"policy,omitempty"`
+	RamRoleName     string `json:"ramRoleName,omitempty"`
+	OidcProviderArn string `json:"oidcProviderArn,omitempty"`
+	OidcTokenFile   string `json:"oidcTokenFile,omitempty"`
+}
+
+// NeedsSecret returns true if credential of the type is read from credential secret
+func (c *CredentialConfig) NeedsSecret() bool {
+	return c.Type != EcsRamRoleCredential && c.Type != OidcRoleArnCredential
+}
+
+// CredentialProvider creates ROS API clients authenticated by a kind of credential
+type CredentialProvider interface {
+	// Type returns credential type of provider, which is safe to be logged
+	Type() string
+	// NewRosClient returns ROS API client of region
+	NewRosClient(regionId string) (*rosapi.Client, error)
+	// Expiration returns expiration of credential of the last created client. Zero time means
+	// the credential does not expire, or it is refreshed by the client itself.
+	Expiration() time.Time
+	// Credential returns the current credential of provider, which signs requests of other services, e.g. OSS.
+	// Roles are assumed every time it is called.
+	Credential() (*AliyunCredential, error)
+}
+
+// ParseExpiration parses expiration of STS credential, e.g. 2020-01-01T00:00:00Z.
+// Returns zero time if expiration is empty or invalid.
+func ParseExpiration(expiration string) time.Time {
+	t, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// NewCredentialProvider returns credential provider selected by config. Credential is read from credential
+// secret, and is used by access_key, sts and ram_role_arn types.
+func NewCredentialProvider(conf CredentialConfig, credential *AliyunCredential) (CredentialProvider, error) {
+	credentialType := conf.Type
+	if credentialType == "" {
+		credentialType = AccessKeyCredential
+		if credential != nil && credential.SecurityToken != "" {
+			credentialType = StsTokenCredential
+		}
+	}
+
+	if credential == nil {
+		credential = &AliyunCredential{}
+	}
+	roleSessionName := conf.RoleSessionName
+	if roleSessionName == "" {
+		roleSessionName = DefaultRoleSessionName
+	}
+
+	switch credentialType {
+	case AccessKeyCredential:
+		return &AccessKeyProvider{
+			AccessKeyId:     credential.AccessKeyId,
+			AccessKeySecret: credential.AccessKeySecret,
+		}, nil
+	case StsTokenCredential:
+		if credential.SecurityToken == "" {
+			return nil, errors.New("SecurityToken is required by credential type sts")
+		}
+		return &StsTokenProvider{
+			AccessKeyId:     credential.AccessKeyId,
+			AccessKeySecret: credential.AccessKeySecret,
+			SecurityToken:   credential.SecurityToken,
+			ExpirationTime:  ParseExpiration(credential.Expiration),
+		}, nil
+	case RamRoleArnCredential:
+		if conf.RoleArn == "" || credential.AccessKeyId == "" || credential.AccessKeySecret == "" {
+			return nil, errors.New("roleArn, AccessKeyId and AccessKeySecret are required by credential type ram_role_arn")
+		}
+		return &RamRoleArnProvider{
+			AccessKeyId:     credential.AccessKeyId,
+			AccessKeySecret: credential.AccessKeySecret,
+			RoleArn:         conf.RoleArn,
+			RoleSessionName: roleSessionName,
+			Policy:          conf.Policy,
+		}, nil
+	case EcsRamRoleCredential:
+		if conf.RamRoleName == "" {
+			return nil, errors.New("ramRoleName is required by credential type ecs_ram_role")
+		}
+		return &EcsRamRoleProvider{RoleName: conf.RamRoleName}, nil
+	case OidcRoleArnCredential:
+		if conf.RoleArn == "" || conf.OidcProviderArn == "" || conf.OidcTokenFile == "" {
+			return nil, errors.New("roleArn, oidcProviderArn and oidcTokenFile are required by credential type oidc_role_arn")
+		}
+		return &OidcRoleArnProvider{
+			RoleArn:         conf.RoleArn,
+			OidcProviderArn: conf.OidcProviderArn,
+			OidcTokenFile:   conf.OidcTokenFile,
+			RoleSessionName: roleSessionName,
+			Policy:          conf.Policy,
+			StsEndpoint:     DefaultStsEndpoint,
+		}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown credential type %s", credentialType))
+}
+
+// AccessKeyProvider authenticates by access key
+type AccessKeyProvider struct {
+	AccessKeyId     string
+	AccessKeySecret string
+}
+
+// Type implements CredentialProvider
+func (p *AccessKeyProvider) Type() string {
+	return AccessKeyCredential
+}
+
+// NewRosClient implements CredentialProvider
+func (p *AccessKeyProvider) NewRosClient(regionId string) (*rosapi.Client, error) {
+	return rosapi.NewClientWithAccessKey(regionId, p.AccessKeyId, p.AccessKeySecret)
+}
+
+// Expiration implements CredentialProvider
+func (p *AccessKeyProvider) Expiration() time.Time {
+	return time.Time{}
+}
+
+// Credential implements CredentialProvider
+func (p *AccessKeyProvider) Credential() (*AliyunCredential, error) {
+	return &AliyunCredential{AccessKeyId: p.AccessKeyId, AccessKeySecret: p.AccessKeySecret}, nil
+}
+
+// StsTokenProvider authenticates by STS token
+type StsTokenProvider struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	SecurityToken   string
+	ExpirationTime  time.Time
+}
+
+// Type implements CredentialProvider
+func (p *StsTokenProvider) Type() string {
+	return StsTokenCredential
+}
+
+// NewRosClient implements CredentialProvider
+func (p *StsTokenProvider) NewRosClient(regionId string) (*rosapi.Client, error) {
+	return rosapi.NewClientWithStsToken(regionId, p.AccessKeyId, p.AccessKeySecret, p.SecurityToken)
+}
+
+// Expiration implements CredentialProvider
+func (p *StsTokenProvider) Expiration() time.Time {
+	return p.ExpirationTime
+}
+
+// Credential implements CredentialProvider
+func (p *StsTokenProvider) Credential() (*AliyunCredential, error) {
+	credential := &AliyunCredential{
+		AccessKeyId:     p.AccessKeyId,
+		AccessKeySecret: p.AccessKeySecret,
+		SecurityToken:   p.SecurityToken,
+	}
+	if !p.ExpirationTime.IsZero() {
+		credential.Expiration = p.ExpirationTime.UTC().Format(time.RFC3339)
+	}
+	return credential, nil
+}
+
+// RamRoleArnProvider authenticates by assuming RAM role with access key
+type RamRoleArnProvider struct {
+	AccessKeyId     string
+	AccessKeySecret string
+	RoleArn         string
+	RoleSessionName string
+	Policy          string
+}
+
+// Type implements CredentialProvider
+func (p *RamRoleArnProvider) Type() string {
+	return RamRoleArnCredential
+}
+
+// NewRosClient implements CredentialProvider
+func (p *RamRoleArnProvider) NewRosClient(regionId string) (*rosapi.Client, error) {
+	if p.Policy != "" {
+		return rosapi.NewClientWithRamRoleArnAndPolicy(
+			regionId, p.AccessKeyId, p.AccessKeySecret, p.RoleArn, p.RoleSessionName, p.Policy)
+	}
+	return rosapi.NewClientWithRamRoleArn(regionId, p.AccessKeyId, p.AccessKeySecret, p.RoleArn, p.RoleSessionName)
+}
+
+// Expiration implements CredentialProvider. The client assumes role again before its credential expires.
+func (p *RamRoleArnProvider) Expiration() time.Time {
+	return time.Time{}
+}
+
+// Credential implements CredentialProvider
+func (p *RamRoleArnProvider) Credential() (*AliyunCredential, error) {
+	client, err := sts.NewClientWithAccessKey(stsRegionId, p.AccessKeyId, p.AccessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	request := sts.CreateAssumeRoleRequest()
+	request.Scheme = "https"
+	request.RoleArn = p.RoleArn
+	request.RoleSessionName = p.RoleSessionName
+	request.Policy = p.Policy
+	response, err := client.AssumeRole(request)
+	if err != nil {
+		return nil, err
+	}
+	return &AliyunCredential{
+		AccessKeyId:     response.Credentials.AccessKeyId,
+		AccessKeySecret: response.Credentials.AccessKeySecret,
+		SecurityToken:   response.Credentials.SecurityToken,
+		Expiration:      response.Credentials.Expiration,
+	}, nil
+}
+
+// EcsRamRoleProvider authenticates by RAM role of ECS instance where controller runs
+type EcsRamRoleProvider struct {
+	RoleName string
+}
+
+// Type implements CredentialProvider
+func (p *EcsRamRoleProvider) Type() string {
+	return EcsRamRoleCredential
+}
+
+// NewRosClient implements CredentialProvider
+func (p *EcsRamRoleProvider) NewRosClient(regionId string) (*rosapi.Client, error) {
+	return rosapi.NewClientWithEcsRamRole(regionId, p.RoleName)
+}
+
+// Expiration implements CredentialProvider. The client fetches credential again before it expires.
+func (p *EcsRamRoleProvider) Expiration() time.Time {
+	return time.Time{}
+}
+
+// ecsRamRoleCredentialResponse is credential of RAM role served by ECS metadata service
+type ecsRamRoleCredentialResponse struct {
+	Code            string `json:"Code"`
+	AccessKeyId     string `json:"AccessKeyId"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	SecurityToken   string `json:"SecurityToken"`
+	Expiration      string `json:"Expiration"`
+}
+
+// Credential implements CredentialProvider
+func (p *EcsRamRoleProvider) Credential() (*AliyunCredential, error) {
+	resp, err := stsHttpClient.Get(ecsMetadataEndpoint + "/latest/meta-data/ram/security-credentials/" + p.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ecsRamRoleCredentialResponse{}
+	err = json.Unmarshal(body, response)
+	if err != nil || resp.StatusCode != http.StatusOK || response.Code != "Success" {
+		return nil, errors.New(fmt.Sprintf("Get credential of ECS RAM role %s failed, http status %d",
+			p.RoleName, resp.StatusCode))
+	}
+	return &AliyunCredential{
+		AccessKeyId:     response.AccessKeyId,
+		AccessKeySecret: response.AccessKeySecret,
+		SecurityToken:   response.SecurityToken,
+		Expiration:      response.Expiration,
+	}, nil
+}
+
+// OidcRoleArnProvider authenticates by assuming RAM role with OIDC token in file, e.g. the token
+// mounted by RRSA of ACK cluster
+type OidcRoleArnProvider struct {
+	RoleArn         string
+	OidcProviderArn string
+	OidcTokenFile   string
+	RoleSessionName string
+	Policy          string
+	StsEndpoint     string
+
+	expiration time.Time
+}
+
+// Type implements CredentialProvider
+func (p *OidcRoleArnProvider) Type() string {
+	return OidcRoleArnCredential
+}
+
+// NewRosClient implements CredentialProvider
+func (p *OidcRoleArnProvider) NewRosClient(regionId string) (*rosapi.Client, error) {
+	credential, err := p.AssumeRole()
+	if err != nil {
+		return nil, err
+	}
+	p.expiration = ParseExpiration(credential.Expiration)
+	return rosapi.NewClientWithStsToken(regionId, credential.AccessKeyId, credential.AccessKeySecret, credential.SecurityToken)
+}
+
+// Expiration implements CredentialProvider
+func (p *OidcRoleArnProvider) Expiration() time.Time {
+	return p.expiration
+}
+
+// Credential implements CredentialProvider
+func (p *OidcRoleArnProvider) Credential() (*AliyunCredential, error) {
+	return p.AssumeRole()
+}
+
+// assumeRoleWithOidcResponse is response of STS AssumeRoleWithOIDC API
+type assumeRoleWithOidcResponse struct {
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+	Credentials struct {
+		AccessKeyId     string `json:"AccessKeyId"`
+		AccessKeySecret string `json:"AccessKeySecret"`
+		SecurityToken   string `json:"SecurityToken"`
+		Expiration      string `json:"Expiration"`
+	} `json:"Credentials"`
+}
+
+var stsHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+// AssumeRole exchanges OIDC token for STS credential of the role
+func (p *OidcRoleArnProvider) AssumeRole() (credential *AliyunCredential, err error) {
+	token, err := ioutil.ReadFile(p.OidcTokenFile)
+	if err != nil {
+		return
+	}
+
+	// AssumeRoleWithOIDC is authenticated by OIDC token, so the request is not signed
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithOIDC")
+	form.Set("Format", "JSON")
+	form.Set("Version", "2015-04-01")
+	form.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	form.Set("RoleArn", p.RoleArn)
+	form.Set("OIDCProviderArn", p.OidcProviderArn)
+	form.Set("OIDCToken", strings.TrimSpace(string(token)))
+	form.Set("RoleSessionName", p.RoleSessionName)
+	if p.Policy != "" {
+		form.Set("Policy", p.Policy)
+	}
+
+	resp, err := stsHttpClient.PostForm(strings.TrimSuffix(p.StsEndpoint, "/")+"/", form)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	response := &assumeRoleWithOidcResponse{}
+	err = json.Unmarshal(body, response)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Parse AssumeRoleWithOIDC response failed, http status %d", resp.StatusCode))
+	}
+	if resp.StatusCode != http.StatusOK || response.Credentials.AccessKeyId == "" {
+		return nil, errors.New(fmt.Sprintf("AssumeRoleWithOIDC failed, http status %d, code %s: %s",
+			resp.StatusCode, response.Code, response.Message))
+	}
+
+	credential = &AliyunCredential{
+		AccessKeyId:     response.Credentials.AccessKeyId,
+		AccessKeySecret: response.Credentials.AccessKeySecret,
+		SecurityToken:   response.Credentials.SecurityToken,
+		Expiration:      response.Credentials.Expiration,
+	}
+	return
+}
@@ -0,0 +1,227 @@
+package aliyun
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCredentialProvider(t *testing.T) {
+	ak := &AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret"}
+	sts := &AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"}
+
+	tests := []struct {
+		name       string
+		conf       CredentialConfig
+		credential *AliyunCredential
+		want       CredentialProvider
+		wantErr    bool
+	}{
+		{
+			name:       "TestDefaultAccessKey",
+			credential: ak,
+			want:       &AccessKeyProvider{AccessKeyId: "id", AccessKeySecret: "secret"},
+		},
+		{
+			name:       "TestDefaultStsToken",
+			credential: sts,
+			want:       &StsTokenProvider{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token"},
+		},
+		{
+			name:       "TestStsTokenExpiration",
+			conf:       CredentialConfig{Type: StsTokenCredential},
+			credential: &AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token", Expiration: "2020-01-01T00:00:00Z"},
+			want: &StsTokenProvider{
+				AccessKeyId:     "id",
+				AccessKeySecret: "secret",
+				SecurityToken:   "token",
+				ExpirationTime:  ParseExpiration("2020-01-01T00:00:00Z"),
+			},
+		},
+		{
+			name:       "TestStsTokenMissing",
+			conf:       CredentialConfig{Type: StsTokenCredential},
+			credential: ak,
+			wantErr:    true,
+		},
+		{
+			name:    "TestRamRoleArnAccessKeyMissing",
+			conf:    CredentialConfig{Type: RamRoleArnCredential, RoleArn: "acs:ram::123:role/ros"},
+			wantErr: true,
+		},
+		{
+			name:       "TestRamRoleArn",
+			conf:       CredentialConfig{Type: RamRoleArnCredential, RoleArn: "acs:ram::123:role/ros", Policy: "{}"},
+			credential: ak,
+			want: &RamRoleArnProvider{
+				AccessKeyId:     "id",
+				AccessKeySecret: "secret",
+				RoleArn:         "acs:ram::123:role/ros",
+				RoleSessionName: DefaultRoleSessionName,
+				Policy:          "{}",
+			},
+		},
+		{
+			name:       "TestRamRoleArnMissing",
+			conf:       CredentialConfig{Type: RamRoleArnCredential},
+			credential: ak,
+			wantErr:    true,
+		},
+		{
+			name: "TestEcsRamRole",
+			conf: CredentialConfig{Type: EcsRamRoleCredential, RamRoleName: "ros"},
+			want: &EcsRamRoleProvider{RoleName: "ros"},
+		},
+		{
+			name: "TestOidcRoleArn",
+			conf: CredentialConfig{
+				Type:            OidcRoleArnCredential,
+				RoleArn:         "acs:ram::123:role/ros",
+				RoleSessionName: "session",
+				OidcProviderArn: "acs:ram::123:oidc-provider/ack",
+				OidcTokenFile:   "/var/run/secrets/tokens/token",
+			},
+			want: &OidcRoleArnProvider{
+				RoleArn:         "acs:ram::123:role/ros",
+				OidcProviderArn: "acs:ram::123:oidc-provider/ack",
+				OidcTokenFile:   "/var/run/secrets/tokens/token",
+				RoleSessionName: "session",
+				StsEndpoint:     DefaultStsEndpoint,
+			},
+		},
+		{
+			name:    "TestOidcTokenFileMissing",
+			conf:    CredentialConfig{Type: OidcRoleArnCredential, RoleArn: "acs:ram::123:role/ros"},
+			wantErr: true,
+		},
+		{
+			name:       "TestUnknownType",
+			conf:       CredentialConfig{Type: "unknown"},
+			credential: ak,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCredentialProvider(tt.conf, tt.credential)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+
+			client, err := got.NewRosClient("cn-hangzhou")
+			if tt.conf.Type != OidcRoleArnCredential {
+				assert.Nil(t, err)
+				assert.NotNil(t, client)
+			}
+		})
+	}
+}
+
+func TestOidcRoleArnProvider_AssumeRole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oidc")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("oidc-token\n"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "AssumeRoleWithOIDC", r.PostForm.Get("Action"))
+		assert.Equal(t, "acs:ram::123:role/ros", r.PostForm.Get("RoleArn"))
+		assert.Equal(t, "acs:ram::123:oidc-provider/ack", r.PostForm.Get("OIDCProviderArn"))
+		assert.Equal(t, "session", r.PostForm.Get("RoleSessionName"))
+		if r.PostForm.Get("OIDCToken") != "oidc-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"Code": "AuthenticationFail.OIDCToken.Invalid", "Message": "invalid token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Credentials": {"AccessKeyId": "STS.id", "AccessKeySecret": "secret",
+			"SecurityToken": "token", "Expiration": "2020-01-01T00:00:00Z"}}`))
+	}))
+	defer server.Close()
+
+	provider := &OidcRoleArnProvider{
+		RoleArn:         "acs:ram::123:role/ros",
+		OidcProviderArn: "acs:ram::123:oidc-provider/ack",
+		OidcTokenFile:   tokenFile,
+		RoleSessionName: "session",
+		StsEndpoint:     server.URL,
+	}
+	credential, err := provider.AssumeRole()
+	assert.Nil(t, err)
+	assert.Equal(t, &AliyunCredential{
+		AccessKeyId:     "STS.id",
+		AccessKeySecret: "secret",
+		SecurityToken:   "token",
+		Expiration:      "2020-01-01T00:00:00Z",
+	}, credential)
+
+	client, err := provider.NewRosClient("cn-hangzhou")
+	assert.Nil(t, err)
+	assert.NotNil(t, client)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), provider.Expiration())
+
+	// invalid token
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("invalid"), 0600))
+	_, err = provider.AssumeRole()
+	assert.EqualError(t, err, "AssumeRoleWithOIDC failed, http status 400, code AuthenticationFail.OIDCToken.Invalid: invalid token")
+
+	// token file not found
+	provider.OidcTokenFile = filepath.Join(dir, "not-found")
+	_, err = provider.AssumeRole()
+	assert.NotNil(t, err)
+}
+
+func TestCredentialProvider_Credential(t *testing.T) {
+	credential, err := (&AccessKeyProvider{AccessKeyId: "id", AccessKeySecret: "secret"}).Credential()
+	assert.Nil(t, err)
+	assert.Equal(t, &AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret"}, credential)
+
+	credential, err = (&StsTokenProvider{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token",
+		ExpirationTime: ParseExpiration("2020-01-01T00:00:00Z")}).Credential()
+	assert.Nil(t, err)
+	assert.Equal(t, &AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret", SecurityToken: "token",
+		Expiration: "2020-01-01T00:00:00Z"}, credential)
+}
+
+func TestEcsRamRoleProvider_Credential(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/latest/meta-data/ram/security-credentials/ros" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Code": "Success", "AccessKeyId": "STS.id", "AccessKeySecret": "secret",
+			"SecurityToken": "token", "Expiration": "2020-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+	endpoint := ecsMetadataEndpoint
+	ecsMetadataEndpoint = server.URL
+	defer func() { ecsMetadataEndpoint = endpoint }()
+
+	credential, err := (&EcsRamRoleProvider{RoleName: "ros"}).Credential()
+	assert.Nil(t, err)
+	assert.Equal(t, &AliyunCredential{
+		AccessKeyId:     "STS.id",
+		AccessKeySecret: "secret",
+		SecurityToken:   "token",
+		Expiration:      "2020-01-01T00:00:00Z",
+	}, credential)
+
+	_, err = (&EcsRamRoleProvider{RoleName: "other"}).Credential()
+	assert.EqualError(t, err, "Get credential of ECS RAM role other failed, http status 404")
+}
+
+func TestParseExpiration(t *testing.T) {
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ParseExpiration("2020-01-01T00:00:00Z"))
+	assert.True(t, ParseExpiration("").IsZero())
+	assert.True(t, ParseExpiration("expiration").IsZero())
+}
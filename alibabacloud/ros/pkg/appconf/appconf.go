@@ -78,6 +78,36 @@ func GetAppConf(
 	}
 }
 
+// ListAppConfs returns application configurations in namespace, and an error if there is any.
+func ListAppConfs(
+	namespace string,
+	oamCrdClient *versioned.Clientset,
+	rosCrdClient *roscrd.Clientset) (appConfs []AppConfInterface, err error) {
+	if oamCrdClient != nil {
+		list, err := oamCrdClient.CoreV1alpha1().ApplicationConfigurations(namespace).List(v1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			appConf, _ := NewAppConf(&list.Items[i])
+			appConfs = append(appConfs, appConf)
+		}
+		return appConfs, nil
+	} else if rosCrdClient != nil {
+		list, err := rosCrdClient.RosV1alpha1().RosStacks(namespace).List(v1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			appConf, _ := NewAppConf(&list.Items[i])
+			appConfs = append(appConfs, appConf)
+		}
+		return appConfs, nil
+	} else {
+		return nil, errors.New("no client found")
+	}
+}
+
 func GetAppConfFromContext(c *Context) (appConf AppConfInterface, err error) {
 	return GetAppConf(
 		c.AppConf.GetNamespace(),
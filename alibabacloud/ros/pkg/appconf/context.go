@@ -2,12 +2,20 @@ package appconf
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
 	roscrd "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
-	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/policy"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
+	"path"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Context struct {
@@ -18,7 +26,14 @@ type Context struct {
 	AppConf      AppConfInterface
 	OamCrdClient *versioned.Clientset
 	RosCrdClient *roscrd.Clientset
-	RosClient    *rosapi.Client
+	RosClient    rosclient.Interface
+
+	// Targets are all targets of application with accounts defaulted, empty if application has no targets
+	Targets []aliyun.Target
+	// Parameters override properties of component instances for the target of context
+	Parameters map[string]map[string]interface{}
+	// Identity is resource identity of the target of context, nil if context uses controller credential
+	Identity *aliyun.AliyunResourceIdentity
 }
 
 func NewContext(
@@ -26,16 +41,7 @@ func NewContext(
 	oamCrdClient *versioned.Clientset,
 	rosCrdClient *roscrd.Clientset) (context *Context, err error) {
 
-	rosClient := &rosapi.Client{}
-	context = &Context{
-		AppName:      appConf.GetName(),
-		AppConf:      appConf,
-		OamCrdClient: oamCrdClient,
-		RosCrdClient: rosCrdClient,
-		RosClient:    rosClient,
-		DryRun:       config.RosCtrlConf.DryRun,
-		RegionId:     config.RosCtrlConf.RegionId, // maybe changed by being assigned in scope
-	}
+	context = newContext(appConf, oamCrdClient, rosCrdClient)
 
 	initialized, err := initContextFromScope(appConf, context)
 	if err != nil {
@@ -45,6 +51,12 @@ func NewContext(
 		return context, nil
 	}
 
+	// account of controller credential must be allowed for namespace of application too
+	err = policy.CheckAccount(appConf.GetNamespace(), config.RosCtrlConf.ControllerAliUid, context.RegionId)
+	if err != nil {
+		return nil, err
+	}
+
 	err = initContextFromConfig(context)
 	if err != nil {
 		return nil, err
@@ -52,28 +64,356 @@ func NewContext(
 	return context, nil
 }
 
+// NewContexts returns a context per target of application, or the context of NewContext if application has no
+// targets. Each context has its own region, account and ROS client.
+func NewContexts(
+	appConf AppConfInterface,
+	oamCrdClient *versioned.Clientset,
+	rosCrdClient *roscrd.Clientset) (contexts []*Context, err error) {
+
+	// recorded even if credential can not be read, so that the application is requeued once it is fixed
+	recordCredentialSecrets(appConf)
+
+	resourceIdentity, err := GetResourceIdentity(appConf)
+	if err != nil {
+		return nil, err
+	}
+	if resourceIdentity == nil || len(resourceIdentity.Targets) == 0 {
+		context, err := NewContext(appConf, oamCrdClient, rosCrdClient)
+		if err != nil {
+			return nil, err
+		}
+		return []*Context{context}, nil
+	}
+
+	var targets []aliyun.Target
+	for _, target := range resourceIdentity.Targets {
+		target.AliUid = resourceIdentity.ForTarget(target).AliUid
+		targets = append(targets, target)
+	}
+
+	for _, target := range targets {
+		context := newContext(appConf, oamCrdClient, rosCrdClient)
+		context.Targets = targets
+		context.Parameters = target.Parameters
+		err = initContextFromIdentity(appConf, context, resourceIdentity.ForTarget(target))
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, nil
+}
+
+// NewControllerContext returns context of controller credential in region of controller, whose ROS client makes
+// API calls not belonging to any application. ROS client is nil if dry run.
+func NewControllerContext() (*Context, error) {
+	context := newContext(&AppConf{}, nil, nil)
+	err := initContextFromConfig(context)
+	if err != nil {
+		return nil, err
+	}
+	return context, nil
+}
+
+// NewRemovedContexts returns a context per target which application is applied to, as recorded by
+// SetAppliedIdentities, but which is not the target of any of contexts, i.e. it is removed from application.
+// Identities of removed targets whose contexts can not be made, e.g. their credentials are gone, are returned as
+// pending, so that they are kept recorded.
+func NewRemovedContexts(appConf AppConfInterface, contexts []*Context) (
+	removed []*Context, pending []*aliyun.AliyunResourceIdentity, err error) {
+
+	applied, err := AppliedIdentitiesOf(appConf)
+	if err != nil || len(applied) == 0 {
+		return nil, nil, err
+	}
+
+	current := make(map[string]bool)
+	for _, context := range contexts {
+		current[context.RegionId+"/"+context.AliUid] = true
+	}
+
+	oamCrdClient, rosCrdClient := contexts[0].OamCrdClient, contexts[0].RosCrdClient
+	for _, identity := range applied {
+		if current[identity.RegionId+"/"+identity.AliUid] {
+			continue
+		}
+		context := newContext(appConf, oamCrdClient, rosCrdClient)
+		err := initContextFromIdentity(appConf, context, identity)
+		if err != nil {
+			logging.Default.Error(err, "Init context of removed target failed",
+				"AppConfName", appConf.GetName(), "RegionId", identity.RegionId, "AliUid", identity.AliUid)
+			pending = append(pending, identity)
+			continue
+		}
+		removed = append(removed, context)
+	}
+	return removed, pending, nil
+}
+
+// TargetsOf returns targets of contexts and identities, which are all targets app stacks of application are kept
+// for while stacks of removed targets are deleted
+func TargetsOf(contexts []*Context, identities []*aliyun.AliyunResourceIdentity) (targets []aliyun.Target) {
+	for _, context := range contexts {
+		targets = append(targets, aliyun.Target{RegionId: context.RegionId, AliUid: context.AliUid})
+	}
+	for _, identity := range identities {
+		targets = append(targets, aliyun.Target{RegionId: identity.RegionId, AliUid: identity.AliUid})
+	}
+	return
+}
+
+// AppliedIdentitiesOf returns resource identities of targets which application is applied to, as recorded in
+// annotation of application
+func AppliedIdentitiesOf(appConf AppConfInterface) ([]*aliyun.AliyunResourceIdentity, error) {
+	value, ok := appConf.GetObjectMeta().Annotations[config.APPLIED_TARGETS_ANNOTATION]
+	if !ok {
+		return nil, nil
+	}
+	var identities []*aliyun.AliyunResourceIdentity
+	err := json.Unmarshal([]byte(value), &identities)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid annotation '%s': %s", config.APPLIED_TARGETS_ANNOTATION, err))
+	}
+	return identities, nil
+}
+
+// SetAppliedIdentities records resource identities of targets which application is applied to in annotation of
+// application. Returns false if they are recorded already.
+func SetAppliedIdentities(appConf AppConfInterface, identities []*aliyun.AliyunResourceIdentity) bool {
+	object := appConf.ToObject()
+	annotations := object.GetAnnotations()
+	value, ok := annotations[config.APPLIED_TARGETS_ANNOTATION]
+	if len(identities) == 0 {
+		if !ok {
+			return false
+		}
+		delete(annotations, config.APPLIED_TARGETS_ANNOTATION)
+		object.SetAnnotations(annotations)
+		return true
+	}
+
+	identitiesBytes, _ := json.Marshal(identities)
+	if ok && value == string(identitiesBytes) {
+		return false
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[config.APPLIED_TARGETS_ANNOTATION] = string(identitiesBytes)
+	object.SetAnnotations(annotations)
+	return true
+}
+
+// newContext returns context of application in region of controller
+func newContext(
+	appConf AppConfInterface,
+	oamCrdClient *versioned.Clientset,
+	rosCrdClient *roscrd.Clientset) *Context {
+
+	return &Context{
+		AppName:      appConf.GetName(),
+		AppConf:      appConf,
+		OamCrdClient: oamCrdClient,
+		RosCrdClient: rosCrdClient,
+		DryRun:       config.RosCtrlConf.DryRun,
+		RegionId:     config.RosCtrlConf.RegionId, // maybe changed by being assigned in scope
+	}
+}
+
+// CredentialError means credential of application is missing or invalid, i.e. it can not be read, or it can not
+// create ROS client
+type CredentialError struct {
+	Err error
+}
+
+func (e *CredentialError) Error() string {
+	return "Credential is missing or invalid: " + e.Err.Error()
+}
+
+// IsCredentialError returns true if error is CredentialError
+func IsCredentialError(err error) bool {
+	_, ok := err.(*CredentialError)
+	return ok
+}
+
+// CredentialSecretOf returns namespace and name of credential secret used by application, which is the secret of
+// resource identity scope, or credential secret of controller. Empty name means no secret is used.
+func CredentialSecretOf(appConf AppConfInterface) (namespace string, name string, err error) {
+	resourceIdentity, err := GetResourceIdentity(appConf)
+	if err != nil {
+		return
+	}
+	if resourceIdentity != nil {
+		if !resourceIdentity.NeedsSecret() {
+			return "", "", nil
+		}
+		return credentialSecretOfIdentity(appConf, resourceIdentity)
+	}
+	credentialConfig := credentialConfigOfController()
+	if !credentialConfig.NeedsSecret() || config.RosCtrlConf.CredentialSecretName == "" {
+		return "", "", nil
+	}
+	return config.RosCtrlConf.Namespace, strings.ToLower(config.RosCtrlConf.CredentialSecretName), nil
+}
+
+// UsesCredentialSecret returns true if application reads credential from the secret, for any of its targets
+func UsesCredentialSecret(appConf AppConfInterface, namespace, secretName string) bool {
+	source := SecretSource(namespace, secretName)
+	for _, s := range credentialSourcesOf(appConf) {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialSourcesOf returns sources of credential secrets which application reads credential from, for any of
+// its targets and the targets removed from it whose stacks are not deleted yet
+func credentialSourcesOf(appConf AppConfInterface) (sources []string) {
+	resourceIdentity, err := GetResourceIdentity(appConf)
+	if err != nil {
+		return nil
+	}
+
+	var identities []*aliyun.AliyunResourceIdentity
+	if resourceIdentity == nil || len(resourceIdentity.Targets) == 0 {
+		credentialNamespace, credentialSecret, err := CredentialSecretOf(appConf)
+		if err == nil && credentialSecret != "" {
+			sources = append(sources, SecretSource(credentialNamespace, credentialSecret))
+		}
+	} else {
+		for _, target := range resourceIdentity.Targets {
+			identities = append(identities, resourceIdentity.ForTarget(target))
+		}
+	}
+	applied, _ := AppliedIdentitiesOf(appConf)
+	identities = append(identities, applied...)
+
+	for _, identity := range identities {
+		if !identity.NeedsSecret() {
+			continue
+		}
+		credentialNamespace, credentialSecret, err := credentialSecretOfIdentity(appConf, identity)
+		if err == nil {
+			sources = append(sources, SecretSource(credentialNamespace, credentialSecret))
+		}
+	}
+	return
+}
+
+// credentialSecretOfIdentity returns namespace and name of credential secret of resource identity. The secret is
+// in namespace of controller, or in namespace of application by application lookup policy, unless secretNamespace
+// of the scope names another namespace allowed by credential secret namespaces. A secretName of the scope must be
+// allowed by credential secret names, and the credential secret of controller is never used.
+func credentialSecretOfIdentity(
+	appConf AppConfInterface,
+	resourceIdentity *aliyun.AliyunResourceIdentity) (namespace string, name string, err error) {
+
+	namespace = config.RosCtrlConf.Namespace
+	if config.RosCtrlConf.CredentialLookupPolicy == config.CREDENTIAL_LOOKUP_APPLICATION {
+		namespace = appConf.GetNamespace()
+	}
+	if resourceIdentity.SecretNamespace != "" && resourceIdentity.SecretNamespace != namespace {
+		if !isSecretNamespaceAllowed(resourceIdentity.SecretNamespace) {
+			return "", "", errors.New(fmt.Sprintf(
+				"Namespace %s of credential secret is not allowed", resourceIdentity.SecretNamespace))
+		}
+		namespace = resourceIdentity.SecretNamespace
+	}
+
+	name = resourceIdentity.CredentialSecretName()
+	if resourceIdentity.SecretName != "" && !isSecretNameAllowed(name) {
+		return "", "", errors.New(fmt.Sprintf("Credential secret %s is not allowed", name))
+	}
+	if namespace == config.RosCtrlConf.Namespace && config.RosCtrlConf.CredentialSecretName != "" &&
+		name == strings.ToLower(config.RosCtrlConf.CredentialSecretName) {
+		return "", "", errors.New(fmt.Sprintf("Credential secret %s of controller is not allowed", name))
+	}
+	return namespace, name, nil
+}
+
+// isSecretNamespaceAllowed returns true if namespace is allowed by credential secret namespaces
+func isSecretNamespaceAllowed(namespace string) bool {
+	for _, allowed := range config.RosCtrlConf.CredentialSecretNamespaces {
+		if allowed == config.ALL_NAMESPACES || allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretNameAllowed returns true if secret name matches any of credential secret names
+func isSecretNameAllowed(name string) bool {
+	for _, pattern := range config.RosCtrlConf.CredentialSecretNames {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func initContextFromConfig(context *Context) error {
 	// Don't init from Config if dry run
 	if context.DryRun {
 		return nil
 	}
 
+	source, readCredential := controllerCredentialSource()
+	return initRosClient(context, source, credentialConfigOfController(), readCredential)
+}
+
+// controllerCredentialSource returns source of credential of controller and reads credential from it, which is
+// the credential secret of controller if there is, or flags otherwise
+func controllerCredentialSource() (string, func() (*aliyun.AliyunCredential, error)) {
 	// get from secret
+	credentialConfig := credentialConfigOfController()
 	credentialSecretName := config.RosCtrlConf.CredentialSecretName
-	if credentialSecretName != "" {
+	if credentialSecretName != "" && credentialConfig.NeedsSecret() {
 		logging.Default.Info("Get aliyun credential by credential secret name", "CredentialSecretName", credentialSecretName)
-		credential, err := aliyun.ReadCredentialFromSecretName(credentialSecretName)
-		if err != nil {
-			return err
+		return SecretSource(config.RosCtrlConf.Namespace, credentialSecretName), func() (*aliyun.AliyunCredential, error) {
+			return aliyun.ReadCredentialFromSecretName(credentialSecretName)
 		}
-		return initRosClient(context, context.RosClient, credential)
 	}
 	// get from ak
-	return context.RosClient.InitWithAccessKey(
-		context.RegionId,
-		config.RosCtrlConf.AccessKeyId,
-		config.RosCtrlConf.AccessKeySecret,
-	)
+	return configSource, func() (*aliyun.AliyunCredential, error) {
+		return &aliyun.AliyunCredential{
+			AccessKeyId:     config.RosCtrlConf.AccessKeyId,
+			AccessKeySecret: config.RosCtrlConf.AccessKeySecret,
+		}, nil
+	}
+}
+
+// ControllerCredential returns the current credential of controller, which is resolved from credential config,
+// credential secret and flags of controller like its ROS clients, and signs requests of other services, e.g. OSS
+func ControllerCredential() (*aliyun.AliyunCredential, error) {
+	credentialConfig := credentialConfigOfController()
+	var credential *aliyun.AliyunCredential
+	if credentialConfig.NeedsSecret() {
+		_, readCredential := controllerCredentialSource()
+		c, err := readCredential()
+		if err != nil {
+			return nil, err
+		}
+		credential = c
+	}
+	provider, err := aliyun.NewCredentialProvider(credentialConfig, credential)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Credential()
+}
+
+func credentialConfigOfController() aliyun.CredentialConfig {
+	return aliyun.CredentialConfig{
+		Type:            config.RosCtrlConf.CredentialType,
+		RoleArn:         config.RosCtrlConf.RoleArn,
+		RoleSessionName: config.RosCtrlConf.RoleSessionName,
+		Policy:          config.RosCtrlConf.RolePolicy,
+		RamRoleName:     config.RosCtrlConf.EcsRamRoleName,
+		OidcProviderArn: config.RosCtrlConf.OidcProviderArn,
+		OidcTokenFile:   config.RosCtrlConf.OidcTokenFile,
+	}
 }
 
 func initContextFromScope(appConf AppConfInterface, context *Context) (bool, error) {
@@ -82,56 +422,277 @@ func initContextFromScope(appConf AppConfInterface, context *Context) (bool, err
 		return false, nil
 	}
 
+	resourceIdentity, err := GetResourceIdentity(appConf)
+	if err != nil || resourceIdentity == nil {
+		return false, err
+	}
+	logging.Default.Info("Identity scope detected", "AppConfName", appConf.GetObjectMeta().Name)
+
+	err = initContextFromIdentity(appConf, context, resourceIdentity)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// initContextFromIdentity inits account, region and ROS client of context from resource identity. ROS client is
+// not initialized if dry run.
+func initContextFromIdentity(
+	appConf AppConfInterface,
+	context *Context,
+	resourceIdentity *aliyun.AliyunResourceIdentity) error {
+
+	// context
+	context.AliUid = resourceIdentity.AliUid
+	context.RegionId = resourceIdentity.RegionId
+	if context.RegionId == "" {
+		context.RegionId = config.RosCtrlConf.RegionId
+	}
+	identity := *resourceIdentity
+	identity.RegionId = context.RegionId
+	identity.Targets = nil
+	context.Identity = &identity
+
+	// account and region must be allowed for namespace of application
+	err := policy.CheckAccount(appConf.GetNamespace(), context.AliUid, context.RegionId)
+	if err != nil {
+		return err
+	}
+
+	if context.DryRun {
+		return nil
+	}
+
+	// get ak from secret
+	secretNamespace, secretName, err := credentialSecretOfIdentity(appConf, resourceIdentity)
+	if err != nil {
+		return err
+	}
+	logging.Default.Info("Get aliyun credential by aliyun resource identity", "Identity", resourceIdentity,
+		"SecretNamespace", secretNamespace)
+	source := SecretSource(secretNamespace, secretName)
+	readCredential := func() (*aliyun.AliyunCredential, error) {
+		credential, err := aliyun.ReadCredentialFromSecretName(secretName, k8s.WithNamespace(secretNamespace))
+		if err != nil {
+			return nil, err
+		}
+		return credential, checkCredentialAccount(credential, source, context.AliUid)
+	}
+
+	// init rosClient
+	return initRosClient(context, source, resourceIdentity.CredentialConfig, readCredential)
+}
+
+// checkCredentialAccount returns an error if credential read from source belongs to another account. Credential
+// secrets must declare their account if account policies are enforced, so that a secret can not be used for any
+// account but its own.
+func checkCredentialAccount(credential *aliyun.AliyunCredential, source, aliUid string) error {
+	if credential.AliUid == "" {
+		if policy.IsEnforced() {
+			return errors.New(fmt.Sprintf(
+				"Credential %s must declare AliyunAccountUid when account policies are enforced", source))
+		}
+		return nil
+	}
+	if credential.AliUid != aliUid {
+		return errors.New(fmt.Sprintf(
+			"Credential %s belongs to account %s, not %s", source, credential.AliUid, aliUid))
+	}
+	return nil
+}
+
+// GetResourceIdentity returns the validated resource identity in scopes of application configuration,
+// nil if there is no resource identity scope, and an error if there is any.
+func GetResourceIdentity(appConf AppConfInterface) (*aliyun.AliyunResourceIdentity, error) {
 	for _, scope := range appConf.GetScopes() {
 		if scope.Name == config.RESOURCE_IDENTITY && scope.Type == config.RESOURCE_IDENTITY_TYPE {
-			logging.Default.Info("Identity scope detected", "AppConfName", appConf.GetObjectMeta().Name)
-
 			resourceIdentity := &aliyun.AliyunResourceIdentity{}
-			err := json.Unmarshal(scope.Properties.Raw, resourceIdentity)
+			if len(scope.Properties.Raw) > 0 {
+				err := json.Unmarshal(scope.Properties.Raw, resourceIdentity)
+				if err != nil {
+					return nil, err
+				}
+			}
+			err := resourceIdentity.Validate()
 			if err != nil {
-				return false, err
+				return nil, err
 			}
-
-			// get ak from secret
-			secretName := resourceIdentity.IdentityAsKey()
-			logging.Default.Info("Get aliyun credential by aliyun resource identity", "Identity", resourceIdentity)
-			credential, err := aliyun.ReadCredentialFromSecretName(secretName)
+			err = checkScopeCredentialConfig(resourceIdentity.CredentialConfig)
 			if err != nil {
-				return false, err
+				return nil, err
 			}
+			return resourceIdentity, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkScopeCredentialConfig returns an error if credential type of resource identity scope is not allowed by scope
+// credential types. Types without credential secret, which run as role of controller node or pod, are allowed only if
+// listed, and the OIDC token of a scope must be the token of controller.
+func checkScopeCredentialConfig(credentialConfig aliyun.CredentialConfig) error {
+	if !isScopeCredentialTypeAllowed(credentialConfig) {
+		return errors.New(fmt.Sprintf(
+			"Credential type %s is not allowed in resource identity scope", credentialConfig.Type))
+	}
+	if credentialConfig.Type == aliyun.OidcRoleArnCredential &&
+		credentialConfig.OidcTokenFile != config.RosCtrlConf.OidcTokenFile {
+		return errors.New("oidcTokenFile of resource identity scope must be the OIDC token file of controller")
+	}
+	return nil
+}
+
+// isScopeCredentialTypeAllowed returns true if credential type is allowed by scope credential types
+func isScopeCredentialTypeAllowed(credentialConfig aliyun.CredentialConfig) bool {
+	allowed := config.RosCtrlConf.ScopeCredentialTypes
+	if len(allowed) == 0 {
+		return credentialConfig.NeedsSecret()
+	}
+	for _, credentialType := range allowed {
+		// empty type is access_key, or sts if secret has security token
+		if credentialType == credentialConfig.Type || credentialConfig.Type == "" &&
+			(credentialType == aliyun.AccessKeyCredential || credentialType == aliyun.StsTokenCredential) {
+			return true
+		}
+	}
+	return false
+}
+
+// rosClientTTL is how long cached ROS clients are kept without being used, e.g. clients of deleted applications
+const rosClientTTL = time.Hour
+
+// RosClientCache caches ROS clients of contexts
+var RosClientCache = rosclient.NewCache(rosclient.WithTTL(rosClientTTL))
+
+// credential source of controller flags
+const configSource = "config"
+
+// SecretSource returns credential source of credential secret
+func SecretSource(namespace, secretName string) string {
+	return "secret/" + namespace + "/" + strings.ToLower(secretName)
+}
+
+var (
+	// credentialSources are sources of credential secrets by applications
+	credentialSources     = make(map[string][]string)
+	credentialSourcesLock sync.RWMutex
+)
 
-			// context
-			context.AliUid = resourceIdentity.AliUid
-			context.RegionId = resourceIdentity.RegionId
-			if context.RegionId == "" {
-				context.RegionId = config.RosCtrlConf.RegionId
+// LoadCredentialSecrets records credential secrets of applications in place of those recorded before, so that
+// changes of them are known as changes of credential secrets before applications are reconciled, e.g. right after
+// controller restarts, and secrets of applications which are gone are forgotten
+func LoadCredentialSecrets(appConfs []AppConfInterface) {
+	sources := make(map[string][]string, len(appConfs))
+	for _, appConf := range appConfs {
+		if appSources := credentialSourcesOf(appConf); len(appSources) > 0 {
+			sources[appKeyOf(appConf)] = appSources
+		}
+	}
+	credentialSourcesLock.Lock()
+	defer credentialSourcesLock.Unlock()
+	credentialSources = sources
+}
+
+// recordCredentialSecrets records credential secrets of application in place of those recorded before
+func recordCredentialSecrets(appConf AppConfInterface) {
+	appSources := credentialSourcesOf(appConf)
+	credentialSourcesLock.Lock()
+	defer credentialSourcesLock.Unlock()
+	if len(appSources) == 0 {
+		delete(credentialSources, appKeyOf(appConf))
+		return
+	}
+	credentialSources[appKeyOf(appConf)] = appSources
+}
+
+// ForgetCredentialSecrets forgets credential secrets of application, which is called once application is deleted
+func ForgetCredentialSecrets(appConf AppConfInterface) {
+	credentialSourcesLock.Lock()
+	defer credentialSourcesLock.Unlock()
+	delete(credentialSources, appKeyOf(appConf))
+}
+
+// IsCredentialSecret returns true if credential of any application is read from the secret
+func IsCredentialSecret(namespace, secretName string) bool {
+	source := SecretSource(namespace, secretName)
+	credentialSourcesLock.RLock()
+	defer credentialSourcesLock.RUnlock()
+	for _, appSources := range credentialSources {
+		for _, s := range appSources {
+			if s == source {
+				return true
 			}
+		}
+	}
+	return false
+}
+
+// appKeyOf returns key of application in credential sources
+func appKeyOf(appConf AppConfInterface) string {
+	return appConf.GetNamespace() + "/" + appConf.GetName()
+}
 
-			// init rosClient
-			err = initRosClient(context, context.RosClient, credential)
+// InvalidateRosClients removes cached ROS clients whose credential is read from the secret
+func InvalidateRosClients(namespace, secretName string) {
+	count := RosClientCache.Invalidate(SecretSource(namespace, secretName))
+	if count > 0 {
+		logging.Default.Info("Credential secret changed, invalidate cached ROS clients",
+			"SecretNamespace", namespace, "SecretName", secretName, "Count", count)
+	}
+}
+
+// initRosClient inits ROS client of context, which is cached by account, region, namespace, credential source and
+// credential config. Credential is read again by readCredential to refresh the client before its credential expires,
+// so that in-flight stack watchers keep working.
+func initRosClient(
+	context *Context,
+	source string,
+	credentialConfig aliyun.CredentialConfig,
+	readCredential func() (*aliyun.AliyunCredential, error)) (err error) {
+
+	// only errors of reading credential and creating client by it are credential errors
+	newClient := func() (rosclient.Interface, time.Time, error) {
+		var credential *aliyun.AliyunCredential
+		if credentialConfig.NeedsSecret() {
+			c, err := readCredential()
 			if err != nil {
-				return false, err
+				return nil, time.Time{}, &CredentialError{Err: err}
 			}
-			return true, nil
+			credential = c
 		}
+		provider, err := aliyun.NewCredentialProvider(credentialConfig, credential)
+		if err != nil {
+			return nil, time.Time{}, &CredentialError{Err: err}
+		}
+		// only type of provider is logged, never the credential
+		logging.Default.Info("Init ROS client", "CredentialProvider", provider.Type(), "RegionId", context.RegionId)
+
+		client, err := provider.NewRosClient(context.RegionId)
+		if err != nil {
+			return nil, time.Time{}, &CredentialError{Err: err}
+		}
+		return client, provider.Expiration(), nil
 	}
-	return false, nil
-}
 
-func initRosClient(context *Context, client *rosapi.Client, credential *aliyun.AliyunCredential) (err error) {
-	if credential.SecurityToken != "" {
-		err = client.InitWithStsToken(
-			context.RegionId,
-			credential.AccessKeyId,
-			credential.AccessKeySecret,
-			credential.SecurityToken,
-		)
-	} else {
-		err = client.InitWithAccessKey(
-			context.RegionId,
-			credential.AccessKeyId,
-			credential.AccessKeySecret,
-		)
+	credentialConfigBytes, _ := json.Marshal(credentialConfig)
+	key := rosclient.CacheKey{
+		AliUid:     context.AliUid,
+		RegionId:   context.RegionId,
+		Namespace:  context.AppConf.GetNamespace(),
+		Source:     source,
+		Credential: string(credentialConfigBytes),
 	}
+	context.RosClient, err = RosClientCache.Get(key, func() (rosclient.Interface, error) {
+		client, expiration, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		return rosclient.New(
+			client,
+			rosclient.WithAccount(context.AliUid),
+			rosclient.WithMetricLabels(context.RegionId, context.AppConf.GetNamespace()),
+			rosclient.WithRefresher(newClient, expiration),
+		), nil
+	})
 	return
 }
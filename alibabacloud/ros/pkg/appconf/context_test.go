@@ -0,0 +1,490 @@
+package appconf
+
+import (
+	"errors"
+	"testing"
+
+	rosv1alpha1 "github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
+	listers "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/listers/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/policy"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newAppConfWithScope(properties string) *AppConf {
+	appConf := &AppConf{}
+	if properties != "" {
+		appConf.Spec.Scopes = []v1alpha1.ScopeBinding{{
+			Name:       config.RESOURCE_IDENTITY,
+			Type:       config.RESOURCE_IDENTITY_TYPE,
+			Properties: runtime.RawExtension{Raw: []byte(properties)},
+		}}
+	}
+	return appConf
+}
+
+func TestCredentialSecretOf(t *testing.T) {
+	tests := []struct {
+		name                       string
+		properties                 string
+		credentialSecretName       string
+		credentialType             string
+		credentialLookupPolicy     string
+		credentialSecretNamespaces []string
+		credentialSecretNames      []string
+		scopeCredentialTypes       []string
+		wantNamespace              string
+		want                       string
+		wantErr                    bool
+	}{
+		{
+			name:          "TestResourceIdentity",
+			properties:    `{"appName": "MyApp", "aliyunAccountUid": "123", "regionId": "cn-beijing"}`,
+			wantNamespace: "ros-system",
+			want:          "myapp.cn-beijing.123",
+		},
+		{
+			name:                 "TestResourceIdentityWithoutSecret",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123", "credentialType": "ecs_ram_role", "ramRoleName": "ros"}`,
+			scopeCredentialTypes: []string{"access_key", "ecs_ram_role"},
+			wantNamespace:        "",
+			want:                 "",
+		},
+		{
+			name:       "TestCredentialTypeWithoutSecretNotAllowed",
+			properties: `{"appName": "MyApp", "aliyunAccountUid": "123", "credentialType": "ecs_ram_role", "ramRoleName": "ros"}`,
+			wantErr:    true,
+		},
+		{
+			name:                 "TestCredentialTypeNotListed",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123", "credentialType": "oidc_role_arn"}`,
+			scopeCredentialTypes: []string{"ecs_ram_role"},
+			wantErr:              true,
+		},
+		{
+			name:                 "TestOidcTokenFileOfScope",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123", "credentialType": "oidc_role_arn", "oidcTokenFile": "/var/run/secrets/kubernetes.io/serviceaccount/token"}`,
+			scopeCredentialTypes: []string{"oidc_role_arn"},
+			wantErr:              true,
+		},
+		{
+			name:                 "TestDefaultTypeNotListed",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123"}`,
+			scopeCredentialTypes: []string{"ecs_ram_role"},
+			wantErr:              true,
+		},
+		{
+			name:                 "TestDefaultTypeListed",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123"}`,
+			scopeCredentialTypes: []string{"sts"},
+			wantNamespace:        "ros-system",
+			want:                 "myapp..123",
+		},
+		{
+			name:       "TestInvalidResourceIdentity",
+			properties: `{"appName": "MyApp"}`,
+			wantErr:    true,
+		},
+		{
+			name:                  "TestSecretName",
+			properties:            `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "MyCredential"}`,
+			credentialSecretNames: []string{"mycredential"},
+			wantNamespace:         "ros-system",
+			want:                  "mycredential",
+		},
+		{
+			name:       "TestSecretNameNotAllowed",
+			properties: `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "MyCredential"}`,
+			wantErr:    true,
+		},
+		{
+			name:                  "TestSecretNameNotMatched",
+			properties:            `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "other"}`,
+			credentialSecretNames: []string{"team-a-*"},
+			wantErr:               true,
+		},
+		{
+			name:                  "TestControllerSecretNotAllowed",
+			properties:            `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "Credential"}`,
+			credentialSecretName:  "credential",
+			credentialSecretNames: []string{"*"},
+			wantErr:               true,
+		},
+		{
+			name:                 "TestControllerSecretOfDefaultNameNotAllowed",
+			properties:           `{"appName": "MyApp", "aliyunAccountUid": "123", "regionId": "cn-beijing"}`,
+			credentialSecretName: "myapp.cn-beijing.123",
+			wantErr:              true,
+		},
+		{
+			name:                   "TestControllerSecretNameInApplicationNamespace",
+			properties:             `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "credential"}`,
+			credentialSecretName:   "credential",
+			credentialSecretNames:  []string{"credential"},
+			credentialLookupPolicy: config.CREDENTIAL_LOOKUP_APPLICATION,
+			wantNamespace:          "tenant",
+			want:                   "credential",
+		},
+		{
+			name:                   "TestApplicationLookupPolicy",
+			properties:             `{"appName": "MyApp", "aliyunAccountUid": "123", "secretName": "credential"}`,
+			credentialLookupPolicy: config.CREDENTIAL_LOOKUP_APPLICATION,
+			credentialSecretNames:  []string{"cred*"},
+			wantNamespace:          "tenant",
+			want:                   "credential",
+		},
+		{
+			name:                   "TestApplicationNamespaceWithoutAllowlist",
+			properties:             `{"appName": "MyApp", "aliyunAccountUid": "123", "secretNamespace": "tenant"}`,
+			credentialLookupPolicy: config.CREDENTIAL_LOOKUP_APPLICATION,
+			wantNamespace:          "tenant",
+			want:                   "myapp..123",
+		},
+		{
+			name:       "TestSecretNamespaceNotAllowed",
+			properties: `{"appName": "MyApp", "aliyunAccountUid": "123", "secretNamespace": "other"}`,
+			wantErr:    true,
+		},
+		{
+			name:                   "TestControllerNamespaceNotAllowedByApplicationPolicy",
+			properties:             `{"appName": "MyApp", "aliyunAccountUid": "123", "secretNamespace": "ros-system"}`,
+			credentialLookupPolicy: config.CREDENTIAL_LOOKUP_APPLICATION,
+			wantErr:                true,
+		},
+		{
+			name:                       "TestSecretNamespaceAllowed",
+			properties:                 `{"appName": "MyApp", "aliyunAccountUid": "123", "secretNamespace": "other"}`,
+			credentialSecretNamespaces: []string{"tenant", "other"},
+			wantNamespace:              "other",
+			want:                       "myapp..123",
+		},
+		{
+			name:                       "TestAllNamespacesAllowed",
+			properties:                 `{"appName": "MyApp", "aliyunAccountUid": "123", "secretNamespace": "other"}`,
+			credentialSecretNamespaces: []string{config.ALL_NAMESPACES},
+			wantNamespace:              "other",
+			want:                       "myapp..123",
+		},
+		{
+			name:                 "TestControllerSecret",
+			credentialSecretName: "Credential",
+			wantNamespace:        "ros-system",
+			want:                 "credential",
+		},
+		{
+			name:                   "TestControllerSecretIgnoresLookupPolicy",
+			credentialSecretName:   "credential",
+			credentialLookupPolicy: config.CREDENTIAL_LOOKUP_APPLICATION,
+			wantNamespace:          "ros-system",
+			want:                   "credential",
+		},
+		{
+			name:                 "TestControllerWithoutSecret",
+			credentialSecretName: "credential",
+			credentialType:       "oidc_role_arn",
+			wantNamespace:        "",
+			want:                 "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.RosCtrlConf.Namespace = "ros-system"
+			config.RosCtrlConf.CredentialSecretName = tt.credentialSecretName
+			config.RosCtrlConf.CredentialType = tt.credentialType
+			config.RosCtrlConf.CredentialLookupPolicy = tt.credentialLookupPolicy
+			config.RosCtrlConf.CredentialSecretNamespaces = tt.credentialSecretNamespaces
+			config.RosCtrlConf.CredentialSecretNames = tt.credentialSecretNames
+			config.RosCtrlConf.ScopeCredentialTypes = tt.scopeCredentialTypes
+			defer func() {
+				config.RosCtrlConf.Namespace = ""
+				config.RosCtrlConf.CredentialSecretName = ""
+				config.RosCtrlConf.CredentialType = ""
+				config.RosCtrlConf.CredentialLookupPolicy = ""
+				config.RosCtrlConf.CredentialSecretNamespaces = nil
+				config.RosCtrlConf.CredentialSecretNames = nil
+				config.RosCtrlConf.ScopeCredentialTypes = nil
+			}()
+
+			appConf := newAppConfWithScope(tt.properties)
+			appConf.Namespace = "tenant"
+			gotNamespace, got, err := CredentialSecretOf(appConf)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantNamespace, gotNamespace)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecretSource(t *testing.T) {
+	assert.Equal(t, "secret/tenant/credential", SecretSource("tenant", "Credential"))
+	assert.NotEqual(t, SecretSource("tenant", "credential"), SecretSource("other", "credential"))
+}
+
+func TestNewContext_AccountPolicy(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.Nil(t, indexer.Add(&rosv1alpha1.RosAccountPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+		Spec: rosv1alpha1.RosAccountPolicySpec{
+			Namespaces: []string{"team-a"},
+			AliUids:    []string{"111"},
+		},
+	}))
+	policy.Enforce(listers.NewRosAccountPolicyLister(indexer))
+	defer policy.Enforce(nil)
+
+	appConf := newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "222", "regionId": "cn-beijing"}`)
+	appConf.Namespace = "team-a"
+	_, err := NewContext(appConf, nil, nil)
+	assert.True(t, policy.IsAccountDenied(err))
+	assert.False(t, IsCredentialError(err))
+
+	// application without scope is checked against account of controller credential
+	config.RosCtrlConf.DryRun = true
+	config.RosCtrlConf.ControllerAliUid = "222"
+	defer func() {
+		config.RosCtrlConf.DryRun = false
+		config.RosCtrlConf.ControllerAliUid = ""
+	}()
+	appConf = newAppConfWithScope("")
+	appConf.Namespace = "team-a"
+	_, err = NewContext(appConf, nil, nil)
+	assert.True(t, policy.IsAccountDenied(err))
+
+	config.RosCtrlConf.ControllerAliUid = "111"
+	_, err = NewContext(appConf, nil, nil)
+	assert.Nil(t, err)
+}
+
+func TestCheckCredentialAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		aliUid   string
+		enforced bool
+		wantErr  string
+	}{
+		{name: "TestSameAccount", aliUid: "111"},
+		{name: "TestOtherAccount", aliUid: "222", wantErr: "Credential secret/team-a/credential belongs to account 222, not 111"},
+		{name: "TestUndeclared"},
+		{
+			name:     "TestUndeclaredEnforced",
+			enforced: true,
+			wantErr:  "Credential secret/team-a/credential must declare AliyunAccountUid when account policies are enforced",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.enforced {
+				policy.Enforce(listers.NewRosAccountPolicyLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})))
+				defer policy.Enforce(nil)
+			}
+			credential := &aliyun.AliyunCredential{AccessKeyId: "id", AccessKeySecret: "secret", AliUid: tt.aliUid}
+			err := checkCredentialAccount(credential, SecretSource("team-a", "credential"), "111")
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewContexts(t *testing.T) {
+	config.RosCtrlConf.DryRun = true
+	config.RosCtrlConf.RegionId = "cn-hangzhou"
+	defer func() {
+		config.RosCtrlConf.DryRun = false
+		config.RosCtrlConf.RegionId = ""
+	}()
+
+	appConf := newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-hangzhou"},
+		{"regionId": "cn-beijing", "aliyunAccountUid": "456", "parameters": {"Vpc": {"CidrBlock": "10.0.0.0/8"}}}]}`)
+	contexts, err := NewContexts(appConf, nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, contexts, 2)
+	assert.Equal(t, "cn-hangzhou", contexts[0].RegionId)
+	assert.Equal(t, "123", contexts[0].AliUid)
+	assert.Nil(t, contexts[0].Parameters)
+	assert.Equal(t, "cn-beijing", contexts[1].RegionId)
+	assert.Equal(t, "456", contexts[1].AliUid)
+	assert.Equal(t, map[string]map[string]interface{}{"Vpc": {"CidrBlock": "10.0.0.0/8"}}, contexts[1].Parameters)
+	for _, context := range contexts {
+		assert.Equal(t, []string{"123", "456"}, []string{context.Targets[0].AliUid, context.Targets[1].AliUid})
+	}
+
+	// application without targets has a single context
+	contexts, err = NewContexts(newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123"}`), nil, nil)
+	assert.Nil(t, err)
+	assert.Len(t, contexts, 1)
+	assert.Nil(t, contexts[0].Targets)
+}
+
+func TestNewRemovedContexts(t *testing.T) {
+	config.RosCtrlConf.DryRun = true
+	config.RosCtrlConf.RegionId = "cn-hangzhou"
+	defer func() {
+		config.RosCtrlConf.DryRun = false
+		config.RosCtrlConf.RegionId = ""
+	}()
+
+	// targets of first version are recorded as applied
+	appConf := newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-hangzhou"}, {"regionId": "cn-beijing", "secretName": "beijing"}]}`)
+	contexts, err := NewContexts(appConf, nil, nil)
+	assert.Nil(t, err)
+	removed, pending, err := NewRemovedContexts(appConf, contexts)
+	assert.Nil(t, err)
+	assert.Empty(t, removed)
+	assert.Empty(t, pending)
+	assert.True(t, SetAppliedIdentities(appConf, []*aliyun.AliyunResourceIdentity{contexts[0].Identity, contexts[1].Identity}))
+	assert.False(t, SetAppliedIdentities(appConf, []*aliyun.AliyunResourceIdentity{contexts[0].Identity, contexts[1].Identity}))
+
+	// target in beijing is removed
+	appConf.Spec.Scopes = newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-hangzhou"}, {"regionId": "cn-shanghai"}]}`).Spec.Scopes
+	contexts, err = NewContexts(appConf, nil, nil)
+	assert.Nil(t, err)
+	removed, pending, err = NewRemovedContexts(appConf, contexts)
+	assert.Nil(t, err)
+	assert.Empty(t, pending)
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "cn-beijing", removed[0].RegionId)
+	assert.Equal(t, "123", removed[0].AliUid)
+	assert.Equal(t, "beijing", removed[0].Identity.SecretName)
+
+	targets := TargetsOf(append(contexts, removed...), nil)
+	assert.Equal(t, []aliyun.Target{
+		{RegionId: "cn-hangzhou", AliUid: "123"},
+		{RegionId: "cn-shanghai", AliUid: "123"},
+		{RegionId: "cn-beijing", AliUid: "123"},
+	}, targets)
+
+	// all targets are removed
+	assert.True(t, SetAppliedIdentities(appConf, nil))
+	identities, err := AppliedIdentitiesOf(appConf)
+	assert.Nil(t, err)
+	assert.Nil(t, identities)
+
+	appConf.Annotations[config.APPLIED_TARGETS_ANNOTATION] = "invalid"
+	_, _, err = NewRemovedContexts(appConf, contexts)
+	assert.NotNil(t, err)
+}
+
+func TestUsesCredentialSecret(t *testing.T) {
+	config.RosCtrlConf.Namespace = "ros-system"
+	config.RosCtrlConf.CredentialSecretNames = []string{"beijing"}
+	defer func() {
+		config.RosCtrlConf.Namespace = ""
+		config.RosCtrlConf.CredentialSecretNames = nil
+	}()
+
+	appConf := newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-hangzhou"}, {"regionId": "cn-beijing", "secretName": "beijing"}]}`)
+	assert.True(t, UsesCredentialSecret(appConf, "ros-system", "myapp.cn-hangzhou.123"))
+	assert.True(t, UsesCredentialSecret(appConf, "ros-system", "Beijing"))
+	assert.False(t, UsesCredentialSecret(appConf, "ros-system", "myapp.cn-beijing.123"))
+	assert.False(t, UsesCredentialSecret(appConf, "other", "beijing"))
+
+	// secret names of targets must be allowed
+	appConf = newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-beijing", "secretName": "other"}]}`)
+	assert.False(t, UsesCredentialSecret(appConf, "ros-system", "other"))
+
+	appConf = newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123", "regionId": "cn-beijing"}`)
+	assert.True(t, UsesCredentialSecret(appConf, "ros-system", "myapp.cn-beijing.123"))
+}
+
+func TestNewContexts_CredentialError(t *testing.T) {
+	clientset := k8s.ClientManager.Clientset
+	k8s.ClientManager.Clientset = testclient.NewSimpleClientset()
+	config.RosCtrlConf.Namespace = "ros-system"
+	defer func() {
+		k8s.ClientManager.Clientset = clientset
+		config.RosCtrlConf.Namespace = ""
+	}()
+
+	// invalid scope is not a credential error
+	_, err := NewContexts(newAppConfWithScope(`{"aliyunAccountUid": "123"}`), nil, nil)
+	assert.NotNil(t, err)
+	assert.False(t, IsCredentialError(err))
+
+	// secret not allowed is not a credential error
+	_, err = NewContexts(newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123",
+		"regionId": "cn-beijing", "secretName": "other"}`), nil, nil)
+	assert.NotNil(t, err)
+	assert.False(t, IsCredentialError(err))
+
+	// credential secret which can not be read is
+	_, err = NewContexts(newAppConfWithScope(`{"appName": "MyApp", "aliyunAccountUid": "123",
+		"regionId": "cn-beijing"}`), nil, nil)
+	assert.True(t, IsCredentialError(err))
+}
+
+func TestLoadCredentialSecrets(t *testing.T) {
+	config.RosCtrlConf.Namespace = "ros-system"
+	defer func() {
+		config.RosCtrlConf.Namespace = ""
+	}()
+
+	appConf := newAppConfWithScope(`{"appName": "LoadedApp", "aliyunAccountUid": "123", "targets": [
+		{"regionId": "cn-hangzhou"}, {"regionId": "cn-beijing", "aliyunAccountUid": "456"}]}`)
+	appConf.Annotations = map[string]string{
+		config.APPLIED_TARGETS_ANNOTATION: `[{"appName": "LoadedApp", "aliyunAccountUid": "123", "regionId": "cn-shanghai"}]`,
+	}
+	assert.False(t, IsCredentialSecret("ros-system", "loadedapp.cn-hangzhou.123"))
+
+	LoadCredentialSecrets([]AppConfInterface{appConf})
+	assert.True(t, IsCredentialSecret("ros-system", "loadedapp.cn-hangzhou.123"))
+	assert.True(t, IsCredentialSecret("ros-system", "loadedapp.cn-beijing.456"))
+	// secret of removed target is still used to delete its stack
+	assert.True(t, IsCredentialSecret("ros-system", "loadedapp.cn-shanghai.123"))
+	assert.True(t, UsesCredentialSecret(appConf, "ros-system", "loadedapp.cn-shanghai.123"))
+	assert.False(t, IsCredentialSecret("ros-system", "loadedapp.cn-hangzhou.456"))
+
+	// secrets of applications which are gone are forgotten
+	LoadCredentialSecrets(nil)
+	assert.False(t, IsCredentialSecret("ros-system", "loadedapp.cn-hangzhou.123"))
+}
+
+func TestRecordCredentialSecrets(t *testing.T) {
+	clientset := k8s.ClientManager.Clientset
+	k8s.ClientManager.Clientset = testclient.NewSimpleClientset()
+	config.RosCtrlConf.Namespace = "ros-system"
+	defer func() {
+		k8s.ClientManager.Clientset = clientset
+		config.RosCtrlConf.Namespace = ""
+	}()
+
+	// recorded even if credential can not be read
+	appConf := newAppConfWithScope(`{"appName": "RecordedApp", "aliyunAccountUid": "123", "regionId": "cn-hangzhou"}`)
+	_, err := NewContexts(appConf, nil, nil)
+	assert.True(t, IsCredentialError(err))
+	assert.True(t, IsCredentialSecret("ros-system", "recordedapp.cn-hangzhou.123"))
+
+	// secrets no longer used by application are pruned
+	changed := newAppConfWithScope(`{"appName": "RecordedApp", "aliyunAccountUid": "123", "regionId": "cn-beijing"}`)
+	_, _ = NewContexts(changed, nil, nil)
+	assert.False(t, IsCredentialSecret("ros-system", "recordedapp.cn-hangzhou.123"))
+	assert.True(t, IsCredentialSecret("ros-system", "recordedapp.cn-beijing.123"))
+
+	ForgetCredentialSecrets(changed)
+	assert.False(t, IsCredentialSecret("ros-system", "recordedapp.cn-beijing.123"))
+}
+
+func TestCredentialError(t *testing.T) {
+	err := &CredentialError{Err: errors.New("secret is invalid")}
+	assert.Equal(t, "Credential is missing or invalid: secret is invalid", err.Error())
+	assert.True(t, IsCredentialError(err))
+	assert.False(t, IsCredentialError(errors.New("other")))
+}
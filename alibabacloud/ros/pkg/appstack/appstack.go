@@ -9,11 +9,14 @@ import (
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/ros"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,9 +24,14 @@ const (
 	AppStackName              = "AppStackName"
 	AppStackStatus            = "AppStackStatus"
 	AppStackOutputSecretNames = "AppStackOutputSecretNames"
+	ChangeSummary             = "ChangeSummary"
+	Revisions                 = "Revisions"
 	ProgressingAppStackInfos  = "ProgressingAppStackInfos"
 	Message                   = "Message"
 	TemplateBody              = "TemplateBody"
+	NestedStacks              = "NestedStacks"
+	AppConfNamespace          = "AppConfNamespace"
+	StackCreations            = "StackCreations"
 
 	Init        = "Init"
 	Progressing = "Progressing"
@@ -119,12 +127,19 @@ type AppStackInterface interface {
 	GetStatus() (value string, err error)
 	GetContext() (ctx *appconf.Context)
 	SetIdAndTemplate(stackId string, templateBody string) (err error)
+	SetChangeSummary(changeSummary string) (err error)
+	SetNestedStacks(nestedStacks []ros.NestedStack) (err error)
+	GetRevisions() (revisions []*Revision, err error)
+	GetRevision(number int) (revision *Revision, err error)
+	AddRevision(revision *Revision) (err error)
+	SetRevisionStatus(stackStatus string) (err error)
 	SetError(e error) (err error)
 	SetProgressing() (err error)
 	SetReady() (err error)
 	SaveOutputs(stack *ros.Stack)
 	IsProgressing() (progressing bool, err error)
 	IsFailed() (failed bool, err error)
+	OtherTargetsDeleted() (deleted bool, err error)
 	Delete() (err error)
 	WaitUntilDone() (status string)
 }
@@ -167,14 +182,6 @@ func NewAppStack(ctx *appconf.Context, opts ...AppStackOption) *AppStack {
 		status:                     Init,
 	}
 
-	if appStack.secret == nil {
-		secretName := appStack.GetSecretName()
-		appStack.secret = k8s.NewSecret(secretName)
-		appStack.name = secretName
-	} else {
-		appStack.name = appStack.secret.GetName()
-	}
-
 	if appStack.progressingAppStacksSecret == nil {
 		appStack.progressingAppStacksSecret = getGlobalProgressingAppStackInfosSecret()
 	}
@@ -191,9 +198,94 @@ func NewAppStack(ctx *appconf.Context, opts ...AppStackOption) *AppStack {
 		appStack.newSecret = k8s.NewSecret
 	}
 
+	if appStack.secret == nil {
+		secretName := appStack.secretNameOf(ctx.RegionId, ctx.AliUid)
+		appStack.secret = appStack.newSecret(secretName)
+		appStack.name = secretName
+	} else {
+		appStack.name = appStack.secret.GetName()
+	}
+
 	return appStack
 }
 
+// legacyAppStackNamespaces are namespaces of applications owning app stacks of legacy names by secret names, so
+// app stacks are named without reading secrets
+var (
+	legacyAppStackNamespaces     = make(map[string]string)
+	legacyAppStackNamespacesLock sync.RWMutex
+)
+
+// LoadLegacyAppStacks loads owners of app stacks with stacks, and is called before any app stack is created.
+// App stacks created before namespaces were recorded, whose names may be legacy names, are backfilled with
+// namespace of their owner, which is the only namespace of given applications of the legacy name. App stacks
+// whose owner is unknown or ambiguous are not adopted by any application until their AppConfNamespace is set.
+func LoadLegacyAppStacks(appConfs []appconf.AppConfInterface, opts ...k8s.SecretOption) (err error) {
+	dataOf, err := k8s.ListSecretData(opts...)
+	if err != nil {
+		return
+	}
+
+	namespaces := make(map[string]string)
+	for name, data := range dataOf {
+		if data[ros.StackId] == "" {
+			continue
+		}
+		namespace := data[AppConfNamespace]
+		if namespace == "" {
+			namespace = legacyOwnerOf(name, appConfs)
+			if namespace == "" {
+				logging.Default.Info("Owner of app stack is unknown, set AppConfNamespace of its secret to adopt it",
+					AppStackName, name)
+				continue
+			}
+			data[AppConfNamespace] = namespace
+			err = k8s.NewSecret(name, opts...).UpdateData(data)
+			if err != nil {
+				return
+			}
+			logging.Default.Info("Backfill namespace of app stack", AppStackName, name, AppConfNamespace, namespace)
+		}
+		namespaces[name] = namespace
+	}
+
+	legacyAppStackNamespacesLock.Lock()
+	defer legacyAppStackNamespacesLock.Unlock()
+	legacyAppStackNamespaces = namespaces
+	return
+}
+
+// legacyOwnerOf returns namespace of applications whose legacy stack names may be name, empty if there is no such
+// application, or there are such applications in different namespaces
+func legacyOwnerOf(name string, appConfs []appconf.AppConfInterface) (namespace string) {
+	for _, appConf := range appConfs {
+		appName := strings.ToLower(appConf.GetName())
+		if name != appName && !strings.HasSuffix(name, "-"+appName) {
+			continue
+		}
+		if namespace != "" && namespace != appConf.GetNamespace() {
+			return ""
+		}
+		namespace = appConf.GetNamespace()
+	}
+	return
+}
+
+// legacyAppStackNamespaceOf returns namespace of application owning app stack of legacy name, false if there is not
+func legacyAppStackNamespaceOf(name string) (namespace string, ok bool) {
+	legacyAppStackNamespacesLock.RLock()
+	defer legacyAppStackNamespacesLock.RUnlock()
+	namespace, ok = legacyAppStackNamespaces[name]
+	return
+}
+
+// forgetLegacyAppStack forgets owner of deleted app stack
+func forgetLegacyAppStack(name string) {
+	legacyAppStackNamespacesLock.Lock()
+	defer legacyAppStackNamespacesLock.Unlock()
+	delete(legacyAppStackNamespaces, name)
+}
+
 // LoadProgressingAppStacks returns all app stacks in Progressing and an error if there is any.
 func LoadProgressingAppStacks(
 	OamCrdClient *versioned.Clientset,
@@ -239,12 +331,10 @@ func LoadProgressingAppStacks(
 			return appStacks, err
 		}
 
-		ctx, err := appconf.NewContext(appConf, OamCrdClient, RosCrdClient)
+		ctx, err := contextOfTarget(appConf, appStackInfo, OamCrdClient, RosCrdClient)
 		if err != nil {
 			return appStacks, err
 		}
-		ctx.AliUid = appStackInfo.AliUid
-		ctx.RegionId = appStackInfo.RegionId
 
 		appStack := NewAppStack(ctx, opts...)
 		appStacks = append(appStacks, appStack)
@@ -252,6 +342,28 @@ func LoadProgressingAppStacks(
 	return
 }
 
+// contextOfTarget returns context of the target which app stack info is deployed to
+func contextOfTarget(
+	appConf appconf.AppConfInterface,
+	appStackInfo *AppStackInfo,
+	OamCrdClient *versioned.Clientset,
+	RosCrdClient *roscrd.Clientset) (*appconf.Context, error) {
+
+	ctxs, err := appconf.NewContexts(appConf, OamCrdClient, RosCrdClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, ctx := range ctxs {
+		if ctx.RegionId == appStackInfo.RegionId && ctx.AliUid == appStackInfo.AliUid {
+			return ctx, nil
+		}
+	}
+	ctx := ctxs[0]
+	ctx.AliUid = appStackInfo.AliUid
+	ctx.RegionId = appStackInfo.RegionId
+	return ctx, nil
+}
+
 // GetName returns name of app stack.
 func (c *AppStack) GetName() string {
 	return c.name
@@ -262,22 +374,29 @@ func (c *AppStack) GetAppName() string {
 	return c.ctx.AppName
 }
 
-// GetSecretName returns secret name of app stack.
+// GetSecretName returns secret name of app stack, which is also name of its stack.
 func (c *AppStack) GetSecretName() string {
-	if c.ctx.AliUid == "" {
-		return strings.ToLower(c.ctx.AppConf.GetName())
-	} else {
-		return strings.ToLower(c.ctx.RegionId + "-" + c.ctx.AliUid + "-" + c.ctx.AppConf.GetName())
+	return c.name
+}
+
+// secretNameOf returns secret name of app stack of application deployed to region and account. An app stack of
+// legacy name is kept if it is loaded by LoadLegacyAppStacks as owned by application in the same namespace.
+func (c *AppStack) secretNameOf(regionId, aliUid string) string {
+	appConf := c.ctx.AppConf
+	legacyName := naming.LegacyStackName(appConf.GetName(), regionId, aliUid)
+	if namespace, ok := legacyAppStackNamespaceOf(legacyName); ok && namespace == appConf.GetNamespace() {
+		return legacyName
 	}
+	return naming.StackName(appConf.GetNamespace(), appConf.GetName(), regionId, aliUid)
 }
 
-// GetOutputSecretName takes compInstanceName and returns the corresponding secret name.
+// GetOutputSecretName takes compInstanceName and returns the corresponding secret name. App stacks of legacy names
+// keep legacy names of output secrets.
 func (c *AppStack) GetOutputSecretName(compInstanceName string) string {
-	if c.ctx.AliUid == "" {
-		return strings.ToLower(c.ctx.AppConf.GetName() + "-" + compInstanceName)
-	} else {
-		return strings.ToLower(c.ctx.RegionId + "-" + c.ctx.AliUid + "-" + c.ctx.AppConf.GetName() + "-" + compInstanceName)
+	if c.name == naming.LegacyStackName(c.ctx.AppConf.GetName(), c.ctx.RegionId, c.ctx.AliUid) {
+		return naming.LegacyOutputSecretName(c.name, compInstanceName)
 	}
+	return naming.OutputSecretName(c.name, compInstanceName)
 }
 
 // GetData returns the data of the secret, and an error, if there is any.
@@ -295,9 +414,11 @@ func (c *AppStack) GetStack() (stack *ros.Stack, err error) {
 
 	if data != nil && data[ros.StackId] != "" {
 		stack = &ros.Stack{
-			Client: c.ctx.RosClient,
-			Id:     data[ros.StackId],
-			Name:   data[ros.StackName],
+			Client:    c.ctx.RosClient,
+			RegionId:  c.ctx.RegionId,
+			Namespace: ros.NamespaceOf(c.ctx),
+			Id:        data[ros.StackId],
+			Name:      data[ros.StackName],
 		}
 	}
 	return
@@ -320,9 +441,44 @@ func (c *AppStack) GetContext() (ctx *appconf.Context) {
 	return c.ctx
 }
 
-// SetIdAndTemplate set stack ID and template body. Returns an error if one occurs.
+// SetIdAndTemplate set stack ID and template body, and counts stacks created for app stack if stack ID changes.
+// Returns an error if one occurs.
 func (c *AppStack) SetIdAndTemplate(stackId string, templateBody string) (err error) {
-	err = c.set(ros.StackId, stackId, TemplateBody, templateBody)
+	data, err := c.secret.GetData()
+	if err != nil {
+		return
+	}
+	creations := StackCreationsOf(data)
+	if data[ros.StackId] != stackId {
+		creations++
+	}
+	err = c.set(
+		ros.StackId, stackId,
+		TemplateBody, templateBody,
+		AppConfNamespace, c.ctx.AppConf.GetNamespace(),
+		StackCreations, strconv.Itoa(creations))
+	return
+}
+
+// StackCreationsOf returns number of stacks created for app stack of data, 0 if it is not counted
+func StackCreationsOf(data map[string]string) int {
+	creations, _ := strconv.Atoi(data[StackCreations])
+	return creations
+}
+
+// SetChangeSummary set summary of the latest template changes. Returns an error if one occurs.
+func (c *AppStack) SetChangeSummary(changeSummary string) (err error) {
+	err = c.set(ChangeSummary, changeSummary)
+	return
+}
+
+// SetNestedStacks set status of nested stacks of stack. Returns an error if one occurs.
+func (c *AppStack) SetNestedStacks(nestedStacks []ros.NestedStack) (err error) {
+	value, err := json.Marshal(nestedStacks)
+	if err != nil {
+		return
+	}
+	err = c.set(NestedStacks, string(value))
 	return
 }
 
@@ -338,6 +494,9 @@ func (c *AppStack) SetError(e error) (err error) {
 	default:
 		message = e.Error()
 	}
+	if ros.IsInvalidCredential(e) {
+		message = "Credential is missing or invalid: " + message
+	}
 
 	c.status = Failed
 	err = c.set(AppStackStatus, Failed, Message, message)
@@ -471,6 +630,75 @@ func (c *AppStack) IsFailed() (failed bool, err error) {
 	return
 }
 
+// OtherTargetsDeleted returns whether app stacks of other targets of application are deleted, which is always true
+// if application has no targets, and an error, if there is any.
+func (c *AppStack) OtherTargetsDeleted() (deleted bool, err error) {
+	targetData, err := c.otherTargetData()
+	if err != nil {
+		return false, err
+	}
+	for _, data := range targetData {
+		if data[AppStackStatus] != "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// otherTargetData returns data of app stacks of other targets of application by target key
+func (c *AppStack) otherTargetData() (targetData map[string]map[string]string, err error) {
+	targetData = make(map[string]map[string]string)
+	for _, target := range c.ctx.Targets {
+		if target.RegionId == c.ctx.RegionId && target.AliUid == c.ctx.AliUid {
+			continue
+		}
+		secretName := c.secretNameOf(target.RegionId, target.AliUid)
+		data, err := c.newSecret(secretName).GetData()
+		if err != nil {
+			return nil, err
+		}
+		targetData[targetKey(target.RegionId, target.AliUid)] = data
+	}
+	return
+}
+
+// targetKey returns key of target in messages of application
+func targetKey(regionId, aliUid string) string {
+	return regionId + "/" + aliUid
+}
+
+// aggregatePhase returns phase of application deployed to several targets from phase of this app stack and app
+// stacks of other targets. Application fails if any target fails, and is ready only if all targets are ready.
+func (c *AppStack) aggregatePhase(phase v1alpha1.ApplicationPhase, message string) (v1alpha1.ApplicationPhase, string) {
+	if len(c.ctx.Targets) == 0 {
+		return phase, message
+	}
+	if phase == v1alpha1.ApplicationFailed {
+		return phase, targetKey(c.ctx.RegionId, c.ctx.AliUid) + ": " + message
+	}
+
+	targetData, err := c.otherTargetData()
+	if err != nil {
+		logging.Default.Error(err, "Get app stacks of other targets failed", AppStackName, c.name)
+		return phase, message
+	}
+	for _, target := range c.ctx.Targets {
+		key := targetKey(target.RegionId, target.AliUid)
+		data, ok := targetData[key]
+		if !ok {
+			continue
+		}
+		switch data[AppStackStatus] {
+		case Failed:
+			return v1alpha1.ApplicationFailed, key + ": " + data[Message]
+		case Ready:
+		default:
+			phase = v1alpha1.ApplicationProgressing
+		}
+	}
+	return phase, message
+}
+
 // Delete deletes outputs and data. Returns an error if one occurs.
 func (c *AppStack) Delete() (err error) {
 	// delete outputs
@@ -496,6 +724,7 @@ func (c *AppStack) Delete() (err error) {
 	if err != nil {
 		return
 	}
+	forgetLegacyAppStack(c.name)
 
 	err = c.removeProgressingAppStackInfoToSecret()
 	c.status = Deleted
@@ -581,6 +810,8 @@ func (c *AppStack) maybeSetAppCondition(phase v1alpha1.ApplicationPhase, message
 		return
 	}
 
+	phase, message = c.aggregatePhase(phase, message)
+
 	var type_ v1alpha1.ApplicationConditionType
 	if phase == v1alpha1.ApplicationFailed {
 		type_ = v1alpha1.Error
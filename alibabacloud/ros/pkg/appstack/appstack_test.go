@@ -4,15 +4,20 @@ import (
 	"errors"
 	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
 	"github.com/golang/mock/gomock"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	roscrd "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/k8s"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/ros"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"strings"
 	"testing"
 )
 
@@ -45,6 +50,28 @@ func newMockProgressingAppStackInfosSecret(ctrl *gomock.Controller, data map[str
 	return secret, data
 }
 
+// newMockSecretFactory returns factory of secrets, whose data are data of their names or empty
+func newMockSecretFactory(ctrl *gomock.Controller, dataOf map[string]map[string]string) func(name string, opts ...k8s.SecretOption) k8s.SecretInterface {
+	return func(name string, opts ...k8s.SecretOption) k8s.SecretInterface {
+		data, ok := dataOf[name]
+		if !ok {
+			data = map[string]string{}
+		}
+		secret := k8s.NewMockSecretInterface(ctrl)
+		secret.EXPECT().GetName().Return(name).AnyTimes()
+		secret.EXPECT().GetData().Return(data, nil).AnyTimes()
+		return secret
+	}
+}
+
+// setLegacyAppStacks sets owners of app stacks of legacy names, and returns function to reset them
+func setLegacyAppStacks(namespaces map[string]string) (reset func()) {
+	legacyAppStackNamespaces = namespaces
+	return func() {
+		legacyAppStackNamespaces = make(map[string]string)
+	}
+}
+
 func TestNewAppStack(t *testing.T) {
 	tests := []struct {
 		name string
@@ -53,10 +80,13 @@ func TestNewAppStack(t *testing.T) {
 			name: "TestNormal",
 		},
 	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx, _ := appconf.NewContext(&appconf.AppConf{}, nil, nil)
-			appStack := NewAppStack(ctx)
+			appStack := NewAppStack(ctx, WithSecretFactory(newMockSecretFactory(ctrl, nil)))
 			assert.NotNil(t, appStack.ctx)
 			assert.NotNil(t, appStack.secret)
 		})
@@ -101,6 +131,7 @@ func TestLoadAllProgressingAppStacks(t *testing.T) {
 			appStacks, _ := LoadProgressingAppStacks(
 				nil, nil,
 				WithProgressingAppStackInfosSecret(secret),
+				WithSecretFactory(newMockSecretFactory(ctrl, nil)),
 				WithAppConfGetter(func(
 					AppConfNamespace string,
 					AppConfName string,
@@ -121,9 +152,11 @@ func TestLoadAllProgressingAppStacks(t *testing.T) {
 
 func TestAppStack_GetSecretName(t *testing.T) {
 	type args struct {
-		AliUid   string
-		RegionId string
-		AppName  string
+		AliUid    string
+		RegionId  string
+		AppName   string
+		Namespace string
+		legacy    map[string]string
 	}
 	tests := []struct {
 		name string
@@ -133,35 +166,74 @@ func TestAppStack_GetSecretName(t *testing.T) {
 		{
 			name: "TestEmptyAliUid",
 			args: args{
-				AliUid:   "",
-				RegionId: "cn-beijing",
-				AppName:  "MyApp",
+				AliUid:    "",
+				RegionId:  "cn-beijing",
+				AppName:   "MyApp",
+				Namespace: "default",
 			},
-			want: "myapp",
+			want: naming.StackName("default", "MyApp", "cn-beijing", ""),
 		},
 		{
 			name: "TestAliUid",
 			args: args{
-				AliUid:   "123456789",
-				RegionId: "cn-beijing",
-				AppName:  "MyApp",
+				AliUid:    "123456789",
+				RegionId:  "cn-beijing",
+				AppName:   "MyApp",
+				Namespace: "default",
+			},
+			want: naming.StackName("default", "MyApp", "cn-beijing", "123456789"),
+		},
+		{
+			name: "TestLegacyName",
+			args: args{
+				AliUid:    "123456789",
+				RegionId:  "cn-beijing",
+				AppName:   "MyApp",
+				Namespace: "default",
+				legacy:    map[string]string{"cn-beijing-123456789-myapp": "default"},
 			},
 			want: "cn-beijing-123456789-myapp",
 		},
+		{
+			name: "TestLegacyNameOfOtherNamespace",
+			args: args{
+				AppName:   "MyApp",
+				Namespace: "default",
+				legacy:    map[string]string{"myapp": "other"},
+			},
+			want: naming.StackName("default", "MyApp", "", ""),
+		},
+		{
+			name: "TestLegacyNameOfOtherTarget",
+			args: args{
+				AliUid:    "123456789",
+				RegionId:  "cn-beijing",
+				AppName:   "MyApp",
+				Namespace: "default",
+				legacy:    map[string]string{"myapp": "default"},
+			},
+			want: naming.StackName("default", "MyApp", "cn-beijing", "123456789"),
+		},
 	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			defer setLegacyAppStacks(tt.args.legacy)()
 			appStack := NewAppStack(
 				&appconf.Context{
 					AppConf: &appconf.AppConf{
-						ObjectMeta: v1.ObjectMeta{Name: tt.args.AppName},
+						ObjectMeta: v1.ObjectMeta{Name: tt.args.AppName, Namespace: tt.args.Namespace},
 					},
 					AliUid:   tt.args.AliUid,
 					RegionId: tt.args.RegionId,
-				})
+				},
+				WithSecretFactory(newMockSecretFactory(ctrl, nil)))
 
 			name := appStack.GetSecretName()
 			assert.Equal(t, tt.want, name)
+			assert.Equal(t, tt.want, appStack.secret.GetName())
 		})
 	}
 }
@@ -172,6 +244,7 @@ func TestAppStack_GetOutputSecretName(t *testing.T) {
 		RegionId         string
 		AppName          string
 		CompInstanceName string
+		legacy           map[string]string
 	}
 	tests := []struct {
 		name string
@@ -179,36 +252,74 @@ func TestAppStack_GetOutputSecretName(t *testing.T) {
 		want string
 	}{
 		{
-			name: "TestEmptyAliUid",
+			name: "TestLegacyName",
 			args: args{
 				AliUid:           "",
 				RegionId:         "cn-beijing",
 				AppName:          "MyApp",
 				CompInstanceName: "MyComp",
+				legacy:           map[string]string{"myapp": "default"},
 			},
 			want: "myapp-mycomp",
 		},
 		{
-			name: "TestAliUid",
+			name: "TestLegacyNameWithAliUid",
 			args: args{
 				AliUid:           "123456789",
 				RegionId:         "cn-beijing",
 				AppName:          "MyApp",
 				CompInstanceName: "MyComp",
+				legacy:           map[string]string{"cn-beijing-123456789-myapp": "default"},
 			},
 			want: "cn-beijing-123456789-myapp-mycomp",
 		},
+		{
+			name: "TestLegacyNameWithDot",
+			args: args{
+				AliUid:           "123456789",
+				RegionId:         "cn-beijing",
+				AppName:          "MyApp",
+				CompInstanceName: "my.vpc",
+				legacy:           map[string]string{"cn-beijing-123456789-myapp": "default"},
+			},
+			want: "cn-beijing-123456789-myapp-my.vpc",
+		},
+		{
+			name: "TestNameWithDot",
+			args: args{
+				AliUid:           "123456789",
+				RegionId:         "cn-beijing",
+				AppName:          "MyApp",
+				CompInstanceName: "my.vpc",
+			},
+			want: naming.StackName("default", "MyApp", "cn-beijing", "123456789") + "-my-vpc",
+		},
+		{
+			name: "TestName",
+			args: args{
+				AliUid:           "123456789",
+				RegionId:         "cn-beijing",
+				AppName:          "MyApp",
+				CompInstanceName: "MyComp",
+			},
+			want: naming.StackName("default", "MyApp", "cn-beijing", "123456789") + "-mycomp",
+		},
 	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			defer setLegacyAppStacks(tt.args.legacy)()
 			appStack := NewAppStack(
 				&appconf.Context{
 					AppConf: &appconf.AppConf{
-						ObjectMeta: v1.ObjectMeta{Name: tt.args.AppName},
+						ObjectMeta: v1.ObjectMeta{Name: tt.args.AppName, Namespace: "default"},
 					},
 					AliUid:   tt.args.AliUid,
 					RegionId: tt.args.RegionId,
-				})
+				},
+				WithSecretFactory(newMockSecretFactory(ctrl, nil)))
 
 			name := appStack.GetOutputSecretName(tt.args.CompInstanceName)
 			assert.Equal(t, tt.want, name)
@@ -350,8 +461,10 @@ func TestAppStack_SetIdAndTemplate(t *testing.T) {
 				TemplateBody: "mybody",
 			},
 			want: map[string]string{
-				"StackId":      "abcdefgh-1234-1234-1234-abcdefghijkl",
-				"TemplateBody": "mybody",
+				"StackId":          "abcdefgh-1234-1234-1234-abcdefghijkl",
+				"TemplateBody":     "mybody",
+				"AppConfNamespace": "default",
+				"StackCreations":   "1",
 			},
 		},
 		{
@@ -365,8 +478,10 @@ func TestAppStack_SetIdAndTemplate(t *testing.T) {
 				TemplateBody: "mybody",
 			},
 			want: map[string]string{
-				"StackId":      "abcdefgh-1234-1234-1234-abcdefghijkl",
-				"TemplateBody": "mybody",
+				"StackId":          "abcdefgh-1234-1234-1234-abcdefghijkl",
+				"TemplateBody":     "mybody",
+				"AppConfNamespace": "default",
+				"StackCreations":   "1",
 			},
 		},
 		{
@@ -381,9 +496,47 @@ func TestAppStack_SetIdAndTemplate(t *testing.T) {
 				TemplateBody: "mybody",
 			},
 			want: map[string]string{
-				"StackId":      "abcdefgh-1234-1234-1234-abcdefghijkl",
-				"TemplateBody": "mybody",
-				"OtherField":   "other",
+				"StackId":          "abcdefgh-1234-1234-1234-abcdefghijkl",
+				"TemplateBody":     "mybody",
+				"OtherField":       "other",
+				"AppConfNamespace": "default",
+				"StackCreations":   "1",
+			},
+		},
+		{
+			name: "TestSameStack",
+			args: args{
+				data: map[string]string{
+					"StackId":        "abcdefgh-1234-1234-1234-abcdefghijkl",
+					"TemplateBody":   "origin",
+					"StackCreations": "2",
+				},
+				StackId:      "abcdefgh-1234-1234-1234-abcdefghijkl",
+				TemplateBody: "mybody",
+			},
+			want: map[string]string{
+				"StackId":          "abcdefgh-1234-1234-1234-abcdefghijkl",
+				"TemplateBody":     "mybody",
+				"AppConfNamespace": "default",
+				"StackCreations":   "2",
+			},
+		},
+		{
+			name: "TestRecreate",
+			args: args{
+				data: map[string]string{
+					"StackId":        "origin",
+					"TemplateBody":   "mybody",
+					"StackCreations": "2",
+				},
+				StackId:      "abcdefgh-1234-1234-1234-abcdefghijkl",
+				TemplateBody: "mybody",
+			},
+			want: map[string]string{
+				"StackId":          "abcdefgh-1234-1234-1234-abcdefghijkl",
+				"TemplateBody":     "mybody",
+				"AppConfNamespace": "default",
+				"StackCreations":   "3",
 			},
 		},
 	}
@@ -395,7 +548,7 @@ func TestAppStack_SetIdAndTemplate(t *testing.T) {
 			secret, _ := newMockAppStackSecret(ctrl, tt.args.data)
 
 			appStack := NewAppStack(
-				&appconf.Context{AppConf: &appconf.AppConf{}},
+				&appconf.Context{AppConf: &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "default"}}},
 				WithSecret(secret),
 			)
 
@@ -406,6 +559,25 @@ func TestAppStack_SetIdAndTemplate(t *testing.T) {
 	}
 }
 
+func TestAppStack_SetChangeSummary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := map[string]string{"TemplateBody": "mybody", "ChangeSummary": "origin"}
+	secret, _ := newMockAppStackSecret(ctrl, data)
+	appStack := NewAppStack(
+		&appconf.Context{AppConf: &appconf.AppConf{}},
+		WithSecret(secret),
+	)
+
+	err := appStack.SetChangeSummary(`{"Resources":[{"LogicalId":"Vpc","ResourceType":"ALIYUN::ECS::VPC","Action":"Added"}]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"TemplateBody":  "mybody",
+		"ChangeSummary": `{"Resources":[{"LogicalId":"Vpc","ResourceType":"ALIYUN::ECS::VPC","Action":"Added"}]}`,
+	}, data)
+}
+
 func TestAppStack_SetError(t *testing.T) {
 	type args struct {
 		error     error
@@ -738,7 +910,7 @@ func TestAppStack_SaveOutputs(t *testing.T) {
 				&appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "myapp"}},
 				nil, nil)
 			appSecret := k8s.NewMockSecretInterface(ctrl)
-			appSecret.EXPECT().GetName().Return("MyAppStackSecret").AnyTimes()
+			appSecret.EXPECT().GetName().Return("myapp").AnyTimes()
 			appSecret.EXPECT().UpdateData(gomock.Any()).
 				Do(func(d map[string]string) error {
 					appSecretData = d
@@ -861,3 +1033,276 @@ func TestAppStack_IsFailed(t *testing.T) {
 		})
 	}
 }
+
+// newTargetAppStack returns app stack of application in cn-hangzhou, whose other target in cn-beijing has app stack
+// of data
+func newTargetAppStack(t *testing.T, ctrl *gomock.Controller, data map[string]string) *AppStack {
+	secret := k8s.NewMockSecretInterface(ctrl)
+	secret.EXPECT().GetName().Return("cn-hangzhou-123-myapp").AnyTimes()
+
+	return NewAppStack(
+		&appconf.Context{
+			AliUid:   "123",
+			RegionId: "cn-hangzhou",
+			AppConf:  &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "MyApp"}},
+			Targets:  []aliyun.Target{{RegionId: "cn-hangzhou", AliUid: "123"}, {RegionId: "cn-beijing", AliUid: "123"}},
+		},
+		WithSecret(secret),
+		WithSecretFactory(func(name string, opts ...k8s.SecretOption) k8s.SecretInterface {
+			other := k8s.NewMockSecretInterface(ctrl)
+			assert.Equal(t, naming.StackName("", "MyApp", "cn-beijing", "123"), name)
+			other.EXPECT().GetData().Return(data, nil).AnyTimes()
+			return other
+		}),
+	)
+}
+
+func TestAppStack_aggregatePhase(t *testing.T) {
+	tests := []struct {
+		name        string
+		phase       v1alpha1.ApplicationPhase
+		message     string
+		otherData   map[string]string
+		wantPhase   v1alpha1.ApplicationPhase
+		wantMessage string
+	}{
+		{
+			name:      "TestAllReady",
+			phase:     v1alpha1.ApplicationReady,
+			otherData: map[string]string{AppStackStatus: Ready},
+			wantPhase: v1alpha1.ApplicationReady,
+		},
+		{
+			name:      "TestOtherProgressing",
+			phase:     v1alpha1.ApplicationReady,
+			otherData: map[string]string{AppStackStatus: Progressing},
+			wantPhase: v1alpha1.ApplicationProgressing,
+		},
+		{
+			name:      "TestOtherNotDeployed",
+			phase:     v1alpha1.ApplicationReady,
+			otherData: map[string]string{},
+			wantPhase: v1alpha1.ApplicationProgressing,
+		},
+		{
+			name:        "TestOtherFailed",
+			phase:       v1alpha1.ApplicationProgressing,
+			otherData:   map[string]string{AppStackStatus: Failed, Message: "quota exceeded"},
+			wantPhase:   v1alpha1.ApplicationFailed,
+			wantMessage: "cn-beijing/123: quota exceeded",
+		},
+		{
+			name:        "TestFailed",
+			phase:       v1alpha1.ApplicationFailed,
+			message:     "invalid template",
+			otherData:   map[string]string{AppStackStatus: Ready},
+			wantPhase:   v1alpha1.ApplicationFailed,
+			wantMessage: "cn-hangzhou/123: invalid template",
+		},
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appStack := newTargetAppStack(t, ctrl, tt.otherData)
+			phase, message := appStack.aggregatePhase(tt.phase, tt.message)
+			assert.Equal(t, tt.wantPhase, phase)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+
+	// application without targets
+	secret, _ := newMockAppStackSecret(ctrl, map[string]string{})
+	appStack := NewAppStack(&appconf.Context{AppConf: &appconf.AppConf{}}, WithSecret(secret))
+	phase, message := appStack.aggregatePhase(v1alpha1.ApplicationReady, "")
+	assert.Equal(t, v1alpha1.ApplicationReady, phase)
+	assert.Equal(t, "", message)
+}
+
+func TestAppStack_OtherTargetsDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deleted, err := newTargetAppStack(t, ctrl, map[string]string{}).OtherTargetsDeleted()
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = newTargetAppStack(t, ctrl, map[string]string{AppStackStatus: Progressing}).OtherTargetsDeleted()
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+}
+
+func TestAppStack_AddRevision(t *testing.T) {
+	large := strings.Repeat("a", 200*1024)
+	tests := []struct {
+		name         string
+		maxRevisions int
+		revisions    string
+		templateBody string
+		want         []*Revision
+	}{
+		{
+			name:         "TestFirst",
+			maxRevisions: 3,
+			revisions:    "",
+			want: []*Revision{
+				{Revision: 1, TemplateBody: "new", Generation: 3, CreateTime: "2020-04-01T00:00:00Z"},
+			},
+		},
+		{
+			name:         "TestAppend",
+			maxRevisions: 3,
+			revisions:    `[{"Revision":4,"TemplateBody":"old","Generation":2,"StackStatus":"CREATE_COMPLETE","CreateTime":"2020-03-01T00:00:00Z"}]`,
+			want: []*Revision{
+				{Revision: 4, TemplateBody: "old", Generation: 2, StackStatus: "CREATE_COMPLETE", CreateTime: "2020-03-01T00:00:00Z"},
+				{Revision: 5, TemplateBody: "new", Generation: 3, CreateTime: "2020-04-01T00:00:00Z"},
+			},
+		},
+		{
+			name:         "TestDropOldest",
+			maxRevisions: 2,
+			revisions:    `[{"Revision":1,"TemplateBody":"a","CreateTime":"t1"},{"Revision":2,"TemplateBody":"b","CreateTime":"t2"}]`,
+			want: []*Revision{
+				{Revision: 2, TemplateBody: "b", CreateTime: "t2"},
+				{Revision: 3, TemplateBody: "new", Generation: 3, CreateTime: "2020-04-01T00:00:00Z"},
+			},
+		},
+		{
+			name:         "TestDropOldestBySize",
+			maxRevisions: 3,
+			revisions:    `[{"Revision":1,"TemplateBody":"` + large + `","CreateTime":"t1"}]`,
+			templateBody: large,
+			want: []*Revision{
+				{Revision: 2, TemplateBody: large, Generation: 3, CreateTime: "2020-04-01T00:00:00Z"},
+			},
+		},
+		{
+			name:         "TestDropLargeTemplateBody",
+			maxRevisions: 3,
+			revisions:    `[{"Revision":1,"TemplateBody":"a","CreateTime":"t1"}]`,
+			templateBody: large + large,
+			want: []*Revision{
+				{Revision: 2, Generation: 3, CreateTime: "2020-04-01T00:00:00Z"},
+			},
+		},
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.RosCtrlConf.MaxRevisions = tt.maxRevisions
+			defer func() { config.RosCtrlConf.MaxRevisions = 0 }()
+
+			data := map[string]string{}
+			if tt.revisions != "" {
+				data[Revisions] = tt.revisions
+			}
+			secret, _ := newMockAppStackSecret(ctrl, data)
+			appStack := NewAppStack(
+				&appconf.Context{AppConf: &appconf.AppConf{}},
+				WithSecret(secret),
+			)
+
+			templateBody := tt.templateBody
+			if templateBody == "" {
+				templateBody = "new"
+			}
+			err := appStack.AddRevision(&Revision{TemplateBody: templateBody, Generation: 3, CreateTime: "2020-04-01T00:00:00Z"})
+			assert.Nil(t, err)
+			revisions, err := appStack.GetRevisions()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, revisions)
+		})
+	}
+}
+
+func TestAppStack_GetRevision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := map[string]string{
+		Revisions: `[{"Revision":1,"TemplateBody":"a","Parameters":{"VpcName":"MyVpc"},"CreateTime":"t1"},{"Revision":2,"TemplateBody":"b","CreateTime":"t2"}]`,
+	}
+	secret, _ := newMockAppStackSecret(ctrl, data)
+	appStack := NewAppStack(
+		&appconf.Context{AppConf: &appconf.AppConf{}},
+		WithSecret(secret),
+	)
+
+	revision, err := appStack.GetRevision(1)
+	assert.Nil(t, err)
+	assert.Equal(t, &Revision{Revision: 1, TemplateBody: "a", Parameters: map[string]string{"VpcName": "MyVpc"}, CreateTime: "t1"}, revision)
+
+	_, err = appStack.GetRevision(3)
+	assert.Equal(t, "Revision 3 is not found in history of application", err.Error())
+
+	err = appStack.SetRevisionStatus("UPDATE_COMPLETE")
+	assert.Nil(t, err)
+	revision, _ = appStack.GetRevision(2)
+	assert.Equal(t, "UPDATE_COMPLETE", revision.StackStatus)
+}
+
+func TestRollbackOfGeneration(t *testing.T) {
+	revisions := []*Revision{
+		{Revision: 1, Generation: 1},
+		{Revision: 2, Generation: 2},
+		{Revision: 3, Generation: 2, RollbackOf: 1},
+	}
+	assert.Equal(t, 1, RollbackOfGeneration(revisions, 2))
+	assert.Equal(t, 0, RollbackOfGeneration(revisions, 3))
+	assert.Equal(t, 0, RollbackOfGeneration(revisions[:2], 2))
+	assert.Equal(t, 0, RollbackOfGeneration(nil, 2))
+}
+
+func TestLoadLegacyAppStacks(t *testing.T) {
+	defer setLegacyAppStacks(nil)()
+
+	secretOf := func(name string, data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{ObjectMeta: v1.ObjectMeta{Namespace: "ros", Name: name}, Data: map[string][]byte{}}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+	clientSet := testclient.NewSimpleClientset(
+		// legacy app stack of the only application of its name
+		secretOf("cn-beijing-123-myapp", map[string]string{ros.StackId: "stack-1"}),
+		// legacy app stack of applications of the same name in different namespaces
+		secretOf("sharedapp", map[string]string{ros.StackId: "stack-2"}),
+		// legacy app stack without application
+		secretOf("goneapp", map[string]string{ros.StackId: "stack-3"}),
+		// app stack whose namespace is recorded
+		secretOf("otherapp", map[string]string{ros.StackId: "stack-4", AppConfNamespace: "other"}),
+		// app stack without stack
+		secretOf("newapp", map[string]string{AppStackStatus: Failed}),
+	)
+	appConfs := []appconf.AppConfInterface{
+		&appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "MyApp"}},
+		&appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "SharedApp"}},
+		&appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "other", Name: "SharedApp"}},
+		&appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "NewApp"}},
+	}
+
+	err := LoadLegacyAppStacks(appConfs, k8s.WithClientSet(clientSet), k8s.WithNamespace("ros"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"cn-beijing-123-myapp": "default", "otherapp": "other"}, legacyAppStackNamespaces)
+
+	// namespace of owner is backfilled
+	data, err := k8s.NewSecret("cn-beijing-123-myapp", k8s.WithClientSet(clientSet), k8s.WithNamespace("ros")).GetData()
+	assert.Nil(t, err)
+	assert.Equal(t, "default", data[AppConfNamespace])
+	data, err = k8s.NewSecret("sharedapp", k8s.WithClientSet(clientSet), k8s.WithNamespace("ros")).GetData()
+	assert.Nil(t, err)
+	assert.Equal(t, "", data[AppConfNamespace])
+
+	// only application of owner namespace adopts legacy app stack
+	appStack := NewAppStack(&appconf.Context{AppConf: appConfs[0], RegionId: "cn-beijing", AliUid: "123"})
+	assert.Equal(t, "cn-beijing-123-myapp", appStack.GetName())
+	otherAppConf := &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Namespace: "other", Name: "MyApp"}}
+	appStack = NewAppStack(&appconf.Context{AppConf: otherAppConf, RegionId: "cn-beijing", AliUid: "123"})
+	assert.Equal(t, naming.StackName("other", "MyApp", "cn-beijing", "123"), appStack.GetName())
+	appStack = NewAppStack(&appconf.Context{AppConf: appConfs[1]})
+	assert.Equal(t, naming.StackName("default", "SharedApp", "", ""), appStack.GetName())
+}
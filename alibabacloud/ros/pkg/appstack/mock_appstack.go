@@ -155,6 +155,92 @@ func (mr *MockAppStackInterfaceMockRecorder) SetIdAndTemplate(stackId, templateB
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdAndTemplate", reflect.TypeOf((*MockAppStackInterface)(nil).SetIdAndTemplate), stackId, templateBody)
 }
 
+// SetChangeSummary mocks base method
+func (m *MockAppStackInterface) SetChangeSummary(changeSummary string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChangeSummary", changeSummary)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChangeSummary indicates an expected call of SetChangeSummary
+func (mr *MockAppStackInterfaceMockRecorder) SetChangeSummary(changeSummary interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChangeSummary", reflect.TypeOf((*MockAppStackInterface)(nil).SetChangeSummary), changeSummary)
+}
+
+// SetNestedStacks mocks base method
+func (m *MockAppStackInterface) SetNestedStacks(nestedStacks []ros.NestedStack) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNestedStacks", nestedStacks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNestedStacks indicates an expected call of SetNestedStacks
+func (mr *MockAppStackInterfaceMockRecorder) SetNestedStacks(nestedStacks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNestedStacks", reflect.TypeOf((*MockAppStackInterface)(nil).SetNestedStacks), nestedStacks)
+}
+
+// GetRevisions mocks base method
+func (m *MockAppStackInterface) GetRevisions() ([]*Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevisions")
+	ret0, _ := ret[0].([]*Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevisions indicates an expected call of GetRevisions
+func (mr *MockAppStackInterfaceMockRecorder) GetRevisions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevisions", reflect.TypeOf((*MockAppStackInterface)(nil).GetRevisions))
+}
+
+// GetRevision mocks base method
+func (m *MockAppStackInterface) GetRevision(number int) (*Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevision", number)
+	ret0, _ := ret[0].(*Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevision indicates an expected call of GetRevision
+func (mr *MockAppStackInterfaceMockRecorder) GetRevision(number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevision", reflect.TypeOf((*MockAppStackInterface)(nil).GetRevision), number)
+}
+
+// AddRevision mocks base method
+func (m *MockAppStackInterface) AddRevision(revision *Revision) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRevision", revision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRevision indicates an expected call of AddRevision
+func (mr *MockAppStackInterfaceMockRecorder) AddRevision(revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRevision", reflect.TypeOf((*MockAppStackInterface)(nil).AddRevision), revision)
+}
+
+// SetRevisionStatus mocks base method
+func (m *MockAppStackInterface) SetRevisionStatus(stackStatus string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRevisionStatus", stackStatus)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRevisionStatus indicates an expected call of SetRevisionStatus
+func (mr *MockAppStackInterfaceMockRecorder) SetRevisionStatus(stackStatus interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRevisionStatus", reflect.TypeOf((*MockAppStackInterface)(nil).SetRevisionStatus), stackStatus)
+}
+
 // SetError mocks base method
 func (m *MockAppStackInterface) SetError(e error) error {
 	m.ctrl.T.Helper()
@@ -239,6 +325,21 @@ func (mr *MockAppStackInterfaceMockRecorder) IsFailed() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsFailed", reflect.TypeOf((*MockAppStackInterface)(nil).IsFailed))
 }
 
+// OtherTargetsDeleted mocks base method
+func (m *MockAppStackInterface) OtherTargetsDeleted() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OtherTargetsDeleted")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OtherTargetsDeleted indicates an expected call of OtherTargetsDeleted
+func (mr *MockAppStackInterfaceMockRecorder) OtherTargetsDeleted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OtherTargetsDeleted", reflect.TypeOf((*MockAppStackInterface)(nil).OtherTargetsDeleted))
+}
+
 // Delete mocks base method
 func (m *MockAppStackInterface) Delete() error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,124 @@
+package appstack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+)
+
+// maxRevisionsSize is the max size in bytes of revisions kept in app stack secret, which also holds the current
+// template body and must not exceed the 1MiB limit of secrets
+const maxRevisionsSize = 256 * 1024
+
+// Revision is a template applied to the stack of application
+type Revision struct {
+	Revision     int               `json:"Revision"`
+	TemplateBody string            `json:"TemplateBody"`
+	Parameters   map[string]string `json:"Parameters,omitempty"`
+	// Generation is the generation of application configuration which produces the revision
+	Generation  int64  `json:"Generation"`
+	StackStatus string `json:"StackStatus,omitempty"`
+	CreateTime  string `json:"CreateTime"`
+	// RollbackOf is the revision which is rolled back to, if the revision is produced by rollback
+	RollbackOf int `json:"RollbackOf,omitempty"`
+}
+
+// GetRevisions returns applied revisions from oldest to latest, and an error, if there is any.
+func (c *AppStack) GetRevisions() (revisions []*Revision, err error) {
+	value, err := c.get(Revisions)
+	if err != nil || value == "" {
+		return
+	}
+	err = json.Unmarshal([]byte(value), &revisions)
+	return
+}
+
+// GetRevision takes revision number and returns the revision, and an error, if there is any.
+func (c *AppStack) GetRevision(number int) (revision *Revision, err error) {
+	revisions, err := c.GetRevisions()
+	if err != nil {
+		return
+	}
+	for _, r := range revisions {
+		if r.Revision == number {
+			if r.TemplateBody == "" {
+				return nil, errors.New(fmt.Sprintf("Template of revision %d is too large to be kept in history", number))
+			}
+			return r, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Revision %d is not found in history of application", number))
+}
+
+// AddRevision numbers revision as the latest one and saves it. Oldest revisions beyond
+// the max revisions or the max size are dropped, and the template body of the latest one is
+// dropped too if it exceeds the max size alone. Returns an error if one occurs.
+func (c *AppStack) AddRevision(revision *Revision) (err error) {
+	revisions, err := c.GetRevisions()
+	if err != nil {
+		return
+	}
+
+	revision.Revision = 1
+	if len(revisions) > 0 {
+		revision.Revision = revisions[len(revisions)-1].Revision + 1
+	}
+	if revision.CreateTime == "" {
+		revision.CreateTime = time.Now().UTC().Format(time.RFC3339)
+	}
+	revisions = append(revisions, revision)
+
+	maxRevisions := config.RosCtrlConf.MaxRevisions
+	if maxRevisions > 0 && len(revisions) > maxRevisions {
+		revisions = revisions[len(revisions)-maxRevisions:]
+	}
+	for len(revisions) > 1 && revisionsSize(revisions) > maxRevisionsSize {
+		revisions = revisions[1:]
+	}
+	if revisionsSize(revisions) > maxRevisionsSize {
+		revision.TemplateBody = ""
+	}
+	return c.setRevisions(revisions)
+}
+
+// revisionsSize returns size of revisions saved in k8s secret
+func revisionsSize(revisions []*Revision) int {
+	value, _ := json.Marshal(revisions)
+	return len(value)
+}
+
+// RollbackOfGeneration returns the revision rolled back to by the latest revision if it is produced by rolling back
+// for generation of application configuration, or 0 otherwise
+func RollbackOfGeneration(revisions []*Revision, generation int64) int {
+	if len(revisions) == 0 {
+		return 0
+	}
+	latest := revisions[len(revisions)-1]
+	if latest.Generation != generation {
+		return 0
+	}
+	return latest.RollbackOf
+}
+
+// SetRevisionStatus sets stack status of the latest revision. Returns an error if one occurs.
+func (c *AppStack) SetRevisionStatus(stackStatus string) (err error) {
+	revisions, err := c.GetRevisions()
+	if err != nil || len(revisions) == 0 {
+		return
+	}
+	revisions[len(revisions)-1].StackStatus = stackStatus
+	return c.setRevisions(revisions)
+}
+
+// setRevisions saves revisions to k8s secret
+func (c *AppStack) setRevisions(revisions []*Revision) (err error) {
+	value, err := json.Marshal(revisions)
+	if err != nil {
+		return
+	}
+	err = c.set(Revisions, string(value))
+	return
+}
@@ -26,6 +26,10 @@ type FakeRosV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeRosV1alpha1) RosAccountPolicies() v1alpha1.RosAccountPolicyInterface {
+	return &FakeRosAccountPolicies{c}
+}
+
 func (c *FakeRosV1alpha1) RosStacks(namespace string) v1alpha1.RosStackInterface {
 	return &FakeRosStacks{c, namespace}
 }
@@ -16,4 +16,6 @@ limitations under the License.
 
 package v1alpha1
 
+type RosAccountPolicyExpansion interface{}
+
 type RosStackExpansion interface{}
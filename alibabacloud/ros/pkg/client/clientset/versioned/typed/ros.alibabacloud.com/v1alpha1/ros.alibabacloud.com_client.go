@@ -24,6 +24,7 @@ import (
 
 type RosV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	RosAccountPoliciesGetter
 	RosStacksGetter
 }
 
@@ -32,6 +33,10 @@ type RosV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *RosV1alpha1Client) RosAccountPolicies() RosAccountPolicyInterface {
+	return newRosAccountPolicies(c)
+}
+
 func (c *RosV1alpha1Client) RosStacks(namespace string) RosStackInterface {
 	return newRosStacks(c, namespace)
 }
@@ -51,6 +51,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=ros.alibabacloud.com, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("rosaccountpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ros().V1alpha1().RosAccountPolicies().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("rosstacks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ros().V1alpha1().RosStacks().Informer()}, nil
 
@@ -22,6 +22,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// RosAccountPolicies returns a RosAccountPolicyInformer.
+	RosAccountPolicies() RosAccountPolicyInformer
 	// RosStacks returns a RosStackInformer.
 	RosStacks() RosStackInformer
 }
@@ -37,6 +39,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// RosAccountPolicies returns a RosAccountPolicyInformer.
+func (v *version) RosAccountPolicies() RosAccountPolicyInformer {
+	return &rosAccountPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // RosStacks returns a RosStackInformer.
 func (v *version) RosStacks() RosStackInformer {
 	return &rosStackInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
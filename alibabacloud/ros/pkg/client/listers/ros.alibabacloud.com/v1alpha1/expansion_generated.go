@@ -16,6 +16,10 @@ limitations under the License.
 
 package v1alpha1
 
+// RosAccountPolicyListerExpansion allows custom methods to be added to
+// RosAccountPolicyLister.
+type RosAccountPolicyListerExpansion interface{}
+
 // RosStackListerExpansion allows custom methods to be added to
 // RosStackLister.
 type RosStackListerExpansion interface{}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 )
 
 const (
@@ -13,6 +14,27 @@ const (
 	ROS_GROUP              = "ros.aliyun.com"
 	ROS_FINALIZER          = "ros.aliyun.com/ros-finalizer"
 	BASE_USER_AGENT        = "ros-oam"
+
+	// annotations of application configuration
+	ALLOW_REPLACEMENT_ANNOTATION    = "ros.aliyun.com/allow-replacement"
+	ROLLBACK_TO_REVISION_ANNOTATION = "ros.aliyun.com/rollback-to-revision"
+	CREDENTIAL_CHANGED_ANNOTATION   = "ros.aliyun.com/credential-changed-at"
+	NESTED_STACKS_ANNOTATION        = "ros.aliyun.com/nested-stacks"
+	APPLIED_TARGETS_ANNOTATION      = "ros.aliyun.com/applied-targets"
+
+	// tag of ROS stacks, whose value is uid of the application owning the stack
+	APP_UID_TAG = "ros.aliyun.com/app-uid"
+
+	// credential lookup policies, which decide default namespace of credential secrets of resource identity scopes
+	CREDENTIAL_LOOKUP_CONTROLLER  = "controller"
+	CREDENTIAL_LOOKUP_APPLICATION = "application"
+
+	// allows credential secrets in any namespace
+	ALL_NAMESPACES = "*"
+
+	// template stores, which store template bodies larger than threshold
+	TEMPLATE_STORE_OSS = "oss"
+	TEMPLATE_STORE_ROS = "ros"
 )
 
 var (
@@ -38,20 +60,60 @@ type RosControllerConfig struct {
 	LogFilePath string
 
 	// API
-	Endpoint string
-	RegionId string
+	Endpoint         string
+	RegionId         string
+	RosApiMaxRetries int
+	RosApiQps        float64
+	RosApiBurst      int
+
+	// Regions
+	RegionsRefreshInterval time.Duration
+
+	// Template store
+	TemplateStore        string
+	TemplateUrlThreshold int
+	TemplateOssBucket    string
+	TemplateOssEndpoint  string
+	TemplateOssRegionId  string
 
 	// AK
 	AccessKeyId          string
 	AccessKeySecret      string
 	CredentialSecretName string
 
+	// Credential secret lookup
+	CredentialLookupPolicy     string
+	CredentialSecretNamespaces []string
+	CredentialSecretNames      []string
+	// ScopeCredentialTypes are credential types allowed in resource identity scopes, empty allows types whose
+	// credentials are read from secrets
+	ScopeCredentialTypes []string
+
+	// Account policy
+	EnforceAccountPolicy bool
+	// ControllerAliUid is account of controller credential, which is checked by account policies for applications
+	// without resource identity scope
+	ControllerAliUid string
+
+	// Credential provider
+	CredentialType  string
+	RoleArn         string
+	RoleSessionName string
+	RolePolicy      string
+	EcsRamRoleName  string
+	OidcProviderArn string
+	OidcTokenFile   string
+
 	// Lifecycle
 	UpdateApp          bool
 	StackCheckInterval int
+	MaxRevisions       int
 
 	// dryRun
 	DryRun bool
+
+	// Webhook
+	EnableWebhook bool
 }
 
 func InitRosCtrlConf(
@@ -66,12 +128,37 @@ func InitRosCtrlConf(
 	updateApp bool,
 	serviceUserAgent string,
 	dryRun bool,
-	workAsRosCrd bool) {
+	workAsRosCrd bool,
+	enableWebhook bool,
+	maxRevisions int,
+	rosApiMaxRetries int,
+	rosApiQps float64,
+	rosApiBurst int,
+	credentialType string,
+	roleArn string,
+	roleSessionName string,
+	rolePolicy string,
+	ecsRamRoleName string,
+	oidcProviderArn string,
+	oidcTokenFile string,
+	credentialLookupPolicy string,
+	credentialSecretNamespaces string,
+	credentialSecretNames string,
+	scopeCredentialTypes string,
+	enforceAccountPolicy bool,
+	controllerAliUid string,
+	regionsRefreshInterval time.Duration,
+	templateStore string,
+	templateUrlThreshold int,
+	templateOssBucket string,
+	templateOssEndpoint string,
+	templateOssRegionId string) {
 
 	RosCtrlConf.Env = env
 	RosCtrlConf.WorkAsRosCrd = workAsRosCrd
 	RosCtrlConf.StackCheckInterval = 5
 	RosCtrlConf.UpdateApp = updateApp
+	RosCtrlConf.MaxRevisions = maxRevisions
 
 	if endpoint != "" {
 		RosCtrlConf.Endpoint = endpoint
@@ -81,7 +168,19 @@ func InitRosCtrlConf(
 		RosCtrlConf.RegionId = regionId
 	}
 
+	RosCtrlConf.RosApiMaxRetries = rosApiMaxRetries
+	RosCtrlConf.RosApiQps = rosApiQps
+	RosCtrlConf.RosApiBurst = rosApiBurst
+	RosCtrlConf.RegionsRefreshInterval = regionsRefreshInterval
+
+	RosCtrlConf.TemplateStore = templateStore
+	RosCtrlConf.TemplateUrlThreshold = templateUrlThreshold
+	RosCtrlConf.TemplateOssBucket = templateOssBucket
+	RosCtrlConf.TemplateOssEndpoint = templateOssEndpoint
+	RosCtrlConf.TemplateOssRegionId = templateOssRegionId
+
 	RosCtrlConf.DryRun = dryRun
+	RosCtrlConf.EnableWebhook = enableWebhook
 
 	// dry run don't do real action, so we don't need ak/sk here.
 	if !dryRun {
@@ -99,6 +198,27 @@ func InitRosCtrlConf(
 		if credentialSecretName == "" {
 			RosCtrlConf.CredentialSecretName = os.Getenv("CREDENTIAL_SECRET_NAME")
 		}
+
+		RosCtrlConf.CredentialType = credentialType
+		RosCtrlConf.RoleSessionName = roleSessionName
+		RosCtrlConf.RolePolicy = rolePolicy
+		RosCtrlConf.EcsRamRoleName = ecsRamRoleName
+
+		// RRSA of ACK cluster injects role arn, oidc provider arn and token file by env
+		RosCtrlConf.RoleArn = roleArn
+		if roleArn == "" {
+			RosCtrlConf.RoleArn = os.Getenv("ALIBABA_CLOUD_ROLE_ARN")
+		}
+
+		RosCtrlConf.OidcProviderArn = oidcProviderArn
+		if oidcProviderArn == "" {
+			RosCtrlConf.OidcProviderArn = os.Getenv("ALIBABA_CLOUD_OIDC_PROVIDER_ARN")
+		}
+
+		RosCtrlConf.OidcTokenFile = oidcTokenFile
+		if oidcTokenFile == "" {
+			RosCtrlConf.OidcTokenFile = os.Getenv("ALIBABA_CLOUD_OIDC_TOKEN_FILE")
+		}
 	}
 
 	RosCtrlConf.LeaderElectionNamespace = leaderElectionNamespace
@@ -111,6 +231,17 @@ func InitRosCtrlConf(
 		RosCtrlConf.Namespace = os.Getenv("NAMESPACE")
 	}
 
+	RosCtrlConf.CredentialLookupPolicy = credentialLookupPolicy
+	if credentialLookupPolicy == "" {
+		RosCtrlConf.CredentialLookupPolicy = CREDENTIAL_LOOKUP_CONTROLLER
+	}
+	RosCtrlConf.CredentialSecretNamespaces = splitList(credentialSecretNamespaces)
+	RosCtrlConf.CredentialSecretNames = splitList(strings.ToLower(credentialSecretNames))
+	RosCtrlConf.ScopeCredentialTypes = splitList(scopeCredentialTypes)
+
+	RosCtrlConf.EnforceAccountPolicy = enforceAccountPolicy
+	RosCtrlConf.ControllerAliUid = controllerAliUid
+
 	RosCtrlConf.UserAgent = BASE_USER_AGENT
 	if serviceUserAgent != "" {
 		RosCtrlConf.UserAgent = BASE_USER_AGENT + ":" + strings.ReplaceAll(serviceUserAgent, " ", "-")
@@ -133,3 +264,26 @@ func InitRosCtrlConf(
 	}
 
 }
+
+// Redacted returns a copy of config without secret material, which is safe to be logged
+func (c RosControllerConfig) Redacted() RosControllerConfig {
+	if c.AccessKeySecret != "" {
+		c.AccessKeySecret = "******"
+	}
+	return c
+}
+
+// CrossNamespaceSecrets returns true if credential secrets may be read out of namespace of controller
+func (c RosControllerConfig) CrossNamespaceSecrets() bool {
+	return c.CredentialLookupPolicy == CREDENTIAL_LOOKUP_APPLICATION || len(c.CredentialSecretNamespaces) > 0
+}
+
+// splitList returns non-empty items of comma separated list
+func splitList(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
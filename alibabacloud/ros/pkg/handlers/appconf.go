@@ -4,17 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appstack"
 	roscrd "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/ros"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/oam-dev/oam-go-sdk/pkg/client/clientset/versioned"
 	"github.com/oam-dev/oam-go-sdk/pkg/finalizer"
 	"github.com/oam-dev/oam-go-sdk/pkg/oam"
 	ks8errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"strconv"
+	"time"
 )
 
 type AppConfHandler struct {
@@ -49,12 +54,145 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 	}
 	logging.Default.Info(fmt.Sprintf("Handle create or update appConf: \n%s", string(appConfStr)))
 
-	// ros context
-	appContext, err := appconf.NewContext(appConf, a.OamCrdClient, a.RosCrdClient)
+	// ros contexts, one per target
+	appContexts, err := appconf.NewContexts(appConf, a.OamCrdClient, a.RosCrdClient)
 	if err != nil {
+		if appconf.IsCredentialError(err) {
+			a.setCredentialError(appConf, err)
+		}
 		return
 	}
 
+	for _, appContext := range appContexts {
+		if e := createOrUpdateTarget(appContext, appConf); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	// stacks of targets removed from application
+	if e := deleteRemovedTargets(appConf, appContexts); e != nil && err == nil {
+		err = e
+	}
+
+	if e := clearRollbackAnnotation(appConf, appContexts); e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// clearRollbackAnnotation removes rollback annotation from application once stacks of all targets are rolled back
+// for the current generation of application, so that later changes of application are applied again
+func clearRollbackAnnotation(appConf *appconf.AppConf, appContexts []*appconf.Context) error {
+	value, ok := appConf.GetAnnotations()[config.ROLLBACK_TO_REVISION_ANNOTATION]
+	if !ok {
+		return nil
+	}
+	rollbackOf, err := strconv.Atoi(value)
+	if err != nil {
+		// invalid annotation is reported by app stacks
+		return nil
+	}
+
+	for _, appContext := range appContexts {
+		revisions, err := appstack.NewAppStack(appContext).GetRevisions()
+		if err != nil {
+			return err
+		}
+		if appstack.RollbackOfGeneration(revisions, appConf.Generation) != rollbackOf {
+			return nil
+		}
+	}
+
+	logging.Default.Info("Rollback is done, remove rollback annotation", "AppConfName", appConf.GetName(),
+		"Revision", rollbackOf)
+	current, err := appconf.GetAppConfFromContext(appContexts[0])
+	if err != nil {
+		return err
+	}
+	object := current.ToObject()
+	annotations := object.GetAnnotations()
+	if annotations[config.ROLLBACK_TO_REVISION_ANNOTATION] != value {
+		return nil
+	}
+	delete(annotations, config.ROLLBACK_TO_REVISION_ANNOTATION)
+	object.SetAnnotations(annotations)
+	return current.Update(appContexts[0], current)
+}
+
+// deleteRemovedTargets deletes stacks of targets which application is applied to but which are removed from it,
+// and records targets application is applied to in its annotation. A removed target stays recorded until its app
+// stack is deleted.
+func deleteRemovedTargets(appConf *appconf.AppConf, appContexts []*appconf.Context) (err error) {
+	if config.RosCtrlConf.DryRun {
+		return nil
+	}
+
+	removedContexts, pending, err := appconf.NewRemovedContexts(appConf, appContexts)
+	if err != nil {
+		return err
+	}
+
+	var identities []*aliyun.AliyunResourceIdentity
+	for _, appContext := range appContexts {
+		if appContext.Identity != nil {
+			identities = append(identities, appContext.Identity)
+		}
+	}
+	identities = append(identities, pending...)
+
+	targets := appconf.TargetsOf(append(appContexts, removedContexts...), pending)
+	for _, removedContext := range removedContexts {
+		removedContext.Targets = targets
+		deleted, e := deleteRemovedTarget(removedContext, appConf)
+		if e != nil {
+			logging.Default.Error(e, "Delete stack of removed target failed", "AppConfName", appConf.GetName(),
+				"RegionId", removedContext.RegionId, "AliUid", removedContext.AliUid)
+			if err == nil {
+				err = e
+			}
+		}
+		if !deleted {
+			identities = append(identities, removedContext.Identity)
+		}
+	}
+
+	return setAppliedIdentities(appContexts[0], identities)
+}
+
+// deleteRemovedTarget deletes stack of removed target of context, and returns true once its app stack is deleted
+func deleteRemovedTarget(appContext *appconf.Context, appConf *appconf.AppConf) (deleted bool, err error) {
+	appStack := appstack.NewAppStack(appContext)
+	status, err := appStack.GetStatus()
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case "", appstack.Deleted:
+		return true, nil
+	case appstack.Progressing:
+		logging.Default.Info("Removed target is still progressing", appstack.AppStackName, appStack.GetName())
+		return false, nil
+	}
+
+	logging.Default.Info("Delete stack of removed target", appstack.AppStackName, appStack.GetName())
+	return false, deleteTarget(appContext, appConf)
+}
+
+// setAppliedIdentities records resource identities of targets which application is applied to
+func setAppliedIdentities(appContext *appconf.Context, identities []*aliyun.AliyunResourceIdentity) error {
+	appConf, err := appconf.GetAppConfFromContext(appContext)
+	if err != nil {
+		return err
+	}
+	if !appconf.SetAppliedIdentities(appConf, identities) {
+		return nil
+	}
+	logging.Default.Info("Record applied targets", "AppConfName", appConf.GetName())
+	return appConf.Update(appContext, appConf)
+}
+
+// createOrUpdateTarget creates or updates stack of application in target of context
+func createOrUpdateTarget(appContext *appconf.Context, appConf *appconf.AppConf) (err error) {
 	// app stack
 	appStack := appstack.NewAppStack(appContext)
 	appStackName := appStack.GetName()
@@ -69,15 +207,24 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 		return
 	}
 
+	// the stack stays on the revision rolled back to until application configuration is changed
+	revisions, err := appStack.GetRevisions()
+	if err != nil {
+		return
+	}
+	_, rollingBack := appConf.GetAnnotations()[config.ROLLBACK_TO_REVISION_ANNOTATION]
+	if !rollingBack && appstack.RollbackOfGeneration(revisions, appConf.Generation) > 0 {
+		logging.Default.Info("Application stays on the revision rolled back to until it is changed",
+			appstack.AppStackName, appStackName)
+		return
+	}
+
 	// template
-	logging.Default.Info("Generating ROS template for application", appstack.AppStackName, appStackName)
-	template, err := ros.NewTemplate(appContext, appConf)
+	template, rollbackOf, err := getTemplate(appContext, appConf, appStack)
 	if err != nil {
-		logging.Default.Error(err, "Generate ROS template for application failed", appstack.AppStackName, appStackName)
 		err = appStack.SetError(err)
 		return
 	}
-	logging.Default.Info("Generate ROS template for application successfully", appstack.AppStackName, appStackName)
 
 	// check template same
 	templateBodyByte, _ := template.Marshal()
@@ -90,10 +237,38 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 	if err != nil {
 		return
 	}
-	if !isFailed && appStackData[appstack.TemplateBody] == templateBody {
+	diff, err := ros.DiffTemplates(appStackData[appstack.TemplateBody], templateBody)
+	if err != nil {
+		return
+	}
+	if !isFailed && diff.IsEmpty() {
 		logging.Default.Info("Application stack template is completely same", appstack.AppStackName, appStackName)
+		// rolling back to the template applied already is recorded too, so that the rollback is done
+		if rollbackOf > 0 && appstack.RollbackOfGeneration(revisions, appConf.Generation) != rollbackOf {
+			revision := &appstack.Revision{
+				TemplateBody: templateBody,
+				Parameters:   template.ParameterValues(),
+				Generation:   appConf.Generation,
+				RollbackOf:   rollbackOf,
+			}
+			if len(revisions) > 0 {
+				revision.StackStatus = revisions[len(revisions)-1].StackStatus
+			}
+			err = appStack.AddRevision(revision)
+		}
 		return
 	}
+	logging.Default.Info("Application stack template changed", appstack.AppStackName, appStackName, "Changes", diff.String())
+
+	// check replacement
+	if !ros.IsReplacementAllowed(appConf.GetAnnotations()) {
+		err = template.CheckReplacement(diff)
+		if err != nil {
+			logging.Default.Error(err, "Update application stack is blocked", appstack.AppStackName, appStackName)
+			err = appStack.SetError(err)
+			return
+		}
+	}
 
 	// get stack
 	stackName := appStackName
@@ -108,11 +283,13 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 		return err
 	}
 
+	var stackStatus ros.StackStatusType
 	if stack == nil {
 		// create stack
 		logging.Default.Info("Creating ROS stack", ros.StackName, stackName)
-		stack, err = ros.NewStack(appContext, stackName, template)
+		stack, stackStatus, err = createStack(appContext, stackName, template, appstack.StackCreationsOf(appStackData))
 		if err != nil {
+			metrics.StackOperationSubmitFailed(metrics.CreateOperation, appContext.RegionId, appConf.Namespace)
 			err = appStack.SetError(err)
 			return err
 		}
@@ -132,8 +309,9 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 			} else if ros.IsStackNotFound(err) {
 				// create stack
 				logging.Default.Info("Stack not exist. Creating ROS stack", ros.StackName, stackName)
-				stack, err = ros.NewStack(appContext, stackName, template)
+				stack, stackStatus, err = createStack(appContext, stackName, template, appstack.StackCreationsOf(appStackData))
 				if err != nil {
+					metrics.StackOperationSubmitFailed(metrics.CreateOperation, appContext.RegionId, appConf.Namespace)
 					err = appStack.SetError(err)
 					return err
 				}
@@ -142,6 +320,7 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 					return err
 				}
 			} else {
+				metrics.StackOperationSubmitFailed(metrics.UpdateOperation, appContext.RegionId, appConf.Namespace)
 				err = appStack.SetError(err)
 				return err
 			}
@@ -150,15 +329,72 @@ func (a *AppConfHandler) CreateOrUpdate(ctx *oam.ActionContext, appConf *appconf
 			if err != nil {
 				return err
 			}
+			stackStatus = ros.UpdateInProgress
 		}
 		err = appStack.SetProgressing()
 	}
 
+	// save change summary
+	changeSummary, _ := diff.Marshal()
+	if e := appStack.SetChangeSummary(string(changeSummary)); e != nil {
+		logging.Default.Error(e, "Save change summary failed", appstack.AppStackName, appStackName)
+	}
+
+	// save revision
+	revision := &appstack.Revision{
+		TemplateBody: templateBody,
+		Parameters:   template.ParameterValues(),
+		Generation:   appConf.Generation,
+		StackStatus:  string(stackStatus),
+		RollbackOf:   rollbackOf,
+	}
+	if e := appStack.AddRevision(revision); e != nil {
+		logging.Default.Error(e, "Save revision failed", appstack.AppStackName, appStackName)
+	}
+
 	go waitStackDoneAndSaveOutputs(appContext, appStack, stack)
 
 	return
 }
 
+// getTemplate renders ROS template from application configuration, or loads template of the revision
+// to roll back to if annotation is set. Returns the revision rolled back to if there is.
+func getTemplate(appContext *appconf.Context, appConf *appconf.AppConf, appStack appstack.AppStackInterface) (
+	template *ros.Template, rollbackOf int, err error) {
+	appStackName := appStack.GetName()
+
+	// rollback
+	if value, ok := appConf.GetAnnotations()[config.ROLLBACK_TO_REVISION_ANNOTATION]; ok {
+		rollbackOf, err = strconv.Atoi(value)
+		if err != nil || rollbackOf <= 0 {
+			return nil, 0, errors.New(fmt.Sprintf(
+				"Invalid annotation '%s: %s', which must be a positive revision number",
+				config.ROLLBACK_TO_REVISION_ANNOTATION, value))
+		}
+
+		logging.Default.Info("Loading ROS template of revision", appstack.AppStackName, appStackName, "Revision", rollbackOf)
+		revision, err := appStack.GetRevision(rollbackOf)
+		if err != nil {
+			return nil, 0, err
+		}
+		template, err = ros.LoadTemplate(appContext, revision.TemplateBody, revision.Parameters)
+		if err != nil {
+			logging.Default.Error(err, "Load ROS template of revision failed", appstack.AppStackName, appStackName)
+			return nil, 0, err
+		}
+		return template, rollbackOf, nil
+	}
+
+	logging.Default.Info("Generating ROS template for application", appstack.AppStackName, appStackName)
+	template, err = ros.NewTemplate(appContext, appConf)
+	if err != nil {
+		logging.Default.Error(err, "Generate ROS template for application failed", appstack.AppStackName, appStackName)
+		return nil, 0, err
+	}
+	logging.Default.Info("Generate ROS template for application successfully", appstack.AppStackName, appStackName)
+	return template, 0, nil
+}
+
 func (a *AppConfHandler) Delete(ctx *oam.ActionContext, appConf *appconf.AppConf) (err error) {
 	appConfStr, err := json.MarshalIndent(appConf, "", "  ")
 	if err != nil {
@@ -166,12 +402,35 @@ func (a *AppConfHandler) Delete(ctx *oam.ActionContext, appConf *appconf.AppConf
 	}
 	logging.Default.Info(fmt.Sprintf("Handle delete AppConf: \n%s", string(appConfStr)))
 
-	// ros context
-	appContext, err := appconf.NewContext(appConf, a.OamCrdClient, a.RosCrdClient)
+	// ros contexts, one per target
+	appContexts, err := appconf.NewContexts(appConf, a.OamCrdClient, a.RosCrdClient)
 	if err != nil {
 		return
 	}
 
+	// stacks of targets removed from application are deleted too, and finalizer is kept until all are deleted
+	removedContexts, pending, err := appconf.NewRemovedContexts(appConf, appContexts)
+	if err != nil {
+		return
+	}
+	if len(removedContexts) > 0 || len(pending) > 0 {
+		targets := appconf.TargetsOf(append(appContexts, removedContexts...), pending)
+		appContexts = append(appContexts, removedContexts...)
+		for _, appContext := range appContexts {
+			appContext.Targets = targets
+		}
+	}
+
+	for _, appContext := range appContexts {
+		if e := deleteTarget(appContext, appConf); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// deleteTarget deletes stack of application in target of context
+func deleteTarget(appContext *appconf.Context, appConf *appconf.AppConf) (err error) {
 	// app stack
 	appStack := appstack.NewAppStack(appContext)
 	appStackName := appStack.GetName()
@@ -197,7 +456,12 @@ func (a *AppConfHandler) Delete(ctx *oam.ActionContext, appConf *appconf.AppConf
 
 	if stack == nil {
 		logging.Default.Info("No need to delete stack. There is no stack for application", appstack.AppStackName, appStackName)
-		err = removeCleanUpFinalizer(appContext)
+		// app stack may still hold status of target, e.g. failing to create stack
+		err = appStack.Delete()
+		if err != nil {
+			return err
+		}
+		err = removeCleanUpFinalizerIfDone(appContext, appStack)
 		return err
 	}
 
@@ -212,9 +476,10 @@ func (a *AppConfHandler) Delete(ctx *oam.ActionContext, appConf *appconf.AppConf
 			if err != nil {
 				return err
 			}
-			err = removeCleanUpFinalizer(appContext)
+			err = removeCleanUpFinalizerIfDone(appContext, appStack)
 			return err
 		} else {
+			metrics.StackOperationSubmitFailed(metrics.DeleteOperation, appContext.RegionId, appConf.Namespace)
 			err = appStack.SetError(err)
 			return err
 		}
@@ -230,6 +495,109 @@ func (a *AppConfHandler) Delete(ctx *oam.ActionContext, appConf *appconf.AppConf
 	return err
 }
 
+// createStack creates stack of application, given number of stacks created for app stack before. If a stack of
+// the same name exists, e.g. it is created before controller crashes and saves its id, the stack is adopted
+// instead, and updated if it is not in progress.
+func createStack(appContext *appconf.Context, stackName string, template *ros.Template, creations int) (
+	stack *ros.Stack, stackStatus ros.StackStatusType, err error) {
+	stack, err = ros.FindStack(appContext, stackName)
+	if err != nil {
+		return
+	}
+	if stack == nil {
+		stack, err = ros.NewStack(appContext, stackName, template, ros.WithCreations(creations))
+		return stack, ros.CreateInProgress, err
+	}
+
+	logging.Default.Info("Adopt existing ROS stack", ros.StackName, stackName, ros.StackId, stack.Id, ros.StackStatus, stack.Status)
+	if stack.IsInProgress() {
+		return stack, ros.StackStatusType(stack.Status), nil
+	}
+	err = stack.Update(template)
+	if ros.IsStackSame(err) {
+		return stack, ros.StackStatusType(stack.Status), nil
+	}
+	return stack, ros.UpdateInProgress, err
+}
+
+// setCredentialError flags application whose credential is missing or invalid in its status. Other errors of
+// making contexts are returned to requeue the application.
+func (a *AppConfHandler) setCredentialError(appConf *appconf.AppConf, err error) {
+	logging.Default.Error(err, "Read credential of application failed", "AppConfName", appConf.GetName())
+	if !config.RosCtrlConf.UpdateApp {
+		return
+	}
+
+	appContext := &appconf.Context{AppConf: appConf, OamCrdClient: a.OamCrdClient, RosCrdClient: a.RosCrdClient}
+	e := appConf.UpdateStatus(appContext, v1alpha1.ApplicationFailed, v1alpha1.Error, err.Error())
+	if e != nil {
+		logging.Default.Error(e, "Set credential error of application failed", "AppConfName", appConf.GetName())
+	}
+}
+
+// RequeueAppsOfSecret returns handler of changed secret, which requeues applications using the secret as
+// credential. Applications are requeued by annotating them with the time of change.
+func RequeueAppsOfSecret(oamCrdClient *versioned.Clientset, rosCrdClient *roscrd.Clientset) func(namespace, secretName string) {
+	return func(namespace, secretName string) {
+		if !appconf.IsCredentialSecret(namespace, secretName) {
+			return
+		}
+
+		appConfs, err := appconf.ListAppConfs(config.RosCtrlConf.Namespace, oamCrdClient, rosCrdClient)
+		if err != nil {
+			logging.Default.Error(err, "List applications of changed credential secret failed", "SecretName", secretName)
+			return
+		}
+		// credential secrets of applications which are gone are forgotten
+		appconf.LoadCredentialSecrets(appConfs)
+
+		changedAt := time.Now().UTC().Format(time.RFC3339)
+		for _, appConf := range appConfs {
+			if !appconf.UsesCredentialSecret(appConf, namespace, secretName) {
+				continue
+			}
+
+			logging.Default.Info("Credential secret changed, requeue application",
+				"SecretNamespace", namespace, "SecretName", secretName, "AppConfName", appConf.GetName())
+			object := appConf.ToObject()
+			annotations := object.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[config.CREDENTIAL_CHANGED_ANNOTATION] = changedAt
+			object.SetAnnotations(annotations)
+
+			appContext := &appconf.Context{AppConf: appConf, OamCrdClient: oamCrdClient, RosCrdClient: rosCrdClient}
+			err = appConf.Update(appContext, appConf)
+			if err != nil {
+				logging.Default.Error(err, "Requeue application failed", "AppConfName", appConf.GetName())
+			}
+		}
+	}
+}
+
+// LoadLegacyAppStacks loads owners of app stacks of legacy names, which must be done before applications are handled
+func LoadLegacyAppStacks(oamCrdClient *versioned.Clientset, rosCrdClient *roscrd.Clientset) error {
+	logging.Default.Info("Load legacy app stacks")
+	appConfs, err := appconf.ListAppConfs(config.RosCtrlConf.Namespace, oamCrdClient, rosCrdClient)
+	if err != nil {
+		return err
+	}
+	return appstack.LoadLegacyAppStacks(appConfs)
+}
+
+// LoadCredentialSecrets records credential secrets of all applications, so that changes of them requeue
+// applications even if they are not reconciled since controller starts
+func LoadCredentialSecrets(oamCrdClient *versioned.Clientset, rosCrdClient *roscrd.Clientset) error {
+	logging.Default.Info("Load credential secrets")
+	appConfs, err := appconf.ListAppConfs(config.RosCtrlConf.Namespace, oamCrdClient, rosCrdClient)
+	if err != nil {
+		return err
+	}
+	appconf.LoadCredentialSecrets(appConfs)
+	return nil
+}
+
 func RecoverProgressingAppStacks(oamCrdClient *versioned.Clientset, rosCrdClient *roscrd.Clientset) {
 	logging.Default.Info("Load progressing app stacks")
 	appStacks, err := appstack.LoadProgressingAppStacks(oamCrdClient, rosCrdClient)
@@ -276,15 +644,73 @@ func removeCleanUpFinalizer(appContext *appconf.Context) (err error) {
 	finalizer.Remove(appConf.ToObject(), config.ROS_FINALIZER)
 
 	err = appConf.Update(appContext, appConf)
+	if err == nil {
+		appconf.ForgetCredentialSecrets(appConf)
+	}
 	return err
 }
 
+// removeCleanUpFinalizerIfDone removes finalizer once app stacks of all targets of application are deleted. It does
+// nothing unless application is being deleted, as stacks of removed targets are deleted while application lives.
+func removeCleanUpFinalizerIfDone(appContext *appconf.Context, appStack appstack.AppStackInterface) (err error) {
+	if appContext.AppConf.GetObjectMeta().DeletionTimestamp == nil {
+		return nil
+	}
+	deleted, err := appStack.OtherTargetsDeleted()
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		logging.Default.Info("Keep ROS finalizer until stacks of other targets are deleted",
+			appstack.AppStackName, appStack.GetName())
+		return nil
+	}
+	return removeCleanUpFinalizer(appContext)
+}
+
+// saveNestedStacks saves status of nested stacks to app stack if template of stack has nested stacks, and returns
+// status reasons of failed nested stacks
+func saveNestedStacks(appStack appstack.AppStackInterface, stack *ros.Stack) string {
+	AppStackName := appStack.GetName()
+	data, err := appStack.GetData()
+	if err != nil || !ros.HasNestedStacks(data[appstack.TemplateBody]) {
+		return ""
+	}
+
+	nestedStacks, err := stack.ListNestedStacks()
+	if err != nil {
+		logging.Default.Error(err, "List nested stacks failed", appstack.AppStackName, AppStackName, ros.StackId, stack.Id)
+		return ""
+	}
+	err = appStack.SetNestedStacks(nestedStacks)
+	if err != nil {
+		logging.Default.Error(err, "Set nested stacks failed", appstack.AppStackName, AppStackName)
+	}
+	return ros.FailedReasonOf(nestedStacks)
+}
+
 func waitStackDoneAndSaveOutputs(appContext *appconf.Context, appStack appstack.AppStackInterface, stack *ros.Stack) {
 	var err error
 	AppStackName := appStack.GetName()
+	operationDone := metrics.StackOperationStarted(stack.RegionId, stack.Namespace)
 	success, statusReason := stack.WaitUntilDone()
+	operationDone(stack.Operation(), success)
 	deleteAppStack := stack.IsInDeleteStatus()
 
+	if !deleteAppStack {
+		err = appStack.SetRevisionStatus(stack.Status)
+		if err != nil {
+			logging.Default.Error(err, "Set revision status failed", appstack.AppStackName, AppStackName)
+		}
+	}
+
+	if !deleteAppStack {
+		nestedReason := saveNestedStacks(appStack, stack)
+		if !success && nestedReason != "" {
+			statusReason = statusReason + "; " + nestedReason
+		}
+	}
+
 	if success {
 		logging.Default.Info("Stack runs done")
 
@@ -317,7 +743,7 @@ func waitStackDoneAndSaveOutputs(appContext *appconf.Context, appStack appstack.
 			logging.Default.Error(err, "Delete app stack error failed", appstack.AppStackName, AppStackName)
 		}
 
-		err = removeCleanUpFinalizer(appContext)
+		err = removeCleanUpFinalizerIfDone(appContext, appStack)
 		if err != nil {
 			logging.Default.Error(err, "Remove CleanUp finalizer failed", appstack.AppStackName, AppStackName)
 		}
@@ -12,7 +12,9 @@ import (
 
 // secretOption defines Secret option
 type secretOption struct {
-	ClientSet kubernetes.Interface
+	ClientSet     kubernetes.Interface
+	Namespace     string
+	AllNamespaces bool
 }
 
 // SecretOption has methods to work with Secret option.
@@ -44,6 +46,20 @@ func WithClientSet(clientSet kubernetes.Interface) SecretOption {
 	})
 }
 
+// WithNamespace sets namespace of Secret in Secret option, namespace of controller by default
+func WithNamespace(namespace string) SecretOption {
+	return newFuncOption(func(o *secretOption) {
+		o.Namespace = namespace
+	})
+}
+
+// WithAllNamespaces watches secrets in all namespaces in Secret option
+func WithAllNamespaces() SecretOption {
+	return newFuncOption(func(o *secretOption) {
+		o.AllNamespaces = true
+	})
+}
+
 // SecretInterface has methods to work with Secret resources.
 type SecretInterface interface {
 	GetName() string
@@ -56,6 +72,7 @@ type SecretInterface interface {
 // Secret implements SecretInterface
 type Secret struct {
 	Name      string
+	Namespace string
 	clientSet kubernetes.Interface
 }
 
@@ -70,11 +87,15 @@ func NewSecret(name string, opts ...SecretOption) SecretInterface {
 	if o.ClientSet == nil {
 		o.ClientSet = ClientManager.Clientset
 	}
+	if o.Namespace == "" {
+		o.Namespace = config.RosCtrlConf.Namespace
+	}
 
 	// new Secret
 	name = strings.ToLower(name)
 	return &Secret{
 		Name:      name,
+		Namespace: o.Namespace,
 		clientSet: o.ClientSet,
 	}
 }
@@ -89,7 +110,7 @@ func (c *Secret) GetData() (data map[string]string, err error) {
 	data = make(map[string]string)
 	secret, err := c.clientSet.
 		CoreV1().
-		Secrets(config.RosCtrlConf.Namespace).
+		Secrets(c.Namespace).
 		Get(c.Name, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		return data, nil
@@ -106,7 +127,7 @@ func (c *Secret) GetData() (data map[string]string, err error) {
 
 // UpdateData takes data and updates it. Returns an error if one occurs.
 func (c *Secret) UpdateData(data map[string]string) (err error) {
-	secretInterface := c.clientSet.CoreV1().Secrets(config.RosCtrlConf.Namespace)
+	secretInterface := c.clientSet.CoreV1().Secrets(c.Namespace)
 	secret, err := secretInterface.Get(c.Name, metav1.GetOptions{})
 
 	byteData := make(map[string][]byte)
@@ -122,7 +143,7 @@ func (c *Secret) UpdateData(data map[string]string) (err error) {
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      c.Name,
-				Namespace: config.RosCtrlConf.Namespace,
+				Namespace: c.Namespace,
 			},
 
 			Data: byteData,
@@ -145,7 +166,7 @@ func (c *Secret) UpdateData(data map[string]string) (err error) {
 
 // SetData takes data and sets it. Returns an error if one occurs.
 func (c *Secret) SetData(data map[string]string) (err error) {
-	secretInterface := c.clientSet.CoreV1().Secrets(config.RosCtrlConf.Namespace)
+	secretInterface := c.clientSet.CoreV1().Secrets(c.Namespace)
 	secret, err := secretInterface.Get(c.Name, metav1.GetOptions{})
 
 	byteData := make(map[string][]byte)
@@ -160,7 +181,7 @@ func (c *Secret) SetData(data map[string]string) (err error) {
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      c.Name,
-			Namespace: config.RosCtrlConf.Namespace,
+			Namespace: c.Namespace,
 		},
 
 		Data: byteData,
@@ -179,10 +200,40 @@ func (c *Secret) SetData(data map[string]string) (err error) {
 func (c *Secret) DeleteData() (err error) {
 	err = c.clientSet.
 		CoreV1().
-		Secrets(config.RosCtrlConf.Namespace).
+		Secrets(c.Namespace).
 		Delete(c.Name, &metav1.DeleteOptions{})
 	if errors.IsNotFound(err) {
 		return nil
 	}
 	return
 }
+
+// ListSecretData returns data of secrets in namespace by names of secrets, and an error if there is any.
+func ListSecretData(opts ...SecretOption) (dataOf map[string]map[string]string, err error) {
+	// init options
+	o := &secretOption{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	if o.ClientSet == nil {
+		o.ClientSet = ClientManager.Clientset
+	}
+	if o.Namespace == "" {
+		o.Namespace = config.RosCtrlConf.Namespace
+	}
+
+	list, err := o.ClientSet.CoreV1().Secrets(o.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return
+	}
+	dataOf = make(map[string]map[string]string)
+	for _, secret := range list.Items {
+		data := make(map[string]string)
+		for key, value := range secret.Data {
+			data[key] = string(value)
+		}
+		dataOf[secret.Name] = data
+	}
+	return
+}
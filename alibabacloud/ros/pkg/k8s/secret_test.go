@@ -231,3 +231,49 @@ func TestSecret_DeleteData(t *testing.T) {
 		})
 	}
 }
+
+func TestSecret_WithNamespace(t *testing.T) {
+	clientSet := testclient.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "tenant", Name: "mysecret"},
+			Data:       map[string][]byte{"AccessKeyId": []byte("id")},
+		},
+	)
+
+	secret := NewSecret("MySecret", WithClientSet(clientSet), WithNamespace("tenant"))
+	data, err := secret.GetData()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"AccessKeyId": "id"}, data)
+
+	// secret of the same name is not found in namespace of controller
+	data, err = NewSecret("MySecret", WithClientSet(clientSet)).GetData()
+	assert.Nil(t, err)
+	assert.Empty(t, data)
+
+	err = secret.UpdateData(map[string]string{"AccessKeySecret": "secret"})
+	assert.Nil(t, err)
+	got, err := clientSet.CoreV1().Secrets("tenant").Get("mysecret", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(got.Data["AccessKeySecret"]))
+}
+
+func TestListSecretData(t *testing.T) {
+	clientSet := testclient.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "mysecret"},
+			Data:       map[string][]byte{"k": []byte("v")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "othersecret"},
+			Data:       map[string][]byte{"k": []byte("other")},
+		},
+	)
+
+	dataOf, err := ListSecretData(WithClientSet(clientSet), WithNamespace("default"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]string{"mysecret": {"k": "v"}}, dataOf)
+
+	dataOf, err = ListSecretData(WithClientSet(clientSet), WithNamespace("other"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]string{"othersecret": {"k": "other"}}, dataOf)
+}
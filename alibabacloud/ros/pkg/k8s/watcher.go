@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SecretWatcher watches secrets in namespace of controller, or in all namespaces, and notifies handlers
+// of changed secrets. It implements Runnable of controller manager.
+type SecretWatcher struct {
+	sync.RWMutex
+	clientSet kubernetes.Interface
+	namespace string
+	handlers  []func(namespace, name string)
+	synced    bool
+}
+
+// NewSecretWatcher returns a secret watcher
+func NewSecretWatcher(opts ...SecretOption) *SecretWatcher {
+	// init options
+	o := &secretOption{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	if o.ClientSet == nil {
+		o.ClientSet = ClientManager.Clientset
+	}
+
+	namespace := o.Namespace
+	if namespace == "" {
+		namespace = config.RosCtrlConf.Namespace
+	}
+	if o.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	return &SecretWatcher{clientSet: o.ClientSet, namespace: namespace}
+}
+
+// OnChange adds handler called with namespace and name of secret which is created, updated or deleted
+func (w *SecretWatcher) OnChange(handler func(namespace, name string)) {
+	w.Lock()
+	defer w.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Start watches secrets until stop is closed
+func (w *SecretWatcher) Start(stop <-chan struct{}) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		w.clientSet, 10*time.Minute, informers.WithNamespace(w.namespace))
+	informer := factory.Core().V1().Secrets().Informer()
+	started := time.Now()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// existing secrets are also added when watcher starts
+			secret, ok := obj.(*corev1.Secret)
+			if ok && !secret.CreationTimestamp.Time.Before(started.Truncate(time.Second)) {
+				w.notify(secret.Namespace, secret.Name)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok1 := oldObj.(*corev1.Secret)
+			newSecret, ok2 := newObj.(*corev1.Secret)
+			// resync also calls update with the same secret
+			if ok1 && ok2 && oldSecret.ResourceVersion != newSecret.ResourceVersion {
+				w.notify(newSecret.Namespace, newSecret.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := obj.(*corev1.Secret); ok {
+				w.notify(secret.Namespace, secret.Name)
+			}
+		},
+	})
+
+	factory.Start(stop)
+	factory.WaitForCacheSync(stop)
+	w.Lock()
+	w.synced = true
+	w.Unlock()
+
+	<-stop
+	return nil
+}
+
+// HasSynced returns true if watcher starts to notify changes
+func (w *SecretWatcher) HasSynced() bool {
+	w.RLock()
+	defer w.RUnlock()
+	return w.synced
+}
+
+// notify calls handlers with namespace and name of changed secret
+func (w *SecretWatcher) notify(namespace, name string) {
+	w.RLock()
+	defer w.RUnlock()
+	for _, handler := range w.handlers {
+		handler(namespace, name)
+	}
+}
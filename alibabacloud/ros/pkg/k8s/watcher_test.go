@@ -0,0 +1,144 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretWatcher(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       config.RosCtrlConf.Namespace,
+			Name:            "mysecret",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{"AccessKeyId": []byte("id")},
+	}
+	clientSet := testclient.NewSimpleClientset(secret)
+
+	changed := make(chan string, 10)
+	watcher := NewSecretWatcher(WithClientSet(clientSet))
+	watcher.OnChange(func(namespace, name string) {
+		changed <- namespace + "/" + name
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = watcher.Start(stop)
+	}()
+	for !watcher.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// watch starts after list of secrets
+	time.Sleep(50 * time.Millisecond)
+
+	// update
+	secret = secret.DeepCopy()
+	secret.ResourceVersion = "2"
+	secret.Data["AccessKeyId"] = []byte("new-id")
+	_, err := clientSet.CoreV1().Secrets(config.RosCtrlConf.Namespace).Update(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, config.RosCtrlConf.Namespace+"/mysecret", waitChanged(changed))
+
+	// create
+	created := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         config.RosCtrlConf.Namespace,
+			Name:              "newsecret",
+			CreationTimestamp: metav1.Now(),
+		},
+	}
+	_, err = clientSet.CoreV1().Secrets(config.RosCtrlConf.Namespace).Create(created)
+	assert.Nil(t, err)
+	assert.Equal(t, config.RosCtrlConf.Namespace+"/newsecret", waitChanged(changed))
+
+	// delete
+	err = clientSet.CoreV1().Secrets(config.RosCtrlConf.Namespace).Delete("mysecret", &metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, config.RosCtrlConf.Namespace+"/mysecret", waitChanged(changed))
+}
+
+func TestSecretWatcher_AllNamespaces(t *testing.T) {
+	config.RosCtrlConf.Namespace = "default"
+	defer func() {
+		config.RosCtrlConf.Namespace = ""
+	}()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "tenant",
+			Name:            "mysecret",
+			ResourceVersion: "1",
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts []SecretOption
+		want string
+	}{
+		{
+			name: "TestControllerNamespace",
+			want: "",
+		},
+		{
+			name: "TestNamespace",
+			opts: []SecretOption{WithNamespace("tenant")},
+			want: "tenant/mysecret",
+		},
+		{
+			name: "TestAllNamespaces",
+			opts: []SecretOption{WithAllNamespaces()},
+			want: "tenant/mysecret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientSet := testclient.NewSimpleClientset(secret)
+			changed := make(chan string, 10)
+			watcher := NewSecretWatcher(append(tt.opts, WithClientSet(clientSet))...)
+			watcher.OnChange(func(namespace, name string) {
+				changed <- namespace + "/" + name
+			})
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go func() {
+				_ = watcher.Start(stop)
+			}()
+			for !watcher.HasSynced() {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+
+			updated := secret.DeepCopy()
+			updated.ResourceVersion = "2"
+			_, err := clientSet.CoreV1().Secrets("tenant").Update(updated)
+			assert.Nil(t, err)
+			if tt.want == "" {
+				select {
+				case name := <-changed:
+					t.Errorf("unexpected change of %s", name)
+				case <-time.After(200 * time.Millisecond):
+				}
+				return
+			}
+			assert.Equal(t, tt.want, waitChanged(changed))
+		})
+	}
+}
+
+func waitChanged(changed chan string) string {
+	select {
+	case name := <-changed:
+		return name
+	case <-time.After(5 * time.Second):
+		return ""
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	subsystem = "ros_controller"
+
+	RegionLabel    = "region"
+	NamespaceLabel = "namespace"
+	OperationLabel = "operation"
+	OutcomeLabel   = "outcome"
+	ActionLabel    = "action"
+	CodeLabel      = "code"
+	ResultLabel    = "result"
+)
+
+// stack operations
+const (
+	CreateOperation = "create"
+	UpdateOperation = "update"
+	DeleteOperation = "delete"
+)
+
+// outcomes of stack operations
+const (
+	// SubmitFailed means ROS rejects the operation
+	SubmitFailed = "submit_failed"
+	// Succeeded means stack reaches a successful terminal status
+	Succeeded = "succeeded"
+	// Failed means stack reaches a failed terminal status
+	Failed = "failed"
+)
+
+var (
+	// StackOperations counts stack operations by outcome
+	StackOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "stack_operations_total",
+			Help:      "Total number of create, update and delete stack operations by outcome.",
+		},
+		[]string{OperationLabel, OutcomeLabel, RegionLabel, NamespaceLabel},
+	)
+
+	// StackOperationDuration observes time from waiting stack until it reaches terminal status
+	StackOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "stack_operation_duration_seconds",
+			Help:      "Time in seconds for stack operations to reach terminal status.",
+			Buckets:   []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+		},
+		[]string{OperationLabel, OutcomeLabel, RegionLabel, NamespaceLabel},
+	)
+
+	// ProgressingStacks is the number of stacks which are waited until terminal status
+	ProgressingStacks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "progressing_stacks",
+			Help:      "Number of stacks in progress.",
+		},
+		[]string{RegionLabel, NamespaceLabel},
+	)
+
+	// RosApiRequestDuration observes latency of ROS API calls
+	RosApiRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "ros_api_request_duration_seconds",
+			Help:      "Latency in seconds of ROS API calls.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{ActionLabel, RegionLabel, NamespaceLabel},
+	)
+
+	// RosApiErrors counts failed ROS API calls by error code, e.g. Throttling.User
+	RosApiErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "ros_api_errors_total",
+			Help:      "Total number of failed ROS API calls by error code.",
+		},
+		[]string{ActionLabel, CodeLabel, RegionLabel, NamespaceLabel},
+	)
+
+	// TemplateRenderDuration observes time of rendering ROS template from application configuration
+	TemplateRenderDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "template_render_duration_seconds",
+			Help:      "Time in seconds of rendering ROS template from application configuration.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{RegionLabel, NamespaceLabel},
+	)
+
+	// RosClientCacheRequests counts lookups of cached ROS clients by result, hit or miss
+	RosClientCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "ros_client_cache_requests_total",
+			Help:      "Total number of lookups of cached ROS clients by result.",
+		},
+		[]string{ResultLabel},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		StackOperations,
+		StackOperationDuration,
+		ProgressingStacks,
+		RosApiRequestDuration,
+		RosApiErrors,
+		TemplateRenderDuration,
+		RosClientCacheRequests,
+	)
+}
+
+// ObserveRosApiCall records latency and error code of a ROS API call started at start.
+// Empty code means the call succeeds.
+func ObserveRosApiCall(action, code, region, namespace string, start time.Time) {
+	RosApiRequestDuration.WithLabelValues(action, region, namespace).Observe(time.Since(start).Seconds())
+	if code != "" {
+		RosApiErrors.WithLabelValues(action, code, region, namespace).Inc()
+	}
+}
+
+// ObserveTemplateRender records duration of rendering template started at start
+func ObserveTemplateRender(region, namespace string, start time.Time) {
+	TemplateRenderDuration.WithLabelValues(region, namespace).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRosClientCache records a lookup of cached ROS client
+func ObserveRosClientCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	RosClientCacheRequests.WithLabelValues(result).Inc()
+}
+
+// StackOperationSubmitFailed records a stack operation rejected by ROS
+func StackOperationSubmitFailed(operation, region, namespace string) {
+	StackOperations.WithLabelValues(operation, SubmitFailed, region, namespace).Inc()
+}
+
+// StackOperationStarted records a stack operation which starts to be waited, and returns a function
+// to record its outcome when stack reaches terminal status
+func StackOperationStarted(region, namespace string) func(operation string, success bool) {
+	start := time.Now()
+	ProgressingStacks.WithLabelValues(region, namespace).Inc()
+	return func(operation string, success bool) {
+		ProgressingStacks.WithLabelValues(region, namespace).Dec()
+		outcome := Failed
+		if success {
+			outcome = Succeeded
+		}
+		StackOperations.WithLabelValues(operation, outcome, region, namespace).Inc()
+		StackOperationDuration.WithLabelValues(operation, outcome, region, namespace).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestObserveRosApiCall(t *testing.T) {
+	ObserveRosApiCall("GetStack", "", "cn-hangzhou", "default", time.Now())
+	ObserveRosApiCall("GetStack", "Throttling.User", "cn-hangzhou", "default", time.Now())
+	ObserveRosApiCall("GetStack", "Throttling.User", "cn-hangzhou", "default", time.Now())
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(RosApiErrors.WithLabelValues("GetStack", "Throttling.User", "cn-hangzhou", "default")))
+}
+
+func TestStackOperation(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		success   bool
+		outcome   string
+	}{
+		{name: "TestCreateSucceeded", operation: CreateOperation, success: true, outcome: Succeeded},
+		{name: "TestUpdateFailed", operation: UpdateOperation, success: false, outcome: Failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progressing := ProgressingStacks.WithLabelValues("cn-beijing", tt.name)
+			done := StackOperationStarted("cn-beijing", tt.name)
+			assert.Equal(t, float64(1), testutil.ToFloat64(progressing))
+
+			done(tt.operation, tt.success)
+			assert.Equal(t, float64(0), testutil.ToFloat64(progressing))
+			assert.Equal(t, float64(1), testutil.ToFloat64(StackOperations.WithLabelValues(tt.operation, tt.outcome, "cn-beijing", tt.name)))
+		})
+	}
+
+	StackOperationSubmitFailed(DeleteOperation, "cn-beijing", "default")
+	assert.Equal(t, float64(1), testutil.ToFloat64(StackOperations.WithLabelValues(DeleteOperation, SubmitFailed, "cn-beijing", "default")))
+}
@@ -0,0 +1,131 @@
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// hashLength is length of short hashes in names
+	hashLength = 8
+
+	// maxStackNameLength is max length of stack names, which are also names of secrets, so the limit of
+	// Kubernetes object names applies besides the limit of ROS stack names (255)
+	maxStackNameLength = 253
+
+	// maxLogicalIdLength is max length of logical ids of resources in ROS templates
+	maxLogicalIdLength = 255
+)
+
+// StackName returns name of stack of application in namespace deployed to region and account, which is also name of
+// its app stack secret, e.g. cn-beijing-123456789-default-myapp-1a2b3c4d. Region is omitted for the default account
+// without aliUid. Names are lower case, only contain letters, digits and hyphens, and end with a short hash of the
+// original values, so applications of the same name in different namespaces never collide.
+func StackName(namespace, appName, regionId, aliUid string) string {
+	parts := []string{namespace, appName}
+	if aliUid != "" {
+		parts = []string{regionId, aliUid, namespace, appName}
+	}
+	hash := shortHash(parts...)
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	prefix := truncate(strings.Trim(toDNSLabel(strings.Join(nonEmpty, "-")), "-"), maxStackNameLength-hashLength-1)
+	if prefix == "" {
+		return hash
+	}
+	return prefix + "-" + hash
+}
+
+// LegacyStackName returns name of stack of application deployed to region and account before namespaces and hashes
+// were part of stack names. Stacks of such names are kept for compatibility.
+func LegacyStackName(appName, regionId, aliUid string) string {
+	if aliUid == "" {
+		return strings.ToLower(appName)
+	}
+	return strings.ToLower(regionId + "-" + aliUid + "-" + appName)
+}
+
+// OutputSecretName returns name of secret of outputs of resource of logical id in stack, e.g. myapp-1a2b3c4d-vpc.
+// Names longer than Kubernetes allows are truncated and end with a short hash.
+func OutputSecretName(stackName, logicalId string) string {
+	name := strings.Trim(toDNSLabel(stackName+"-"+logicalId), "-")
+	if len(name) <= maxStackNameLength {
+		return name
+	}
+	return truncate(name, maxStackNameLength-hashLength-1) + "-" + shortHash(stackName, logicalId)
+}
+
+// LegacyOutputSecretName returns name of secret of outputs of component instance in stack of legacy name, which is
+// kept for stacks of legacy names, so secrets read by consumers are not renamed
+func LegacyOutputSecretName(stackName, instanceName string) string {
+	return strings.ToLower(stackName + "-" + instanceName)
+}
+
+// LogicalId returns logical id of resource of component instance in ROS template. Instance names which are valid
+// logical ids are kept, so resources of existing stacks are not replaced. Other characters are replaced by
+// underscores and a short hash of instance name is appended, e.g. my.vpc is my_vpc_1a2b3c4d.
+func LogicalId(instanceName string) string {
+	if isLogicalId(instanceName) {
+		return instanceName
+	}
+	sanitized := strings.TrimLeft(strings.Map(func(r rune) rune {
+		if isLogicalIdChar(r) {
+			return r
+		}
+		return '_'
+	}, instanceName), "_-")
+	if sanitized == "" {
+		sanitized = "Resource"
+	}
+	return truncate(sanitized, maxLogicalIdLength-hashLength-1) + "_" + shortHash(instanceName)
+}
+
+// isLogicalId returns true if s is a valid logical id, which starts with a letter or digit and only contains
+// letters, digits, underscores and hyphens
+func isLogicalId(s string) bool {
+	if s == "" || len(s) > maxLogicalIdLength || s[0] == '_' || s[0] == '-' {
+		return false
+	}
+	for _, r := range s {
+		if !isLogicalIdChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLogicalIdChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
+
+// toDNSLabel lower cases s and replaces characters other than letters, digits and hyphens by hyphens
+func toDNSLabel(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, s)
+}
+
+// truncate returns at most max bytes of s
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// shortHash returns short hex hash of parts
+func shortHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
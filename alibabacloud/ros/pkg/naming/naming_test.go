@@ -0,0 +1,183 @@
+package naming
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dnsSubdomain matches names of Kubernetes secrets
+var dnsSubdomain = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+func TestStackName(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		appName   string
+		regionId  string
+		aliUid    string
+		want      string
+	}{
+		{
+			name:      "TestDefaultAccount",
+			namespace: "default",
+			appName:   "myapp",
+			regionId:  "cn-beijing",
+			want:      "default-myapp-" + shortHash("default", "myapp"),
+		},
+		{
+			name:      "TestAliUid",
+			namespace: "default",
+			appName:   "myapp",
+			regionId:  "cn-beijing",
+			aliUid:    "123456789",
+			want:      "cn-beijing-123456789-default-myapp-" + shortHash("cn-beijing", "123456789", "default", "myapp"),
+		},
+		{
+			name:      "TestUpperCaseAndInvalidCharacters",
+			namespace: "Team_A",
+			appName:   "My.App",
+			want:      "team-a-my-app-" + shortHash("Team_A", "My.App"),
+		},
+		{
+			name:    "TestEmptyNamespace",
+			appName: "-myapp",
+			want:    "myapp-" + shortHash("", "-myapp"),
+		},
+		{
+			name: "TestEmpty",
+			want: shortHash("", ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StackName(tt.namespace, tt.appName, tt.regionId, tt.aliUid)
+			assert.Equal(t, tt.want, got)
+			assert.Regexp(t, dnsSubdomain, got)
+		})
+	}
+}
+
+func TestStackName_Collision(t *testing.T) {
+	// applications of the same name in different namespaces
+	assert.NotEqual(t, StackName("a", "myapp", "", ""), StackName("b", "myapp", "", ""))
+	// names which are the same after lower case and sanitization
+	assert.NotEqual(t, StackName("default", "MyApp", "", ""), StackName("default", "myapp", "", ""))
+	assert.NotEqual(t, StackName("a-b", "c", "", ""), StackName("a", "b-c", "", ""))
+	// stable
+	assert.Equal(t, StackName("default", "myapp", "cn-beijing", "123"), StackName("default", "myapp", "cn-beijing", "123"))
+}
+
+func TestStackName_Long(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	got := StackName("default", long, "cn-beijing", "123456789")
+	assert.Len(t, got, maxStackNameLength)
+	assert.Regexp(t, dnsSubdomain, got)
+	assert.True(t, strings.HasSuffix(got, "-"+shortHash("cn-beijing", "123456789", "default", long)))
+	assert.NotEqual(t, got, StackName("default", long+"b", "cn-beijing", "123456789"))
+}
+
+func TestLegacyStackName(t *testing.T) {
+	assert.Equal(t, "myapp", LegacyStackName("MyApp", "cn-beijing", ""))
+	assert.Equal(t, "cn-beijing-123456789-myapp", LegacyStackName("MyApp", "cn-beijing", "123456789"))
+}
+
+func TestOutputSecretName(t *testing.T) {
+	tests := []struct {
+		name      string
+		stackName string
+		logicalId string
+		want      string
+	}{
+		{
+			name:      "TestNormal",
+			stackName: "cn-beijing-123456789-myapp",
+			logicalId: "Vpc",
+			want:      "cn-beijing-123456789-myapp-vpc",
+		},
+		{
+			name:      "TestInvalidCharacters",
+			stackName: "myapp",
+			logicalId: "my_vpc",
+			want:      "myapp-my-vpc",
+		},
+		{
+			name:      "TestLong",
+			stackName: "myapp",
+			logicalId: strings.Repeat("A", 300),
+			want:      "myapp-" + strings.Repeat("a", 238) + "-" + shortHash("myapp", strings.Repeat("A", 300)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OutputSecretName(tt.stackName, tt.logicalId)
+			assert.Equal(t, tt.want, got)
+			assert.Regexp(t, dnsSubdomain, got)
+		})
+	}
+}
+
+func TestLegacyOutputSecretName(t *testing.T) {
+	assert.Equal(t, "cn-beijing-123456789-myapp-my.vpc", LegacyOutputSecretName("cn-beijing-123456789-myapp", "My.Vpc"))
+}
+
+func TestLogicalId(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceName string
+		want         string
+	}{
+		{
+			name:         "TestValid",
+			instanceName: "Vpc",
+			want:         "Vpc",
+		},
+		{
+			name:         "TestValidWithHyphensAndUnderscores",
+			instanceName: "my-vpc_1",
+			want:         "my-vpc_1",
+		},
+		{
+			name:         "TestStartsWithDigit",
+			instanceName: "1vpc",
+			want:         "1vpc",
+		},
+		{
+			name:         "TestInvalidCharacters",
+			instanceName: "my.vpc",
+			want:         "my_vpc_" + shortHash("my.vpc"),
+		},
+		{
+			name:         "TestStartsWithUnderscore",
+			instanceName: "_vpc",
+			want:         "vpc_" + shortHash("_vpc"),
+		},
+		{
+			name:         "TestNonAscii",
+			instanceName: "专有网络",
+			want:         "Resource_" + shortHash("专有网络"),
+		},
+		{
+			name:         "TestEmpty",
+			instanceName: "",
+			want:         "Resource_" + shortHash(""),
+		},
+		{
+			name:         "TestLong",
+			instanceName: strings.Repeat("a", 256),
+			want:         strings.Repeat("a", 246) + "_" + shortHash(strings.Repeat("a", 256)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LogicalId(tt.instanceName)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, isLogicalId(got))
+		})
+	}
+
+	// names which are the same after sanitization
+	assert.NotEqual(t, LogicalId("my.vpc"), LogicalId("my/vpc"))
+}
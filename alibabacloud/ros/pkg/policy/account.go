@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/informers/externalversions"
+	listers "github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/listers/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// matches any namespace or account in account policies
+const wildcard = "*"
+
+var (
+	// accountPolicyLister lists enforced account policies, nil if account policies are not enforced
+	accountPolicyLister listers.RosAccountPolicyLister
+	accountPolicyLock   sync.RWMutex
+)
+
+// Enforce enforces account policies listed by lister. Nil lister stops enforcement.
+func Enforce(lister listers.RosAccountPolicyLister) {
+	accountPolicyLock.Lock()
+	defer accountPolicyLock.Unlock()
+	accountPolicyLister = lister
+}
+
+// IsEnforced returns true if account policies are enforced
+func IsEnforced() bool {
+	accountPolicyLock.RLock()
+	defer accountPolicyLock.RUnlock()
+	return accountPolicyLister != nil
+}
+
+// Start watches account policies until stop is closed, and enforces them once they are synced
+func Start(client versioned.Interface, stop <-chan struct{}) error {
+	factory := externalversions.NewSharedInformerFactory(client, 10*time.Minute)
+	informer := factory.Ros().V1alpha1().RosAccountPolicies()
+	informer.Informer()
+
+	factory.Start(stop)
+	if !cache.WaitForCacheSync(stop, informer.Informer().HasSynced) {
+		return errors.New("sync account policies failed")
+	}
+	Enforce(informer.Lister())
+	logging.Default.Info("Account policies enforced")
+	return nil
+}
+
+// AccountDeniedError means applications in namespace are not allowed to deploy to account and region
+type AccountDeniedError struct {
+	Namespace string
+	AliUid    string
+	RegionId  string
+}
+
+func (e *AccountDeniedError) Error() string {
+	return fmt.Sprintf("Account %s in region %s is not allowed for namespace %s by account policies",
+		e.AliUid, e.RegionId, e.Namespace)
+}
+
+// IsAccountDenied returns true if error is AccountDeniedError
+func IsAccountDenied(err error) bool {
+	_, ok := err.(*AccountDeniedError)
+	return ok
+}
+
+// CheckAccount returns AccountDeniedError if account policies are enforced and none of them allows
+// applications in namespace to deploy to account and region
+func CheckAccount(namespace, aliUid, regionId string) error {
+	accountPolicyLock.RLock()
+	lister := accountPolicyLister
+	accountPolicyLock.RUnlock()
+	if lister == nil {
+		return nil
+	}
+
+	policies, err := lister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	if !Allows(policies, namespace, aliUid, regionId) {
+		return &AccountDeniedError{Namespace: namespace, AliUid: aliUid, RegionId: regionId}
+	}
+	return nil
+}
+
+// Allows returns true if any of policies allows applications in namespace to deploy to account and region
+func Allows(policies []*v1alpha1.RosAccountPolicy, namespace, aliUid, regionId string) bool {
+	for _, policy := range policies {
+		spec := policy.Spec
+		if contains(spec.Namespaces, namespace) &&
+			contains(spec.AliUids, aliUid) &&
+			(len(spec.RegionIds) == 0 || contains(spec.RegionIds, regionId)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if values contain value or wildcard
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == wildcard || v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newAccountPolicy(name string, namespaces, aliUids, regionIds []string) *v1alpha1.RosAccountPolicy {
+	return &v1alpha1.RosAccountPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.RosAccountPolicySpec{
+			Namespaces: namespaces,
+			AliUids:    aliUids,
+			RegionIds:  regionIds,
+		},
+	}
+}
+
+func TestAllows(t *testing.T) {
+	policies := []*v1alpha1.RosAccountPolicy{
+		newAccountPolicy("team-a", []string{"team-a"}, []string{"111"}, []string{"cn-hangzhou", "cn-beijing"}),
+		newAccountPolicy("team-b", []string{"team-b", "team-b-test"}, []string{"222"}, nil),
+		newAccountPolicy("platform", []string{"platform"}, []string{"*"}, nil),
+		newAccountPolicy("sandbox", []string{"*"}, []string{"999"}, []string{"cn-shanghai"}),
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		aliUid    string
+		regionId  string
+		want      bool
+	}{
+		{name: "TestAllowedAccountAndRegion", namespace: "team-a", aliUid: "111", regionId: "cn-beijing", want: true},
+		{name: "TestRegionNotAllowed", namespace: "team-a", aliUid: "111", regionId: "cn-shanghai", want: false},
+		{name: "TestAccountOfOtherTeam", namespace: "team-a", aliUid: "222", regionId: "cn-hangzhou", want: false},
+		{name: "TestAnyRegion", namespace: "team-b-test", aliUid: "222", regionId: "us-west-1", want: true},
+		{name: "TestAnyAccount", namespace: "platform", aliUid: "333", regionId: "cn-hangzhou", want: true},
+		{name: "TestAnyNamespace", namespace: "other", aliUid: "999", regionId: "cn-shanghai", want: true},
+		{name: "TestNamespaceWithoutPolicy", namespace: "other", aliUid: "111", regionId: "cn-hangzhou", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Allows(policies, tt.namespace, tt.aliUid, tt.regionId))
+		})
+	}
+	assert.False(t, Allows(nil, "team-a", "111", "cn-hangzhou"))
+}
+
+func TestCheckAccount(t *testing.T) {
+	defer Enforce(nil)
+
+	// not enforced
+	assert.False(t, IsEnforced())
+	assert.Nil(t, CheckAccount("team-a", "222", "cn-hangzhou"))
+
+	client := fake.NewSimpleClientset(
+		newAccountPolicy("team-a", []string{"team-a"}, []string{"111"}, nil),
+	)
+	stop := make(chan struct{})
+	defer close(stop)
+	assert.Nil(t, Start(client, stop))
+	assert.True(t, IsEnforced())
+
+	assert.Nil(t, CheckAccount("team-a", "111", "cn-hangzhou"))
+	err := CheckAccount("team-a", "222", "cn-hangzhou")
+	assert.True(t, IsAccountDenied(err))
+	assert.EqualError(t, err, "Account 222 in region cn-hangzhou is not allowed for namespace team-a by account policies")
+}
@@ -0,0 +1,121 @@
+package region
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Path is the path region list is served on
+const Path = "/regions"
+
+var (
+	// regions are regions supported by ROS sorted by id, nil if they are not loaded
+	regions     []rosapi.Region
+	regionsLock sync.RWMutex
+)
+
+// Load loads regions supported by ROS by client
+func Load(client rosclient.Interface) error {
+	response, err := client.DescribeRegions(rosapi.CreateDescribeRegionsRequest())
+	if err != nil {
+		return err
+	}
+	if len(response.Regions) == 0 {
+		return errors.New("no region is supported by ROS")
+	}
+
+	loaded := append([]rosapi.Region{}, response.Regions...)
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].RegionId < loaded[j].RegionId
+	})
+	Set(loaded)
+	logging.Default.Info("Regions loaded", "Count", len(loaded))
+	return nil
+}
+
+// Start loads regions, and reloads them every interval until stop is closed even if loading fails. Zero interval
+// never reloads. Regions loaded before are kept if reloading fails.
+func Start(client rosclient.Interface, interval time.Duration, stop <-chan struct{}) error {
+	err := Load(client)
+	if interval > 0 {
+		go wait.Until(func() {
+			if err := Load(client); err != nil {
+				logging.Default.Error(err, "Reload regions failed")
+			}
+		}, interval, stop)
+	}
+	return err
+}
+
+// Set replaces loaded regions, nil means regions are not loaded
+func Set(loaded []rosapi.Region) {
+	regionsLock.Lock()
+	defer regionsLock.Unlock()
+	regions = loaded
+}
+
+// List returns regions supported by ROS, nil if they are not loaded
+func List() []rosapi.Region {
+	regionsLock.RLock()
+	defer regionsLock.RUnlock()
+	return regions
+}
+
+// UnsupportedRegionError means region is not supported by ROS
+type UnsupportedRegionError struct {
+	RegionId  string
+	Supported []string
+}
+
+func (e *UnsupportedRegionError) Error() string {
+	return fmt.Sprintf("Region %s is not supported by ROS. Supported regions: %s",
+		e.RegionId, strings.Join(e.Supported, ", "))
+}
+
+// IsUnsupportedRegion returns true if error is UnsupportedRegionError
+func IsUnsupportedRegion(err error) bool {
+	_, ok := err.(*UnsupportedRegionError)
+	return ok
+}
+
+// Validate returns UnsupportedRegionError if regions are loaded and region is not one of them
+func Validate(regionId string) error {
+	loaded := List()
+	if loaded == nil {
+		return nil
+	}
+
+	var supported []string
+	for _, region := range loaded {
+		if region.RegionId == regionId {
+			return nil
+		}
+		supported = append(supported, region.RegionId)
+	}
+	return &UnsupportedRegionError{RegionId: regionId, Supported: supported}
+}
+
+// Handler serves regions supported by ROS as json, which is an empty list if they are not loaded
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loaded := List()
+		if loaded == nil {
+			loaded = []rosapi.Region{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(loaded); err != nil {
+			logging.Default.Error(err, "Serve regions failed")
+		}
+	})
+}
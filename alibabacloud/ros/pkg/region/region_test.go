@@ -0,0 +1,86 @@
+package region
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeClient(regionIds ...string) *rosclient.Fake {
+	client := rosclient.NewFake()
+	for _, regionId := range regionIds {
+		client.Regions = append(client.Regions, rosapi.Region{RegionId: regionId, LocalName: regionId})
+	}
+	return client
+}
+
+func TestValidate(t *testing.T) {
+	defer Set(nil)
+
+	// not loaded
+	assert.Nil(t, Validate("cn-hangzou"))
+
+	assert.Nil(t, Load(newFakeClient("cn-hangzhou", "cn-beijing")))
+	assert.Nil(t, Validate("cn-hangzhou"))
+
+	err := Validate("cn-hangzou")
+	assert.True(t, IsUnsupportedRegion(err))
+	assert.EqualError(t, err, "Region cn-hangzou is not supported by ROS. Supported regions: cn-beijing, cn-hangzhou")
+}
+
+func TestLoad(t *testing.T) {
+	defer Set(nil)
+
+	client := newFakeClient("cn-hangzhou")
+	assert.Nil(t, Load(client))
+	assert.Len(t, List(), 1)
+
+	// regions loaded before are kept if loading fails
+	client.Errors["DescribeRegions"] = []error{rosclient.NewFakeServerError(500, "InternalError", "internal error")}
+	assert.NotNil(t, Load(client))
+	assert.Len(t, List(), 1)
+
+	assert.EqualError(t, Load(newFakeClient()), "no region is supported by ROS")
+}
+
+func TestStart(t *testing.T) {
+	defer Set(nil)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// reloaded even if loading fails at start
+	client := newFakeClient("cn-hangzhou")
+	client.Errors["DescribeRegions"] = []error{rosclient.NewFakeServerError(403, "Forbidden", "forbidden")}
+	assert.NotNil(t, Start(client, 10*time.Millisecond, stop))
+
+	client.Lock()
+	client.Regions = append(client.Regions, rosapi.Region{RegionId: "cn-beijing"})
+	client.Unlock()
+	assert.Eventually(t, func() bool {
+		return Validate("cn-beijing") == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandler(t *testing.T) {
+	defer Set(nil)
+
+	serve := func() []rosapi.Region {
+		recorder := httptest.NewRecorder()
+		Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		var got []rosapi.Region
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+		return got
+	}
+
+	assert.Equal(t, []rosapi.Region{}, serve())
+
+	assert.Nil(t, Load(newFakeClient("cn-hangzhou")))
+	assert.Equal(t, []rosapi.Region{{RegionId: "cn-hangzhou", LocalName: "cn-hangzhou"}}, serve())
+}
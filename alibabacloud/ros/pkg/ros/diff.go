@@ -0,0 +1,187 @@
+package ros
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type ChangeType string
+
+const (
+	Added    ChangeType = "Added"
+	Removed  ChangeType = "Removed"
+	Modified ChangeType = "Modified"
+)
+
+// ResourceChange is a change of resource in template
+type ResourceChange struct {
+	LogicalId    string     `json:"LogicalId"`
+	ResourceType string     `json:"ResourceType"`
+	Action       ChangeType `json:"Action"`
+	// Paths are paths of changed fields of modified resource, e.g. "Properties.Tags[0].Value"
+	Paths []string `json:"Paths,omitempty"`
+}
+
+// TemplateDiff is the semantic difference between two templates
+type TemplateDiff struct {
+	Resources []ResourceChange `json:"Resources,omitempty"`
+	// Paths are paths of changed fields out of resources, e.g. "Outputs.Vpc.VpcId"
+	Paths []string `json:"Paths,omitempty"`
+}
+
+// DiffTemplates compares template bodies regardless of key order and formatting.
+// Empty previous template body means all resources are added.
+func DiffTemplates(previousTemplateBody, currentTemplateBody string) (*TemplateDiff, error) {
+	previous, err := unmarshalTemplateBody(previousTemplateBody)
+	if err != nil {
+		return nil, err
+	}
+	current, err := unmarshalTemplateBody(currentTemplateBody)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &TemplateDiff{}
+	previousResources, _ := previous["Resources"].(map[string]interface{})
+	currentResources, _ := current["Resources"].(map[string]interface{})
+	for _, logicalId := range unionKeys(previousResources, currentResources) {
+		previousResource, inPrevious := previousResources[logicalId]
+		currentResource, inCurrent := currentResources[logicalId]
+		change := ResourceChange{LogicalId: logicalId}
+		switch {
+		case !inPrevious:
+			change.Action = Added
+			change.ResourceType = resourceTypeOf(currentResource)
+		case !inCurrent:
+			change.Action = Removed
+			change.ResourceType = resourceTypeOf(previousResource)
+		default:
+			diffValues("", previousResource, currentResource, &change.Paths)
+			if len(change.Paths) == 0 {
+				continue
+			}
+			change.Action = Modified
+			change.ResourceType = resourceTypeOf(currentResource)
+		}
+		diff.Resources = append(diff.Resources, change)
+	}
+
+	delete(previous, "Resources")
+	delete(current, "Resources")
+	diffValues("", previous, current, &diff.Paths)
+
+	return diff, nil
+}
+
+// IsEmpty returns true if there is no change
+func (d *TemplateDiff) IsEmpty() bool {
+	return len(d.Resources) == 0 && len(d.Paths) == 0
+}
+
+// String returns summary of changes, e.g. "Added: VSwitch; Modified: Vpc(Properties.VpcName)"
+func (d *TemplateDiff) String() string {
+	var added, removed, modified []string
+	for _, change := range d.Resources {
+		switch change.Action {
+		case Added:
+			added = append(added, change.LogicalId)
+		case Removed:
+			removed = append(removed, change.LogicalId)
+		case Modified:
+			modified = append(modified, fmt.Sprintf("%s(%s)", change.LogicalId, strings.Join(change.Paths, ", ")))
+		}
+	}
+
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "Added: "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "Removed: "+strings.Join(removed, ", "))
+	}
+	if len(modified) > 0 {
+		parts = append(parts, "Modified: "+strings.Join(modified, ", "))
+	}
+	if len(d.Paths) > 0 {
+		parts = append(parts, "Others: "+strings.Join(d.Paths, ", "))
+	}
+	if len(parts) == 0 {
+		return "No change"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Marshal generates JSON string
+func (d *TemplateDiff) Marshal() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// unmarshalTemplateBody decodes template body to JSON types
+func unmarshalTemplateBody(templateBody string) (map[string]interface{}, error) {
+	template := make(map[string]interface{})
+	if templateBody == "" {
+		return template, nil
+	}
+	err := json.Unmarshal([]byte(templateBody), &template)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// diffValues appends paths of changed fields between two JSON values
+func diffValues(path string, previous, current interface{}, paths *[]string) {
+	switch p := previous.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range unionKeys(p, c) {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			diffValues(keyPath, p[key], c[key], paths)
+		}
+		return
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok || len(p) != len(c) {
+			break
+		}
+		for i := range p {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), p[i], c[i], paths)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(previous, current) {
+		*paths = append(*paths, path)
+	}
+}
+
+// unionKeys returns sorted keys of both maps
+func unionKeys(a, b map[string]interface{}) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resourceTypeOf returns Type of resource decoded from JSON
+func resourceTypeOf(resource interface{}) string {
+	r, _ := resource.(map[string]interface{})
+	resourceType, _ := r["Type"].(string)
+	return resourceType
+}
@@ -0,0 +1,86 @@
+package ros
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const previousDiffTemplateBody = `{
+  "ROSTemplateFormatVersion": "2015-09-01",
+  "Resources": {
+    "Vpc": {"Type": "ALIYUN::ECS::VPC", "Properties": {"CidrBlock": "192.168.0.0/16", "VpcName": "MyVpc"}, "DeletionPolicy": "Retain"},
+    "VSwitch": {"Type": "ALIYUN::ECS::VSwitch", "Properties": {"ZoneId": "cn-hangzhou-h", "VpcId": {"Fn::GetAtt": ["Vpc", "VpcId"]}}, "DependsOn": ["Vpc"]},
+    "Index": {"Type": "ALIYUN::SLS::Index", "Properties": {"KeyIndices": [{"Name": "a", "Type": "text"}]}}
+  },
+  "Outputs": {"Vpc.VpcId": {"Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}}}
+}`
+
+func TestDiffTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		current  string
+		want     *TemplateDiff
+		wantStr  string
+	}{
+		{
+			name:     "TestReorderedAndReformatted",
+			previous: previousDiffTemplateBody,
+			current:  `{"Outputs":{"Vpc.VpcId":{"Value":{"Fn::GetAtt":["Vpc","VpcId"]}}},"Resources":{"Index":{"Properties":{"KeyIndices":[{"Type":"text","Name":"a"}]},"Type":"ALIYUN::SLS::Index"},"VSwitch":{"DependsOn":["Vpc"],"Properties":{"VpcId":{"Fn::GetAtt":["Vpc","VpcId"]},"ZoneId":"cn-hangzhou-h"},"Type":"ALIYUN::ECS::VSwitch"},"Vpc":{"DeletionPolicy":"Retain","Properties":{"VpcName":"MyVpc","CidrBlock":"192.168.0.0/16"},"Type":"ALIYUN::ECS::VPC"}},"ROSTemplateFormatVersion":"2015-09-01"}`,
+			want:     &TemplateDiff{},
+			wantStr:  "No change",
+		},
+		{
+			name:     "TestCreate",
+			previous: "",
+			current:  `{"ROSTemplateFormatVersion": "2015-09-01", "Resources": {"Vpc": {"Type": "ALIYUN::ECS::VPC"}}}`,
+			want: &TemplateDiff{
+				Resources: []ResourceChange{{LogicalId: "Vpc", ResourceType: "ALIYUN::ECS::VPC", Action: Added}},
+				Paths:     []string{"ROSTemplateFormatVersion"},
+			},
+			wantStr: "Added: Vpc; Others: ROSTemplateFormatVersion",
+		},
+		{
+			name:     "TestAddRemoveModify",
+			previous: previousDiffTemplateBody,
+			current: `{
+  "ROSTemplateFormatVersion": "2015-09-01",
+  "Resources": {
+    "Vpc": {"Type": "ALIYUN::ECS::VPC", "Properties": {"CidrBlock": "10.0.0.0/8", "Description": "vpc"}, "DeletionPolicy": "Delete"},
+    "Index": {"Type": "ALIYUN::SLS::Index", "Properties": {"KeyIndices": [{"Name": "b", "Type": "text"}]}},
+    "Project": {"Type": "ALIYUN::SLS::Project", "Properties": {"Name": "project"}}
+  },
+  "Outputs": {"Vpc.VpcId": {"Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}}, "Vpc.VRouterId": {"Value": {"Fn::GetAtt": ["Vpc", "VRouterId"]}}}
+}`,
+			want: &TemplateDiff{
+				Resources: []ResourceChange{
+					{LogicalId: "Index", ResourceType: "ALIYUN::SLS::Index", Action: Modified, Paths: []string{"Properties.KeyIndices[0].Name"}},
+					{LogicalId: "Project", ResourceType: "ALIYUN::SLS::Project", Action: Added},
+					{LogicalId: "VSwitch", ResourceType: "ALIYUN::ECS::VSwitch", Action: Removed},
+					{LogicalId: "Vpc", ResourceType: "ALIYUN::ECS::VPC", Action: Modified, Paths: []string{
+						"DeletionPolicy", "Properties.CidrBlock", "Properties.Description", "Properties.VpcName",
+					}},
+				},
+				Paths: []string{"Outputs.Vpc.VRouterId"},
+			},
+			wantStr: "Added: Project; Removed: VSwitch; " +
+				"Modified: Index(Properties.KeyIndices[0].Name), " +
+				"Vpc(DeletionPolicy, Properties.CidrBlock, Properties.Description, Properties.VpcName); " +
+				"Others: Outputs.Vpc.VRouterId",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := DiffTemplates(tt.previous, tt.current)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, diff)
+			assert.Equal(t, tt.wantStr, diff.String())
+			assert.Equal(t, tt.wantStr == "No change", diff.IsEmpty())
+		})
+	}
+}
+
+func TestDiffTemplatesInvalid(t *testing.T) {
+	_, err := DiffTemplates("{", "{}")
+	assert.NotNil(t, err)
+}
@@ -3,16 +3,13 @@ package ros
 
 import (
 	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
 	"strings"
 )
 
 // CodeOfError returns the Code for a particular error.
 func CodeOfError(error error) string {
-	switch t := error.(type) {
-	case sdkerrors.Error:
-		return t.ErrorCode()
-	}
-	return ""
+	return rosclient.CodeOfError(error)
 }
 
 // IsStackNotFound returns true if the stack not found.
@@ -28,3 +25,19 @@ func IsStackSame(error error) bool {
 	}
 	return false
 }
+
+// error codes of ROS API calls with missing or invalid credential
+var invalidCredentialCodes = map[string]bool{
+	"InvalidAccessKeyId.NotFound":                true,
+	"InvalidAccessKeyId.Inactive":                true,
+	"SignatureDoesNotMatch":                      true,
+	"IncompleteSignature":                        true,
+	"InvalidSecurityToken.Expired":               true,
+	"InvalidSecurityToken.Malformed":             true,
+	"InvalidSecurityToken.MismatchWithAccessKey": true,
+}
+
+// IsInvalidCredential returns true if ROS API call fails due to missing or invalid credential.
+func IsInvalidCredential(error error) bool {
+	return invalidCredentialCodes[CodeOfError(error)]
+}
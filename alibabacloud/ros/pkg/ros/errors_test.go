@@ -128,3 +128,25 @@ func TestIsStackSame(t *testing.T) {
 		})
 	}
 }
+
+func TestIsInvalidCredential(t *testing.T) {
+	tests := []struct {
+		errorCode string
+		want      bool
+	}{
+		{errorCode: "InvalidAccessKeyId.NotFound", want: true},
+		{errorCode: "SignatureDoesNotMatch", want: true},
+		{errorCode: "InvalidSecurityToken.Expired", want: true},
+		{errorCode: "StackNotFound", want: false},
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.errorCode, func(t *testing.T) {
+			err := NewMockError(ctrl)
+			err.EXPECT().ErrorCode().Return(tt.errorCode)
+			assert.Equal(t, tt.want, IsInvalidCredential(err))
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package ros
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
+)
+
+// Parameter values may contain expressions in ${...}, which are compiled into ROS intrinsic functions:
+//   ${Vpc.VpcId}                        Fn::GetAtt of attribute VpcId of component instance Vpc
+//   ${ALIYUN::Region}                   Ref of pseudo parameter ALIYUN::Region
+//   ${join(",", Vpc.VpcId, "a")}        Fn::Join of values, or of a single list value
+//   ${select(0, Ecs.InstanceIds)}       Fn::Select of element of list value
+//   vpc-${Vpc.VpcId}-${ALIYUN::Region}  Fn::Sub of text with expressions
+// Only ${...} which starts with a pseudo parameter, a function or a component instance name followed by a dot is an
+// expression, other ${...} are kept as text, and $${ is text ${.
+
+const (
+	joinFunction   = "join"
+	selectFunction = "select"
+
+	pseudoParameterPrefix = "ALIYUN::"
+)
+
+// pseudoParameters are names of ROS pseudo parameters
+var pseudoParameters = map[string]bool{
+	"ALIYUN::AccountId": true,
+	"ALIYUN::NoValue":   true,
+	"ALIYUN::Region":    true,
+	"ALIYUN::StackId":   true,
+	"ALIYUN::StackName": true,
+	"ALIYUN::TenantId":  true,
+}
+
+// expression is a compiled expression, literal is true for string and integer literals
+type expression struct {
+	value   interface{}
+	literal bool
+}
+
+// expressionParser compiles parameter value of component instance
+type expressionParser struct {
+	value         string
+	pos           int
+	instanceName  string
+	instanceNames map[string]bool
+	dependsOn     []string
+}
+
+// compileParameterValue compiles expressions in parameter value of component instance into ROS intrinsic functions,
+// and returns the compiled value and logical ids of component instances which it refers
+func compileParameterValue(value string, instanceName string, instanceNames map[string]bool) (interface{}, []string, error) {
+	p := &expressionParser{value: value, instanceName: instanceName, instanceNames: instanceNames}
+	compiled, err := p.parseValue()
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf(
+			"Invalid expression in parameter value '%s' of component instance '%s': %s", value, instanceName, err))
+	}
+	return compiled, p.dependsOn, nil
+}
+
+// parseValue parses text with expressions
+func (p *expressionParser) parseValue() (interface{}, error) {
+	var text strings.Builder
+	var expressions []expression
+	var textOf []string // text of Fn::Sub before each expression
+	for p.pos < len(p.value) {
+		start := strings.Index(p.value[p.pos:], "${")
+		if start < 0 {
+			text.WriteString(p.value[p.pos:])
+			break
+		}
+		start += p.pos
+		text.WriteString(p.value[p.pos:start])
+		p.pos = start + 2
+
+		// $${ is text ${
+		if start > 0 && p.value[start-1] == '$' {
+			trimmed := strings.TrimSuffix(text.String(), "$")
+			text.Reset()
+			text.WriteString(trimmed)
+			text.WriteString("${")
+			continue
+		}
+		if !p.isExpression(p.value[p.pos:]) {
+			text.WriteString("${")
+			continue
+		}
+
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume("}") {
+			return nil, p.errorf("expect '}'")
+		}
+		textOf = append(textOf, text.String())
+		text.Reset()
+		expressions = append(expressions, e)
+	}
+
+	// whole value is text or a single expression
+	if len(expressions) == 0 {
+		return text.String(), nil
+	}
+	if len(expressions) == 1 && textOf[0] == "" && text.Len() == 0 {
+		return expressions[0].value, nil
+	}
+
+	// text with expressions is Fn::Sub, whose variables are values of expressions other than pseudo parameters
+	var sub strings.Builder
+	variables := make(map[string]interface{})
+	for i, e := range expressions {
+		sub.WriteString(escapeSub(textOf[i]))
+		if e.literal {
+			sub.WriteString(escapeSub(fmt.Sprint(e.value)))
+			continue
+		}
+		if ref, ok := e.value.(map[string]string); ok {
+			sub.WriteString("${" + ref["Ref"] + "}")
+			continue
+		}
+		variable := fmt.Sprintf("Var%d", len(variables)+1)
+		variables[variable] = e.value
+		sub.WriteString("${" + variable + "}")
+	}
+	sub.WriteString(escapeSub(text.String()))
+	if len(variables) == 0 {
+		return map[string]interface{}{"Fn::Sub": sub.String()}, nil
+	}
+	return map[string]interface{}{"Fn::Sub": []interface{}{sub.String(), variables}}, nil
+}
+
+// isExpression returns true if s after ${ is an expression
+func (p *expressionParser) isExpression(s string) bool {
+	if strings.HasPrefix(s, pseudoParameterPrefix) {
+		return true
+	}
+	for _, function := range []string{joinFunction, selectFunction} {
+		if strings.HasPrefix(s, function+"(") {
+			return true
+		}
+	}
+	return instanceNameOf(s, p.instanceNames) != ""
+}
+
+// instanceNameOf returns the longest component instance name followed by a dot at the beginning of s, so that names
+// of component instances may contain dots
+func instanceNameOf(s string, instanceNames map[string]bool) string {
+	var longest string
+	for name := range instanceNames {
+		if len(name) > len(longest) && strings.HasPrefix(s, name+".") {
+			longest = name
+		}
+	}
+	return longest
+}
+
+// parseExpression parses a literal, a pseudo parameter, an attribute of component instance or a function
+func (p *expressionParser) parseExpression() (expression, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.value) {
+		return expression{}, p.errorf("expect expression")
+	}
+	c := p.value[p.pos]
+	switch {
+	case c == '"':
+		s, err := p.parseString()
+		return expression{value: s, literal: true}, err
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.value) && p.value[p.pos] >= '0' && p.value[p.pos] <= '9' {
+			p.pos++
+		}
+		i, err := strconv.Atoi(p.value[start:p.pos])
+		return expression{value: i, literal: true}, err
+	case strings.HasPrefix(p.value[p.pos:], pseudoParameterPrefix):
+		p.pos += len(pseudoParameterPrefix)
+		name := pseudoParameterPrefix + p.parseName()
+		if !pseudoParameters[name] {
+			return expression{}, p.errorf("unknown pseudo parameter '%s'", name)
+		}
+		return expression{value: map[string]string{"Ref": name}}, nil
+	}
+
+	start := p.pos
+	name := instanceNameOf(p.value[p.pos:], p.instanceNames)
+	if name != "" {
+		p.pos += len(name)
+	} else {
+		name = p.parseName()
+	}
+	if name == "" {
+		return expression{}, p.errorf("unexpected '%c'", c)
+	}
+	if p.consume("(") {
+		return p.parseFunction(name)
+	}
+	if !p.consume(".") {
+		return expression{}, p.errorf("expect attribute of component instance '%s'", name)
+	}
+	attribute := p.parseName()
+	if attribute == "" {
+		return expression{}, p.errorf("expect attribute of component instance '%s'", name)
+	}
+	if !p.instanceNames[name] {
+		p.pos = start
+		return expression{}, p.errorf("unknown component instance '%s'", name)
+	}
+	if name == p.instanceName {
+		p.pos = start
+		return expression{}, p.errorf("component instance '%s' refers to itself", name)
+	}
+	logicalId := naming.LogicalId(name)
+	p.addDependsOn(logicalId)
+	return expression{value: map[string][]string{"Fn::GetAtt": {logicalId, attribute}}}, nil
+}
+
+// parseFunction parses arguments of function after '('
+func (p *expressionParser) parseFunction(function string) (expression, error) {
+	var args []expression
+	p.skipSpaces()
+	for !p.consume(")") {
+		if len(args) > 0 && !p.consume(",") {
+			return expression{}, p.errorf("expect ',' or ')'")
+		}
+		arg, err := p.parseExpression()
+		if err != nil {
+			return expression{}, err
+		}
+		args = append(args, arg)
+		p.skipSpaces()
+	}
+
+	switch function {
+	case joinFunction:
+		if len(args) < 2 {
+			return expression{}, p.errorf("%s requires a delimiter and values", joinFunction)
+		}
+		delimiter, ok := args[0].value.(string)
+		if !ok {
+			return expression{}, p.errorf("delimiter of %s must be a string", joinFunction)
+		}
+		// a single value which is not a literal is a list
+		if len(args) == 2 && !args[1].literal {
+			return expression{value: map[string]interface{}{"Fn::Join": []interface{}{delimiter, args[1].value}}}, nil
+		}
+		values := make([]interface{}, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			if i, ok := arg.value.(int); ok {
+				values = append(values, strconv.Itoa(i))
+				continue
+			}
+			values = append(values, arg.value)
+		}
+		return expression{value: map[string]interface{}{"Fn::Join": []interface{}{delimiter, values}}}, nil
+	case selectFunction:
+		if len(args) != 2 {
+			return expression{}, p.errorf("%s requires an index and a list", selectFunction)
+		}
+		index, ok := args[0].value.(int)
+		if !ok {
+			return expression{}, p.errorf("index of %s must be an integer", selectFunction)
+		}
+		return expression{value: map[string]interface{}{"Fn::Select": []interface{}{strconv.Itoa(index), args[1].value}}}, nil
+	default:
+		return expression{}, p.errorf("unknown function '%s'", function)
+	}
+}
+
+// parseString parses a double quoted string, in which \" and \\ are escaped
+func (p *expressionParser) parseString() (string, error) {
+	var s strings.Builder
+	for p.pos++; p.pos < len(p.value); p.pos++ {
+		c := p.value[p.pos]
+		if c == '"' {
+			p.pos++
+			return s.String(), nil
+		}
+		if c == '\\' && p.pos+1 < len(p.value) {
+			p.pos++
+			c = p.value[p.pos]
+		}
+		s.WriteByte(c)
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseName parses a name of component instance, attribute, function or pseudo parameter
+func (p *expressionParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.value) {
+		c := p.value[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			break
+		}
+		p.pos++
+	}
+	return p.value[start:p.pos]
+}
+
+func (p *expressionParser) skipSpaces() {
+	for p.pos < len(p.value) && p.value[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *expressionParser) consume(s string) bool {
+	if strings.HasPrefix(p.value[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) addDependsOn(logicalId string) {
+	for _, dependOn := range p.dependsOn {
+		if dependOn == logicalId {
+			return
+		}
+	}
+	p.dependsOn = append(p.dependsOn, logicalId)
+}
+
+func (p *expressionParser) errorf(format string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("%s at offset %d", fmt.Sprintf(format, args...), p.pos))
+}
+
+// escapeSub escapes text of Fn::Sub, in which ${ is written as ${!
+func escapeSub(text string) string {
+	return strings.Replace(text, "${", "${!", -1)
+}
@@ -0,0 +1,151 @@
+package ros
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compileParameterValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		want          interface{}
+		wantDependsOn []string
+		wantErr       string
+	}{
+		{
+			name:  "TestText",
+			value: "my-vpc",
+			want:  "my-vpc",
+		},
+		{
+			name:  "TestTextNotExpression",
+			value: "echo ${HOME} ${Unknown.Attr}",
+			want:  "echo ${HOME} ${Unknown.Attr}",
+		},
+		{
+			name:  "TestEscape",
+			value: "$${Vpc.VpcId}",
+			want:  "${Vpc.VpcId}",
+		},
+		{
+			name:          "TestGetAtt",
+			value:         "${Vpc.VpcId}",
+			want:          map[string][]string{"Fn::GetAtt": {"Vpc", "VpcId"}},
+			wantDependsOn: []string{"Vpc"},
+		},
+		{
+			name:  "TestPseudoParameter",
+			value: "${ALIYUN::AccountId}",
+			want:  map[string]string{"Ref": "ALIYUN::AccountId"},
+		},
+		{
+			name:  "TestSubPseudoParameter",
+			value: "log-${ALIYUN::Region}-${HOME}",
+			want:  map[string]interface{}{"Fn::Sub": "log-${ALIYUN::Region}-${!HOME}"},
+		},
+		{
+			name:  "TestSub",
+			value: "http://${Slb.IpAddress}:${Listener.ListenerPort}/${Slb.IpAddress}",
+			want: map[string]interface{}{"Fn::Sub": []interface{}{
+				"http://${Var1}:${Var2}/${Var3}",
+				map[string]interface{}{
+					"Var1": map[string][]string{"Fn::GetAtt": {"Slb", "IpAddress"}},
+					"Var2": map[string][]string{"Fn::GetAtt": {"Listener", "ListenerPort"}},
+					"Var3": map[string][]string{"Fn::GetAtt": {"Slb", "IpAddress"}},
+				},
+			}},
+			wantDependsOn: []string{"Slb", "Listener"},
+		},
+		{
+			name:  "TestJoin",
+			value: `${join(",", Vpc.VpcId, "vpc-2", 3)}`,
+			want: map[string]interface{}{"Fn::Join": []interface{}{
+				",", []interface{}{map[string][]string{"Fn::GetAtt": {"Vpc", "VpcId"}}, "vpc-2", "3"},
+			}},
+			wantDependsOn: []string{"Vpc"},
+		},
+		{
+			name:  "TestJoinList",
+			value: `${join(",", Ecs.InstanceIds)}`,
+			want: map[string]interface{}{"Fn::Join": []interface{}{
+				",", map[string][]string{"Fn::GetAtt": {"Ecs", "InstanceIds"}},
+			}},
+			wantDependsOn: []string{"Ecs"},
+		},
+		{
+			name:  "TestNestedFunctions",
+			value: `${select( 1, Ecs.InstanceIds )}-${join("\"", "a", "b")}`,
+			want: map[string]interface{}{"Fn::Sub": []interface{}{
+				"${Var1}-${Var2}",
+				map[string]interface{}{
+					"Var1": map[string]interface{}{"Fn::Select": []interface{}{
+						"1", map[string][]string{"Fn::GetAtt": {"Ecs", "InstanceIds"}},
+					}},
+					"Var2": map[string]interface{}{"Fn::Join": []interface{}{`"`, []interface{}{"a", "b"}}},
+				},
+			}},
+			wantDependsOn: []string{"Ecs"},
+		},
+		{
+			name:    "TestRefSelf",
+			value:   "${VSwitch.VSwitchId}",
+			wantErr: "Invalid expression in parameter value '${VSwitch.VSwitchId}' of component instance 'VSwitch': component instance 'VSwitch' refers to itself at offset 2",
+		},
+		{
+			name:    "TestUnknownInstance",
+			value:   `${join(",", Unknown.Attr)}`,
+			wantErr: "unknown component instance 'Unknown' at offset 12",
+		},
+		{
+			name:    "TestUnknownPseudoParameter",
+			value:   "${ALIYUN::Zone}",
+			wantErr: "unknown pseudo parameter 'ALIYUN::Zone'",
+		},
+		{
+			name:    "TestUnknownFunction",
+			value:   `${join(",", split(",", Vpc.VpcId))}`,
+			wantErr: "unknown function 'split'",
+		},
+		{
+			name:    "TestMissingBrace",
+			value:   "${Vpc.VpcId",
+			wantErr: "expect '}' at offset 11",
+		},
+		{
+			name:    "TestMissingAttribute",
+			value:   "${Vpc.}",
+			wantErr: "expect attribute of component instance 'Vpc'",
+		},
+		{
+			name:    "TestUnterminatedString",
+			value:   `${join(", Vpc.VpcId)}`,
+			wantErr: "unterminated string",
+		},
+		{
+			name:    "TestSelectIndex",
+			value:   `${select("0", Ecs.InstanceIds)}`,
+			wantErr: "index of select must be an integer",
+		},
+		{
+			name:    "TestJoinDelimiter",
+			value:   `${join(Vpc.VpcId, Ecs.InstanceIds)}`,
+			wantErr: "delimiter of join must be a string",
+		},
+	}
+	instanceNames := map[string]bool{"Vpc": true, "VSwitch": true, "Ecs": true, "Slb": true, "Listener": true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dependsOn, err := compileParameterValue(tt.value, "VSwitch", instanceNames)
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDependsOn, dependsOn)
+		})
+	}
+}
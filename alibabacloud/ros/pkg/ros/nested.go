@@ -0,0 +1,593 @@
+package ros
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+)
+
+// NestedStackType is ROS resource type of nested stacks
+const NestedStackType = "ALIYUN::ROS::Stack"
+
+// NestedStacksOf returns component instances grouped by nested stacks in annotation, e.g.
+// {"Network": ["Vpc", "VSwitch"]}. Returns nil if annotation is not set.
+func NestedStacksOf(annotations map[string]string) (map[string][]string, error) {
+	value, ok := annotations[config.NESTED_STACKS_ANNOTATION]
+	if !ok {
+		return nil, nil
+	}
+	groups := make(map[string][]string)
+	err := json.Unmarshal([]byte(value), &groups)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"Invalid annotation '%s', which must be a json object of nested stack names to component instance names",
+			config.NESTED_STACKS_ANNOTATION))
+	}
+	return groups, nil
+}
+
+// HasNestedStacks returns true if template body has nested stacks
+func HasNestedStacks(templateBody string) bool {
+	return strings.Contains(templateBody, `"`+NestedStackType+`"`)
+}
+
+// nest moves resources of component instances in groups into nested stacks named by groups. References across
+// nested stacks and the parent stack are passed by parameters and outputs of nested stacks, conditions and mappings
+// used by resources in nested stacks are copied into them, and outputs of the parent stack are kept.
+func (t *Template) nest(groups map[string][]string) error {
+	groupOf, err := t.groupResources(groups)
+	if err != nil {
+		return err
+	}
+
+	resourceIds := make(map[string]bool)
+	for logicalId := range t.Resources {
+		resourceIds[logicalId] = true
+	}
+	children := make(map[string]*Template)
+	childParameters := make(map[string]map[string]interface{})
+	dependsOn := make(map[string]map[string]bool)
+	for group := range groups {
+		children[group] = &Template{
+			ROSTemplateFormatVersion: t.ROSTemplateFormatVersion,
+			Parameters:               make(map[string]Parameter),
+			Resources:                make(map[string]Resource),
+			Outputs:                  make(map[string]Output),
+		}
+		childParameters[group] = make(map[string]interface{})
+		dependsOn[group] = make(map[string]bool)
+	}
+
+	// parentIdOf returns logical id in parent stack of resource, which is its nested stack if there is
+	parentIdOf := func(logicalId string) string {
+		if group, ok := groupOf[logicalId]; ok {
+			return group
+		}
+		return logicalId
+	}
+
+	// parentGetAtt returns attribute of resource in parent stack, which is an output of its nested stack if there is
+	parentGetAtt := func(logicalId, attribute string) interface{} {
+		group, ok := groupOf[logicalId]
+		if !ok {
+			return map[string][]string{"Fn::GetAtt": {logicalId, attribute}}
+		}
+		outputName := logicalId + "." + attribute
+		if _, ok := children[group].Outputs[outputName]; !ok {
+			children[group].Outputs[outputName] = Output{
+				Value: map[string][]string{"Fn::GetAtt": {logicalId, attribute}},
+			}
+		}
+		return map[string][]string{"Fn::GetAtt": {group, outputName}}
+	}
+
+	// parentRef returns reference of resource in parent stack, which is an output of its nested stack if there is
+	parentRef := func(logicalId string) interface{} {
+		group, ok := groupOf[logicalId]
+		if !ok {
+			return map[string]string{"Ref": logicalId}
+		}
+		outputName := logicalId + ".Ref"
+		if _, ok := children[group].Outputs[outputName]; !ok {
+			children[group].Outputs[outputName] = Output{
+				Value: map[string]string{"Ref": logicalId},
+			}
+		}
+		return map[string][]string{"Fn::GetAtt": {group, outputName}}
+	}
+
+	// childRef rewrites reference in nested stack of group. Parameters of parent stack are passed as is, and resources
+	// out of nested stack are passed by parameters named by their logical ids.
+	childRef := func(group, name string) interface{} {
+		if parameter, ok := t.Parameters[name]; ok {
+			if _, ok := children[group].Parameters[name]; !ok {
+				children[group].Parameters[name] = Parameter{
+					Name:        name,
+					Type:        parameter.Type,
+					Default:     parameter.Default,
+					Description: parameter.Description,
+				}
+				childParameters[group][name] = map[string]string{"Ref": name}
+			}
+			return nil
+		}
+		if !resourceIds[name] || groupOf[name] == group {
+			// pseudo parameters and resources in nested stack
+			return nil
+		}
+		if _, ok := children[group].Parameters[name]; !ok {
+			children[group].Parameters[name] = Parameter{Name: name, Type: "String"}
+		}
+		childParameters[group][name] = parentRef(name)
+		dependsOn[group][parentIdOf(name)] = true
+		return map[string]string{"Ref": name}
+	}
+
+	// copyDeclarations copies conditions and mappings used by value into nested stack of group
+	var copyDeclarations func(group string, value interface{})
+	copyCondition := func(group, name string) {
+		condition, ok := t.Conditions[name]
+		if !ok {
+			return
+		}
+		child := children[group]
+		if _, ok := child.Conditions[name]; ok {
+			return
+		}
+		if child.Conditions == nil {
+			child.Conditions = make(map[string]interface{})
+		}
+		// set before rewriting, so that conditions referring each other are copied once
+		child.Conditions[name] = condition
+		child.Conditions[name] = rewriteReferences(condition, nil, func(refName string) interface{} {
+			return childRef(group, refName)
+		})
+		copyDeclarations(group, condition)
+	}
+	copyDeclarations = func(group string, value interface{}) {
+		conditions, mappings := declarationsOf(value)
+		for _, name := range conditions {
+			copyCondition(group, name)
+		}
+		child := children[group]
+		for _, name := range mappings {
+			mapping, ok := t.Mappings[name]
+			if !ok {
+				continue
+			}
+			if child.Mappings == nil {
+				child.Mappings = make(map[string]interface{})
+			}
+			child.Mappings[name] = mapping
+		}
+	}
+
+	for _, logicalId := range sortedResourceIds(t.Resources) {
+		resource := t.Resources[logicalId]
+		group, nested := groupOf[logicalId]
+
+		// references to resources out of nested stack are passed by parameters
+		var properties map[string]interface{}
+		if nested {
+			parameterTypes := parameterTypesOf(resource.Properties, t.settingsSchemas[logicalId])
+			properties = rewriteReferences(resource.Properties, func(refId, attribute string) interface{} {
+				if groupOf[refId] == group {
+					return nil
+				}
+				parameterName := refId + attribute
+				if _, ok := children[group].Parameters[parameterName]; !ok {
+					children[group].Parameters[parameterName] = Parameter{
+						Name: parameterName,
+						Type: parameterTypes[refId+"."+attribute],
+					}
+				}
+				childParameters[group][parameterName] = parentGetAtt(refId, attribute)
+				dependsOn[group][parentIdOf(refId)] = true
+				return map[string]string{"Ref": parameterName}
+			}, func(name string) interface{} {
+				return childRef(group, name)
+			}).(map[string]interface{})
+			copyDeclarations(group, resource.Properties)
+			if resource.Condition != "" {
+				copyCondition(group, resource.Condition)
+			}
+		} else {
+			properties = rewriteReferences(resource.Properties, func(refId, attribute string) interface{} {
+				if _, ok := groupOf[refId]; !ok {
+					return nil
+				}
+				return parentGetAtt(refId, attribute)
+			}, func(name string) interface{} {
+				if _, ok := groupOf[name]; !ok {
+					return nil
+				}
+				return parentRef(name)
+			}).(map[string]interface{})
+		}
+		resource.Properties = properties
+
+		// dependencies out of nested stack are dependencies of nested stack
+		resourceDependsOn := make([]string, 0)
+		found := make(map[string]bool)
+		for _, dependency := range resource.DependsOn {
+			if nested && groupOf[dependency] != group {
+				dependsOn[group][parentIdOf(dependency)] = true
+				continue
+			}
+			if !nested {
+				dependency = parentIdOf(dependency)
+			}
+			if !found[dependency] {
+				found[dependency] = true
+				resourceDependsOn = append(resourceDependsOn, dependency)
+			}
+		}
+		resource.DependsOn = resourceDependsOn
+
+		if nested {
+			children[group].Resources[logicalId] = resource
+			delete(t.Resources, logicalId)
+		} else {
+			t.Resources[logicalId] = resource
+		}
+	}
+
+	// outputs of resources in nested stacks are outputs of nested stacks
+	for name, output := range t.Outputs {
+		output.Value = rewriteReferences(output.Value, func(refId, attribute string) interface{} {
+			group, ok := groupOf[refId]
+			if !ok {
+				return nil
+			}
+			children[group].Outputs[name] = Output{
+				Description: output.Description,
+				Value:       map[string][]string{"Fn::GetAtt": {refId, attribute}},
+			}
+			return map[string][]string{"Fn::GetAtt": {group, name}}
+		}, func(refName string) interface{} {
+			if _, ok := groupOf[refName]; !ok {
+				return nil
+			}
+			return parentRef(refName)
+		})
+		t.Outputs[name] = output
+	}
+
+	for group, child := range children {
+		delete(dependsOn[group], group)
+		properties := map[string]interface{}{"TemplateBody": child}
+		if len(childParameters[group]) > 0 {
+			properties["Parameters"] = childParameters[group]
+		}
+		t.Resources[group] = Resource{
+			Type:           NestedStackType,
+			Properties:     properties,
+			DependsOn:      sortedKeys(dependsOn[group]),
+			DeletionPolicy: "Delete",
+		}
+	}
+	return checkNestedStackCycles(t.Resources)
+}
+
+// groupResources returns nested stacks of component instances in groups, and an error if groups are invalid
+func (t *Template) groupResources(groups map[string][]string) (map[string]string, error) {
+	groupOf := make(map[string]string)
+	for _, group := range sortedGroups(groups) {
+		if _, ok := t.Resources[group]; ok {
+			return nil, errors.New(fmt.Sprintf("Nested stack '%s' has the same name as a component instance", group))
+		}
+		if len(groups[group]) == 0 {
+			return nil, errors.New(fmt.Sprintf("Nested stack '%s' has no component instance", group))
+		}
+		for _, instanceName := range groups[group] {
+			logicalId := naming.LogicalId(instanceName)
+			if _, ok := t.Resources[logicalId]; !ok {
+				return nil, errors.New(fmt.Sprintf(
+					"Nested stack '%s' refers a no exist component instance '%s'", group, instanceName))
+			}
+			if other, ok := groupOf[logicalId]; ok {
+				return nil, errors.New(fmt.Sprintf(
+					"Component instance '%s' is in both nested stack '%s' and '%s'", instanceName, other, group))
+			}
+			groupOf[logicalId] = group
+		}
+	}
+	return groupOf, nil
+}
+
+// checkNestedStackCycles returns an error if resources depend on each other through nested stacks
+func checkNestedStackCycles(resources map[string]Resource) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := make(map[string]int)
+	var visit func(logicalId string) error
+	visit = func(logicalId string) error {
+		switch states[logicalId] {
+		case visiting:
+			return errors.New(fmt.Sprintf("Nested stacks depend on each other through '%s'", logicalId))
+		case visited:
+			return nil
+		}
+		states[logicalId] = visiting
+		for _, dependency := range resources[logicalId].DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		states[logicalId] = visited
+		return nil
+	}
+
+	for _, logicalId := range sortedResourceIds(resources) {
+		if err := visit(logicalId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSettingsSchema records workload settings schema of resource
+func (t *Template) setSettingsSchema(logicalId string, settingsSchema *schema.JsonSchema) {
+	if t.settingsSchemas == nil {
+		t.settingsSchemas = make(map[string]*schema.JsonSchema)
+	}
+	t.settingsSchemas[logicalId] = settingsSchema
+}
+
+// parameterTypesOf returns ROS parameter types of attributes referred by properties of resource, e.g.
+// {"Ecs.InstanceIds": "CommaDelimitedList"}. Types are derived from the workload settings schema of properties where
+// attributes are referred, or from functions they are passed to. Attributes of unknown types are String.
+func parameterTypesOf(properties map[string]interface{}, settingsSchema *schema.JsonSchema) map[string]string {
+	parameterTypes := make(map[string]string)
+	collectParameterTypes(properties, settingsSchema, parameterTypes)
+	return parameterTypes
+}
+
+// collectParameterTypes collects ROS parameter types of attributes referred by value of property of schema
+func collectParameterTypes(value interface{}, propertySchema *schema.JsonSchema, parameterTypes map[string]string) {
+	if logicalId, attribute, ok := getAttOf(value); ok {
+		key := logicalId + "." + attribute
+		// the first reference in order of property names decides type of attribute referred more than once
+		if _, ok := parameterTypes[key]; !ok {
+			parameterTypes[key] = parameterTypeOf(propertySchema)
+		}
+		return
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		var items *schema.JsonSchema
+		if propertySchema != nil {
+			items = propertySchema.Items
+		}
+		for _, item := range v {
+			collectParameterTypes(item, items, parameterTypes)
+		}
+	case map[string]interface{}:
+		if schema.IsIntrinsicFunction(v) {
+			collectFunctionParameterTypes(v, parameterTypes)
+			return
+		}
+		for _, name := range sortedNames(v) {
+			var property *schema.JsonSchema
+			if propertySchema != nil {
+				property = propertySchema.Properties[name]
+				if property == nil {
+					property = propertySchema.AdditionalProperties
+				}
+			}
+			collectParameterTypes(v[name], property, parameterTypes)
+		}
+	}
+}
+
+// collectFunctionParameterTypes collects ROS parameter types of attributes passed to function. Lists joined or
+// selected from are CommaDelimitedList, and other arguments are String.
+func collectFunctionParameterTypes(function map[string]interface{}, parameterTypes map[string]string) {
+	list := &schema.JsonSchema{Type: "array", Items: &schema.JsonSchema{Type: "string"}}
+	for name, args := range function {
+		argList, ok := args.([]interface{})
+		if (name == "Fn::Join" || name == "Fn::Select") && ok && len(argList) == 2 {
+			collectParameterTypes(argList[0], nil, parameterTypes)
+			if _, _, isGetAtt := getAttOf(argList[1]); isGetAtt {
+				collectParameterTypes(argList[1], list, parameterTypes)
+			} else {
+				collectParameterTypes(argList[1], nil, parameterTypes)
+			}
+			continue
+		}
+		collectParameterTypes(args, nil, parameterTypes)
+	}
+}
+
+// parameterTypeOf returns ROS parameter type of values of property schema
+func parameterTypeOf(propertySchema *schema.JsonSchema) string {
+	if propertySchema == nil {
+		return "String"
+	}
+	switch propertySchema.Type {
+	case "integer", "number":
+		return "Number"
+	case "boolean":
+		return "Boolean"
+	case "array":
+		if propertySchema.Items == nil || propertySchema.Items.Type == "string" {
+			return "CommaDelimitedList"
+		}
+		return "Json"
+	case "object":
+		return "Json"
+	}
+	return "String"
+}
+
+// rewriteReferences returns a copy of value whose Fn::GetAtt and Ref functions are replaced by results of getAtt and
+// ref. Functions are kept if getAtt or ref is nil or returns nil.
+func rewriteReferences(value interface{}, getAtt func(logicalId, attribute string) interface{},
+	ref func(name string) interface{}) interface{} {
+	if logicalId, attribute, ok := getAttOf(value); ok {
+		if getAtt == nil {
+			return value
+		}
+		if rewritten := getAtt(logicalId, attribute); rewritten != nil {
+			return rewritten
+		}
+		return value
+	}
+	if name, ok := refOf(value); ok {
+		if ref == nil {
+			return value
+		}
+		if rewritten := ref(name); rewritten != nil {
+			return rewritten
+		}
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			rewritten[key] = rewriteReferences(item, getAtt, ref)
+		}
+		return rewritten
+	case []interface{}:
+		rewritten := make([]interface{}, len(v))
+		for i, item := range v {
+			rewritten[i] = rewriteReferences(item, getAtt, ref)
+		}
+		return rewritten
+	default:
+		return value
+	}
+}
+
+// refOf returns name referred by Ref function
+func refOf(value interface{}) (name string, ok bool) {
+	switch v := value.(type) {
+	case map[string]string:
+		name, ok = v["Ref"]
+		return name, ok && len(v) == 1
+	case map[string]interface{}:
+		name, ok = v["Ref"].(string)
+		return name, ok && len(v) == 1
+	}
+	return "", false
+}
+
+// declarationsOf returns names of conditions and mappings used by value, i.e. conditions of Fn::If and Condition
+// functions and mappings of Fn::FindInMap
+func declarationsOf(value interface{}) (conditions []string, mappings []string) {
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(v) == 1 {
+				if name, ok := v["Condition"].(string); ok {
+					conditions = append(conditions, name)
+					return
+				}
+				if args, ok := v["Fn::If"].([]interface{}); ok && len(args) > 0 {
+					if name, ok := args[0].(string); ok {
+						conditions = append(conditions, name)
+					}
+				}
+				if args, ok := v["Fn::FindInMap"].([]interface{}); ok && len(args) > 0 {
+					if name, ok := args[0].(string); ok {
+						mappings = append(mappings, name)
+					}
+				}
+			}
+			for _, name := range sortedNames(v) {
+				collect(v[name])
+			}
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		}
+	}
+	collect(value)
+	return conditions, mappings
+}
+
+// getAttOf returns logical id and attribute of Fn::GetAtt function
+func getAttOf(value interface{}) (logicalId string, attribute string, ok bool) {
+	switch v := value.(type) {
+	case map[string][]string:
+		args, found := v["Fn::GetAtt"]
+		if len(v) == 1 && found && len(args) == 2 {
+			return args[0], args[1], true
+		}
+	case map[string][2]string:
+		args, found := v["Fn::GetAtt"]
+		if len(v) == 1 && found {
+			return args[0], args[1], true
+		}
+	case map[string]interface{}:
+		args, found := v["Fn::GetAtt"].([]interface{})
+		if len(v) == 1 && found && len(args) == 2 {
+			logicalId, ok1 := args[0].(string)
+			attribute, ok2 := args[1].(string)
+			return logicalId, attribute, ok1 && ok2
+		}
+	}
+	return "", "", false
+}
+
+// nestedResourceChangeOf returns logical id of resource in nested stack and path of change in the resource from
+// path of change in nested stack, e.g. "Vpc" and "Properties.CidrBlock" from
+// "Properties.TemplateBody.Resources.Vpc.Properties.CidrBlock"
+func nestedResourceChangeOf(path string) (logicalId string, resourcePath string, ok bool) {
+	const prefix = "Properties.TemplateBody.Resources."
+	if !strings.HasPrefix(path, prefix) {
+		return "", "", false
+	}
+	split := strings.SplitN(strings.TrimPrefix(path, prefix), ".", 2)
+	if len(split) != 2 {
+		return "", "", false
+	}
+	return split[0], split[1], true
+}
+
+// nestedResourcesOf returns resources in template body of nested stack resource
+func nestedResourcesOf(resource Resource) map[string]interface{} {
+	templateBody, _ := resource.Properties["TemplateBody"].(map[string]interface{})
+	resources, _ := templateBody["Resources"].(map[string]interface{})
+	return resources
+}
+
+func sortedResourceIds(resources map[string]Resource) []string {
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedGroups(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
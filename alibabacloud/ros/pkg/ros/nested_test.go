@@ -0,0 +1,401 @@
+package ros
+
+import (
+	"testing"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newNetworkTemplate returns template of an ECS instance in a VSwitch of a VPC
+func newNetworkTemplate() *Template {
+	return &Template{
+		ROSTemplateFormatVersion: "2015-09-01",
+		Parameters:               map[string]Parameter{},
+		Resources: map[string]Resource{
+			"Vpc": {
+				Type:           "ALIYUN::ECS::VPC",
+				Properties:     map[string]interface{}{"CidrBlock": "192.168.0.0/16"},
+				DependsOn:      []string{},
+				DeletionPolicy: "Retain",
+			},
+			"VSwitch": {
+				Type: "ALIYUN::ECS::VSwitch",
+				Properties: map[string]interface{}{
+					"CidrBlock": "192.168.0.0/24",
+					"VpcId":     map[string][]string{"Fn::GetAtt": {"Vpc", "VpcId"}},
+				},
+				DependsOn:      []string{"Vpc"},
+				DeletionPolicy: "Retain",
+			},
+			"Ecs": {
+				Type: "ALIYUN::ECS::Instance",
+				Properties: map[string]interface{}{
+					"VSwitchId": map[string][]string{"Fn::GetAtt": {"VSwitch", "VSwitchId"}},
+				},
+				DependsOn:      []string{"VSwitch"},
+				DeletionPolicy: "Retain",
+			},
+		},
+		Outputs: map[string]Output{
+			"Vpc.VpcId":      {Description: "VPC ID", Value: map[string][2]string{"Fn::GetAtt": {"Vpc", "VpcId"}}},
+			"Ecs.InstanceId": {Value: map[string][2]string{"Fn::GetAtt": {"Ecs", "InstanceId"}}},
+		},
+	}
+}
+
+func TestTemplate_nest(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  map[string][]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "TestParentRefersNestedStack",
+			groups: map[string][]string{"Network": {"Vpc", "VSwitch"}},
+			want: `{
+				"ROSTemplateFormatVersion": "2015-09-01",
+				"Resources": {
+					"Ecs": {
+						"Type": "ALIYUN::ECS::Instance",
+						"Properties": {"VSwitchId": {"Fn::GetAtt": ["Network", "VSwitch.VSwitchId"]}},
+						"DependsOn": ["Network"],
+						"DeletionPolicy": "Retain"
+					},
+					"Network": {
+						"Type": "ALIYUN::ROS::Stack",
+						"Properties": {
+							"TemplateBody": {
+								"ROSTemplateFormatVersion": "2015-09-01",
+								"Resources": {
+									"Vpc": {
+										"Type": "ALIYUN::ECS::VPC",
+										"Properties": {"CidrBlock": "192.168.0.0/16"},
+										"DeletionPolicy": "Retain"
+									},
+									"VSwitch": {
+										"Type": "ALIYUN::ECS::VSwitch",
+										"Properties": {"CidrBlock": "192.168.0.0/24", "VpcId": {"Fn::GetAtt": ["Vpc", "VpcId"]}},
+										"DependsOn": ["Vpc"],
+										"DeletionPolicy": "Retain"
+									}
+								},
+								"Outputs": {
+									"Vpc.VpcId": {"Description": "VPC ID", "Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}},
+									"VSwitch.VSwitchId": {"Value": {"Fn::GetAtt": ["VSwitch", "VSwitchId"]}}
+								}
+							}
+						},
+						"DeletionPolicy": "Delete"
+					}
+				},
+				"Outputs": {
+					"Vpc.VpcId": {"Description": "VPC ID", "Value": {"Fn::GetAtt": ["Network", "Vpc.VpcId"]}},
+					"Ecs.InstanceId": {"Value": {"Fn::GetAtt": ["Ecs", "InstanceId"]}}
+				}
+			}`,
+		},
+		{
+			name:   "TestNestedStackRefersParent",
+			groups: map[string][]string{"Compute": {"Ecs"}},
+			want: `{
+				"ROSTemplateFormatVersion": "2015-09-01",
+				"Resources": {
+					"Vpc": {
+						"Type": "ALIYUN::ECS::VPC",
+						"Properties": {"CidrBlock": "192.168.0.0/16"},
+						"DeletionPolicy": "Retain"
+					},
+					"VSwitch": {
+						"Type": "ALIYUN::ECS::VSwitch",
+						"Properties": {"CidrBlock": "192.168.0.0/24", "VpcId": {"Fn::GetAtt": ["Vpc", "VpcId"]}},
+						"DependsOn": ["Vpc"],
+						"DeletionPolicy": "Retain"
+					},
+					"Compute": {
+						"Type": "ALIYUN::ROS::Stack",
+						"Properties": {
+							"TemplateBody": {
+								"ROSTemplateFormatVersion": "2015-09-01",
+								"Parameters": {"VSwitchVSwitchId": {"Type": "String"}},
+								"Resources": {
+									"Ecs": {
+										"Type": "ALIYUN::ECS::Instance",
+										"Properties": {"VSwitchId": {"Ref": "VSwitchVSwitchId"}},
+										"DeletionPolicy": "Retain"
+									}
+								},
+								"Outputs": {
+									"Ecs.InstanceId": {"Value": {"Fn::GetAtt": ["Ecs", "InstanceId"]}}
+								}
+							},
+							"Parameters": {"VSwitchVSwitchId": {"Fn::GetAtt": ["VSwitch", "VSwitchId"]}}
+						},
+						"DependsOn": ["VSwitch"],
+						"DeletionPolicy": "Delete"
+					}
+				},
+				"Outputs": {
+					"Vpc.VpcId": {"Description": "VPC ID", "Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}},
+					"Ecs.InstanceId": {"Value": {"Fn::GetAtt": ["Compute", "Ecs.InstanceId"]}}
+				}
+			}`,
+		},
+		{
+			name:    "TestNameOfComponentInstance",
+			groups:  map[string][]string{"Vpc": {"VSwitch"}},
+			wantErr: "Nested stack 'Vpc' has the same name as a component instance",
+		},
+		{
+			name:    "TestUnknownComponentInstance",
+			groups:  map[string][]string{"Network": {"Vpc", "NoVSwitch"}},
+			wantErr: "Nested stack 'Network' refers a no exist component instance 'NoVSwitch'",
+		},
+		{
+			name:    "TestComponentInstanceInTwoNestedStacks",
+			groups:  map[string][]string{"Network": {"Vpc"}, "Other": {"Vpc"}},
+			wantErr: "Component instance 'Vpc' is in both nested stack 'Network' and 'Other'",
+		},
+		{
+			name:    "TestCycle",
+			groups:  map[string][]string{"A": {"Vpc", "Ecs"}, "B": {"VSwitch"}},
+			wantErr: "Nested stacks depend on each other through 'A'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := newNetworkTemplate()
+			err := template.nest(tt.groups)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			got, _ := template.Marshal()
+			assert.JSONEq(t, tt.want, string(got))
+			assert.True(t, HasNestedStacks(string(got)))
+		})
+	}
+}
+
+func TestTemplate_nest_ParameterTypes(t *testing.T) {
+	settingsSchema, err := schema.Parse(`{
+		"type": "object",
+		"properties": {
+			"BackendServers": {"type": "array", "items": {"type": "object", "properties": {"Weight": {"type": "integer"}}}},
+			"InstanceIds": {"type": "array", "items": {"type": "string"}},
+			"Listener": {"type": "object"},
+			"Port": {"type": "integer"},
+			"Enabled": {"type": "boolean"}
+		}
+	}`)
+	assert.Nil(t, err)
+	template := &Template{
+		ROSTemplateFormatVersion: "2015-09-01",
+		Parameters:               map[string]Parameter{},
+		Resources: map[string]Resource{
+			"Ecs": {Type: "ALIYUN::ECS::InstanceGroup", Properties: map[string]interface{}{}},
+			"Slb": {
+				Type: "ALIYUN::SLB::BackendServerAttachment",
+				Properties: map[string]interface{}{
+					"BackendServers": []interface{}{
+						map[string]interface{}{"Weight": map[string][]string{"Fn::GetAtt": {"Ecs", "Weight"}}},
+					},
+					"InstanceIds": map[string][]string{"Fn::GetAtt": {"Ecs", "InstanceIds"}},
+					"Listener":    map[string][]string{"Fn::GetAtt": {"Ecs", "Listener"}},
+					"Port":        map[string][]string{"Fn::GetAtt": {"Ecs", "Port"}},
+					"Enabled":     map[string][]string{"Fn::GetAtt": {"Ecs", "Enabled"}},
+					"Name": map[string]interface{}{"Fn::Join": []interface{}{
+						"-", map[string][]string{"Fn::GetAtt": {"Ecs", "HostNames"}},
+					}},
+					"Description": map[string]interface{}{"Fn::Select": []interface{}{
+						"0", map[string][]string{"Fn::GetAtt": {"Ecs", "PrivateIps"}},
+					}},
+					"Other": map[string][]string{"Fn::GetAtt": {"Ecs", "ZoneId"}},
+				},
+			},
+		},
+		Outputs:         map[string]Output{},
+		settingsSchemas: map[string]*schema.JsonSchema{"Slb": settingsSchema},
+	}
+
+	assert.Nil(t, template.nest(map[string][]string{"Lb": {"Slb"}}))
+	child := template.Resources["Lb"].Properties["TemplateBody"].(*Template)
+	types := make(map[string]string)
+	for name, parameter := range child.Parameters {
+		types[name] = parameter.Type
+	}
+	assert.Equal(t, map[string]string{
+		"EcsWeight":      "Number",
+		"EcsInstanceIds": "CommaDelimitedList",
+		"EcsListener":    "Json",
+		"EcsPort":        "Number",
+		"EcsEnabled":     "Boolean",
+		"EcsHostNames":   "CommaDelimitedList",
+		"EcsPrivateIps":  "CommaDelimitedList",
+		"EcsZoneId":      "String",
+	}, types)
+}
+
+func TestTemplate_nest_References(t *testing.T) {
+	template := &Template{
+		ROSTemplateFormatVersion: "2015-09-01",
+		Parameters:               map[string]Parameter{"Env": {Name: "Env", Type: "String", Default: "prod"}},
+		Mappings: map[string]interface{}{
+			"Cidrs": map[string]interface{}{"prod": map[string]interface{}{"VSwitch": "192.168.0.0/24"}},
+			"Other": map[string]interface{}{},
+		},
+		Conditions: map[string]interface{}{
+			"IsProd": map[string]interface{}{"Fn::Equals": []interface{}{map[string]interface{}{"Ref": "Env"}, "prod"}},
+			"IsHangzhou": map[string]interface{}{"Fn::And": []interface{}{
+				map[string]interface{}{"Condition": "IsProd"},
+				map[string]interface{}{"Fn::Equals": []interface{}{
+					map[string]interface{}{"Ref": "ALIYUN::Region"}, "cn-hangzhou"}},
+			}},
+			"Unused": map[string]interface{}{"Fn::Equals": []interface{}{"a", "b"}},
+		},
+		Resources: map[string]Resource{
+			"Vpc": {Type: "ALIYUN::ECS::VPC", Properties: map[string]interface{}{}},
+			"VSwitch": {
+				Type:      "ALIYUN::ECS::VSwitch",
+				Condition: "IsHangzhou",
+				Properties: map[string]interface{}{
+					"VpcId": map[string]interface{}{"Ref": "Vpc"},
+					"CidrBlock": map[string]interface{}{"Fn::FindInMap": []interface{}{
+						"Cidrs", map[string]interface{}{"Ref": "Env"}, "VSwitch"}},
+					"Description": map[string]interface{}{"Fn::If": []interface{}{"IsProd", "prod", "test"}},
+					"ZoneId":      map[string]interface{}{"Ref": "ALIYUN::Region"},
+				},
+			},
+			"Ecs": {
+				Type:       "ALIYUN::ECS::Instance",
+				Properties: map[string]interface{}{"VSwitchId": map[string]interface{}{"Ref": "VSwitch"}},
+			},
+		},
+		Outputs: map[string]Output{
+			"VSwitchId": {Value: map[string]interface{}{"Ref": "VSwitch"}},
+		},
+	}
+
+	assert.Nil(t, template.nest(map[string][]string{"Network": {"VSwitch"}}))
+	got, _ := template.Marshal()
+	assert.JSONEq(t, `{
+		"ROSTemplateFormatVersion": "2015-09-01",
+		"Parameters": {"Env": {"Type": "String", "Default": "prod"}},
+		"Mappings": {
+			"Cidrs": {"prod": {"VSwitch": "192.168.0.0/24"}},
+			"Other": {}
+		},
+		"Conditions": {
+			"IsProd": {"Fn::Equals": [{"Ref": "Env"}, "prod"]},
+			"IsHangzhou": {"Fn::And": [{"Condition": "IsProd"}, {"Fn::Equals": [{"Ref": "ALIYUN::Region"}, "cn-hangzhou"]}]},
+			"Unused": {"Fn::Equals": ["a", "b"]}
+		},
+		"Resources": {
+			"Vpc": {"Type": "ALIYUN::ECS::VPC"},
+			"Ecs": {
+				"Type": "ALIYUN::ECS::Instance",
+				"Properties": {"VSwitchId": {"Fn::GetAtt": ["Network", "VSwitch.Ref"]}}
+			},
+			"Network": {
+				"Type": "ALIYUN::ROS::Stack",
+				"Properties": {
+					"TemplateBody": {
+						"ROSTemplateFormatVersion": "2015-09-01",
+						"Parameters": {
+							"Env": {"Type": "String", "Default": "prod"},
+							"Vpc": {"Type": "String"}
+						},
+						"Mappings": {"Cidrs": {"prod": {"VSwitch": "192.168.0.0/24"}}},
+						"Conditions": {
+							"IsProd": {"Fn::Equals": [{"Ref": "Env"}, "prod"]},
+							"IsHangzhou": {"Fn::And": [{"Condition": "IsProd"}, {"Fn::Equals": [{"Ref": "ALIYUN::Region"}, "cn-hangzhou"]}]}
+						},
+						"Resources": {
+							"VSwitch": {
+								"Type": "ALIYUN::ECS::VSwitch",
+								"Condition": "IsHangzhou",
+								"Properties": {
+									"VpcId": {"Ref": "Vpc"},
+									"CidrBlock": {"Fn::FindInMap": ["Cidrs", {"Ref": "Env"}, "VSwitch"]},
+									"Description": {"Fn::If": ["IsProd", "prod", "test"]},
+									"ZoneId": {"Ref": "ALIYUN::Region"}
+								}
+							}
+						},
+						"Outputs": {"VSwitch.Ref": {"Value": {"Ref": "VSwitch"}}}
+					},
+					"Parameters": {"Env": {"Ref": "Env"}, "Vpc": {"Ref": "Vpc"}}
+				},
+				"DependsOn": ["Vpc"],
+				"DeletionPolicy": "Delete"
+			}
+		},
+		"Outputs": {
+			"VSwitchId": {"Value": {"Fn::GetAtt": ["Network", "VSwitch.Ref"]}}
+		}
+	}`, string(got))
+}
+
+func TestNestedStacksOf(t *testing.T) {
+	groups, err := NestedStacksOf(map[string]string{"ros.aliyun.com/nested-stacks": `{"Network": ["Vpc", "VSwitch"]}`})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"Network": {"Vpc", "VSwitch"}}, groups)
+
+	groups, err = NestedStacksOf(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, groups)
+
+	_, err = NestedStacksOf(map[string]string{"ros.aliyun.com/nested-stacks": `["Vpc"]`})
+	assert.EqualError(t, err, "Invalid annotation 'ros.aliyun.com/nested-stacks', which must be a json object of nested stack names to component instance names")
+}
+
+func TestTemplate_CheckReplacement_NestedStack(t *testing.T) {
+	previous := newNetworkTemplate()
+	assert.Nil(t, previous.nest(map[string][]string{"Network": {"Vpc", "VSwitch"}}))
+	previousBody, _ := previous.Marshal()
+
+	current := newNetworkTemplate()
+	current.Resources["Vpc"].Properties["CidrBlock"] = "10.0.0.0/8"
+	current.updateAllowed = map[string]map[string]bool{"Vpc": {"CidrBlock": false}}
+	assert.Nil(t, current.nest(map[string][]string{"Network": {"Vpc", "VSwitch"}}))
+	currentBody, _ := current.Marshal()
+
+	diff, err := DiffTemplates(string(previousBody), string(currentBody))
+	assert.Nil(t, err)
+	err = current.CheckReplacement(diff)
+	assert.Equal(t, &ReplacementError{Properties: []ReplacementProperty{{LogicalId: "Vpc", Property: "CidrBlock"}}}, err)
+}
+
+func TestStack_ListNestedStacks(t *testing.T) {
+	fake := rosclient.NewFake()
+	fake.Stacks["stack-1"] = &rosclient.FakeStack{Id: "stack-1", Name: "MyStack", Status: "CREATE_FAILED"}
+	fake.Stacks["stack-2"] = &rosclient.FakeStack{
+		Id: "stack-2", Name: "MyStack-Network-abc", Status: "CREATE_FAILED", StatusReason: "quota exceeded",
+		ParentStackId: "stack-1", Tags: map[string]string{"ros.aliyun.com/app-uid": "app-uid"},
+	}
+	fake.Stacks["stack-3"] = &rosclient.FakeStack{Id: "stack-3", Name: "OtherStack", Status: "CREATE_COMPLETE"}
+
+	stack := &Stack{Client: fake, Id: "stack-1", Name: "MyStack"}
+	nestedStacks, err := stack.ListNestedStacks()
+	assert.Nil(t, err)
+	assert.Equal(t, []NestedStack{
+		{Id: "stack-2", Name: "MyStack-Network-abc", Status: "CREATE_FAILED", StatusReason: "quota exceeded"},
+	}, nestedStacks)
+	assert.Equal(t, "nested stack MyStack-Network-abc CREATE_FAILED: quota exceeded", FailedReasonOf(nestedStacks))
+
+	// nested stacks are not listed as top level stacks
+	appConf := &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "app", UID: "app-uid"}}
+	stack, err = FindStack(&appconf.Context{AppConf: appConf, RosClient: fake}, "MyStack-Network-abc")
+	assert.Nil(t, err)
+	assert.Nil(t, stack)
+
+	nestedStacks, err = (&Stack{Id: DryRunFakeStack}).ListNestedStacks()
+	assert.Nil(t, err)
+	assert.Nil(t, nestedStacks)
+	assert.Equal(t, "", FailedReasonOf([]NestedStack{{Name: "Network", Status: "CREATE_COMPLETE"}}))
+}
@@ -0,0 +1,103 @@
+package ros
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+)
+
+// RawTemplateKind is kind of workload type ros.aliyun.com/v1alpha1.RawTemplate, whose workload settings are a ROS
+// template fragment merged into the template as is. It expresses features of ROS which generated workload types
+// can not, e.g. Conditions, Mappings and intrinsic functions like Fn::Join and Fn::Sub.
+const RawTemplateKind = "RawTemplate"
+
+// Fragment is ROS template fragment of a raw template component
+type Fragment struct {
+	Mappings   map[string]interface{} `json:"Mappings,omitempty"`
+	Conditions map[string]interface{} `json:"Conditions,omitempty"`
+	Resources  map[string]Resource    `json:"Resources,omitempty"`
+	Outputs    map[string]Output      `json:"Outputs,omitempty"`
+}
+
+// parseFragment parses workload settings of raw template component
+func parseFragment(compConf v1alpha1.ComponentConfiguration, compSpec v1alpha1.ComponentSpec) (*Fragment, error) {
+	instanceName := compConf.InstanceName
+	if len(compConf.ParameterValues) > 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"Component instance '%s' of %s does not support parameterValues", instanceName, RawTemplateKind))
+	}
+
+	fragment := &Fragment{}
+	decoder := json.NewDecoder(bytes.NewReader(compSpec.WorkloadSettings.Raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(fragment)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid raw template of component instance '%s': %s", instanceName, err))
+	}
+
+	for logicalId, resource := range fragment.Resources {
+		if resource.Type == "" {
+			return nil, errors.New(fmt.Sprintf(
+				"Resource '%s' in raw template of component instance '%s' misses Type", logicalId, instanceName))
+		}
+		// resources are retained like resources of other components unless specified
+		if resource.DeletionPolicy == "" {
+			resource.DeletionPolicy = "Retain"
+		}
+		fragment.Resources[logicalId] = resource
+	}
+	return fragment, nil
+}
+
+// mergeFragment merges fragment of component instance into template, and returns an error if any part of fragment
+// already exists in template
+func (t *Template) mergeFragment(instanceName string, fragment *Fragment) error {
+	collision := func(section, name string) error {
+		return errors.New(fmt.Sprintf(
+			"Raw template of component instance '%s' redefines '%s' in %s", instanceName, name, section))
+	}
+
+	for name := range fragment.Mappings {
+		if _, ok := t.Mappings[name]; ok {
+			return collision("Mappings", name)
+		}
+	}
+	for name := range fragment.Conditions {
+		if _, ok := t.Conditions[name]; ok {
+			return collision("Conditions", name)
+		}
+	}
+	for logicalId := range fragment.Resources {
+		if _, ok := t.Resources[logicalId]; ok {
+			return collision("Resources", logicalId)
+		}
+	}
+	for name := range fragment.Outputs {
+		if _, ok := t.Outputs[name]; ok {
+			return collision("Outputs", name)
+		}
+	}
+
+	for name, mapping := range fragment.Mappings {
+		if t.Mappings == nil {
+			t.Mappings = make(map[string]interface{})
+		}
+		t.Mappings[name] = mapping
+	}
+	for name, condition := range fragment.Conditions {
+		if t.Conditions == nil {
+			t.Conditions = make(map[string]interface{})
+		}
+		t.Conditions[name] = condition
+	}
+	for logicalId, resource := range fragment.Resources {
+		t.Resources[logicalId] = resource
+	}
+	for name, output := range fragment.Outputs {
+		t.Outputs[name] = output
+	}
+	return nil
+}
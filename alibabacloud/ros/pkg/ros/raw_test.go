@@ -0,0 +1,202 @@
+package ros
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const logProjectFragment = `{
+  "Conditions": {"IsProd": {"Fn::Equals": [{"Ref": "ALIYUN::Region"}, "cn-hangzhou"]}},
+  "Resources": {
+    "LogProject": {
+      "Type": "ALIYUN::SLS::Project",
+      "Condition": "IsProd",
+      "Properties": {"Name": {"Fn::Join": ["-", ["vpc", {"Fn::GetAtt": ["Vpc", "VpcId"]}]]}},
+      "DependsOn": ["Vpc"]
+    }
+  },
+  "Outputs": {
+    "LogProjectName": {"Condition": "IsProd", "Value": {"Fn::GetAtt": ["LogProject", "Name"]}}
+  }
+}`
+
+func TestNewTemplate_RawTemplate(t *testing.T) {
+	tests := []struct {
+		name            string
+		settings        string
+		parameterValues []v1alpha1.ParameterValue
+		parameters      map[string]map[string]interface{}
+		dryRun          bool
+		want            string
+		wantErr         string
+	}{
+		{
+			name:     "TestNormal",
+			settings: logProjectFragment,
+			want: `{
+  "ROSTemplateFormatVersion": "2015-09-01",
+  "Conditions": {"IsProd": {"Fn::Equals": [{"Ref": "ALIYUN::Region"}, "cn-hangzhou"]}},
+  "Resources": {
+    "Vpc": {"Type": "ALIYUN::ECS::VPC", "Properties": {"CidrBlock": "192.168.0.0/16"}, "DeletionPolicy": "Retain"},
+    "LogProject": {
+      "Type": "ALIYUN::SLS::Project",
+      "Condition": "IsProd",
+      "Properties": {"Name": {"Fn::Join": ["-", ["vpc", {"Fn::GetAtt": ["Vpc", "VpcId"]}]]}},
+      "DependsOn": ["Vpc"],
+      "DeletionPolicy": "Retain"
+    }
+  },
+  "Outputs": {
+    "Vpc.VpcId": {"Description": "ID of VPC", "Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}},
+    "LogProjectName": {"Condition": "IsProd", "Value": {"Fn::GetAtt": ["LogProject", "Name"]}}
+  }
+}`,
+		},
+		{
+			name:     "TestDryRun",
+			settings: `{"Mappings": {"Zones": {"cn-hangzhou": {"Zone": "cn-hangzhou-h"}}}, "Resources": {"Log": {"Type": "ALIYUN::SLS::Project", "DeletionPolicy": "Delete"}}}`,
+			dryRun:   true,
+			want: `{
+  "ROSTemplateFormatVersion": "2015-09-01",
+  "Mappings": {"Zones": {"cn-hangzhou": {"Zone": "cn-hangzhou-h"}}},
+  "Resources": {
+    "Vpc": {"Type": "ALIYUN::ECS::VPC", "Properties": {"CidrBlock": "192.168.0.0/16"}, "DeletionPolicy": "Retain"},
+    "Log": {"Type": "ALIYUN::SLS::Project", "DeletionPolicy": "Delete"}
+  },
+  "Outputs": {
+    "Vpc.VpcId": {"Description": "ID of VPC", "Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}}
+  }
+}`,
+		},
+		{
+			name: "TestResourceKeys",
+			settings: `{"Resources": {"LogProject": {
+  "Type": "ALIYUN::SLS::Project",
+  "Metadata": {"Owner": "ops"},
+  "Count": 2,
+  "CreationPolicy": {"ResourceSignal": {"Count": 2}},
+  "UpdatePolicy": {"PauseTime": "PT1M"}
+}}}`,
+			want: `{
+  "ROSTemplateFormatVersion": "2015-09-01",
+  "Resources": {
+    "Vpc": {"Type": "ALIYUN::ECS::VPC", "Properties": {"CidrBlock": "192.168.0.0/16"}, "DeletionPolicy": "Retain"},
+    "LogProject": {
+      "Type": "ALIYUN::SLS::Project",
+      "Metadata": {"Owner": "ops"},
+      "Count": 2,
+      "CreationPolicy": {"ResourceSignal": {"Count": 2}},
+      "UpdatePolicy": {"PauseTime": "PT1M"},
+      "DeletionPolicy": "Retain"
+    }
+  },
+  "Outputs": {
+    "Vpc.VpcId": {"Description": "ID of VPC", "Value": {"Fn::GetAtt": ["Vpc", "VpcId"]}}
+  }
+}`,
+		},
+		{
+			name:     "TestRedefineResource",
+			settings: `{"Resources": {"Vpc": {"Type": "ALIYUN::ECS::VPC"}}}`,
+			wantErr:  "Raw template of component instance 'Log' redefines 'Vpc' in Resources",
+		},
+		{
+			name:     "TestRedefineOutput",
+			settings: `{"Outputs": {"Vpc.VpcId": {"Value": "vpc-1"}}}`,
+			wantErr:  "Raw template of component instance 'Log' redefines 'Vpc.VpcId' in Outputs",
+		},
+		{
+			name:     "TestUnknownSection",
+			settings: `{"Parameters": {"Name": {"Type": "String"}}}`,
+			wantErr:  "Invalid raw template of component instance 'Log'",
+		},
+		{
+			name:     "TestMissingType",
+			settings: `{"Resources": {"LogProject": {"Properties": {"Name": "log"}}}}`,
+			wantErr:  "Resource 'LogProject' in raw template of component instance 'Log' misses Type",
+		},
+		{
+			name:            "TestParameterValues",
+			settings:        logProjectFragment,
+			parameterValues: []v1alpha1.ParameterValue{{Name: "Name", Value: "log"}},
+			wantErr:         "Component instance 'Log' of RawTemplate does not support parameterValues",
+		},
+		{
+			name:       "TestTargetParameters",
+			settings:   logProjectFragment,
+			parameters: map[string]map[string]interface{}{"Log": {"Name": "log"}},
+			wantErr:    "parameters of target in region cn-hangzhou override raw template component instance 'Log'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := rosclient.NewFake()
+			fake.ResourceTypes["ALIYUN::ECS::VPC"] = &rosapi.GetResourceTypeResponse{
+				ResourceType: "ALIYUN::ECS::VPC",
+				Properties:   map[string]interface{}{"CidrBlock": map[string]interface{}{"UpdateAllowed": false}},
+				Attributes:   map[string]interface{}{"VpcId": map[string]interface{}{"Description": "ID of VPC"}},
+			}
+			fake.ResourceTypes["ALIYUN::SLS::Project"] = &rosapi.GetResourceTypeResponse{
+				ResourceType: "ALIYUN::SLS::Project",
+				Properties:   map[string]interface{}{"Name": map[string]interface{}{"UpdateAllowed": false}},
+			}
+
+			appConf := &appconf.AppConf{
+				ObjectMeta: v1.ObjectMeta{Namespace: "MyNamespace"},
+				Spec: v1alpha1.ApplicationConfigurationSpec{
+					Components: []v1alpha1.ComponentConfiguration{
+						{InstanceName: "Log", ComponentName: "LogComp", ParameterValues: tt.parameterValues},
+						{InstanceName: "Vpc", ComponentName: "VpcComp"},
+					},
+				},
+			}
+			template, err := NewTemplate(
+				&appconf.Context{DryRun: tt.dryRun, RegionId: "cn-hangzhou", Parameters: tt.parameters, RosClient: fake},
+				appConf,
+				WithCompSchematicGetter(func(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
+					if name == "VpcComp" {
+						return &v1alpha1.ComponentSchematic{
+							Spec: v1alpha1.ComponentSpec{
+								WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VPC",
+								WorkloadSettings: runtime.RawExtension{Raw: []byte(`{"CidrBlock": "192.168.0.0/16"}`)},
+							},
+						}, nil
+					}
+					return &v1alpha1.ComponentSchematic{
+						Spec: v1alpha1.ComponentSpec{
+							WorkloadType:     "ros.aliyun.com/v1alpha1.RawTemplate",
+							WorkloadSettings: runtime.RawExtension{Raw: []byte(tt.settings)},
+						},
+					}, nil
+				}),
+				WithWorkloadSchemaGetter(func(namespace, kind string) (*schema.JsonSchema, error) {
+					return nil, nil
+				}),
+				WithWorkloadAttributesGetter(func(namespace, kind string) (map[string]interface{}, error) {
+					assert.Equal(t, "ECS_VPC", kind)
+					return fake.ResourceTypes["ALIYUN::ECS::VPC"].Attributes, nil
+				}))
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			marshalled, err := json.Marshal(template)
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.want, string(marshalled))
+			if !tt.dryRun {
+				assert.Equal(t, map[string]bool{"Name": false}, template.updateAllowed["LogProject"])
+			}
+		})
+	}
+}
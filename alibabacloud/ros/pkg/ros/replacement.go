@@ -0,0 +1,117 @@
+package ros
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+)
+
+// ReplacementProperty is a changed property which ROS can not update in place
+type ReplacementProperty struct {
+	LogicalId string
+	Property  string
+}
+
+// String returns {LogicalId}.{Property}
+func (p ReplacementProperty) String() string {
+	return p.LogicalId + "." + p.Property
+}
+
+// ReplacementError reports changed properties which cause resource replacement
+type ReplacementError struct {
+	Properties []ReplacementProperty
+}
+
+// Error implements error
+func (e *ReplacementError) Error() string {
+	names := make([]string, 0, len(e.Properties))
+	for _, property := range e.Properties {
+		names = append(names, property.String())
+	}
+	return fmt.Sprintf(
+		"Update is blocked since non-updatable properties are changed: %s. Set annotation '%s: \"true\"' to allow replacement",
+		strings.Join(names, ", "), config.ALLOW_REPLACEMENT_ANNOTATION)
+}
+
+// IsReplacementAllowed returns true if annotations explicitly allow resource replacement
+func IsReplacementAllowed(annotations map[string]string) bool {
+	return annotations[config.ALLOW_REPLACEMENT_ANNOTATION] == "true"
+}
+
+// CheckReplacement returns ReplacementError if any modified property in diff is not allowed to update
+func (t *Template) CheckReplacement(diff *TemplateDiff) error {
+	var properties []ReplacementProperty
+	for _, change := range diff.Resources {
+		if change.Action != Modified {
+			continue
+		}
+
+		found := make(map[string]bool)
+		for _, path := range change.Paths {
+			// changes of resources in nested stacks are checked as changes of the resources
+			logicalId := change.LogicalId
+			if change.ResourceType == NestedStackType {
+				var ok bool
+				logicalId, path, ok = nestedResourceChangeOf(path)
+				if !ok {
+					continue
+				}
+			}
+
+			name := replacementPropertyOf(path, t.updateAllowed[logicalId])
+			key := logicalId + "." + name
+			if name == "" || found[key] {
+				continue
+			}
+			found[key] = true
+			properties = append(properties, ReplacementProperty{LogicalId: logicalId, Property: name})
+		}
+	}
+
+	if len(properties) == 0 {
+		return nil
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].String() < properties[j].String()
+	})
+	return &ReplacementError{Properties: properties}
+}
+
+// replacementPropertyOf returns name of the top level property changed at path if it causes replacement
+func replacementPropertyOf(path string, updateAllowed map[string]bool) string {
+	if path == "Type" {
+		return path
+	}
+	if !strings.HasPrefix(path, "Properties.") {
+		return ""
+	}
+	name := strings.TrimPrefix(path, "Properties.")
+	if i := strings.IndexAny(name, ".["); i >= 0 {
+		name = name[:i]
+	}
+	// unknown properties are regarded as updatable
+	if allowed, ok := updateAllowed[name]; ok && !allowed {
+		return name
+	}
+	return ""
+}
+
+// setUpdateAllowed records whether properties of resource can be updated in place by ROS resource type properties
+func (t *Template) setUpdateAllowed(logicalId string, resourceTypeProperties map[string]interface{}) {
+	if t.updateAllowed == nil {
+		t.updateAllowed = make(map[string]map[string]bool)
+	}
+	updateAllowed := make(map[string]bool)
+	for name, property := range resourceTypeProperties {
+		property, ok := property.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if allowed, ok := property["UpdateAllowed"].(bool); ok {
+			updateAllowed[name] = allowed
+		}
+	}
+	t.updateAllowed[logicalId] = updateAllowed
+}
@@ -0,0 +1,103 @@
+package ros
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTemplate_CheckReplacement(t *testing.T) {
+	newTemplate := func(vpcType string, vpcProperties map[string]interface{}) *Template {
+		return &Template{
+			ROSTemplateFormatVersion: "2015-09-01",
+			Resources: map[string]Resource{
+				"Vpc": {Type: vpcType, Properties: vpcProperties},
+				"VSwitch": {
+					Type: "ALIYUN::ECS::VSwitch",
+					Properties: map[string]interface{}{
+						"ZoneId": "cn-hangzhou-h",
+						"VpcId":  map[string][]string{"Fn::GetAtt": {"Vpc", "VpcId"}},
+					},
+				},
+			},
+			updateAllowed: map[string]map[string]bool{
+				"Vpc":     {"CidrBlock": false, "VpcName": true, "ResourceGroupId": false},
+				"VSwitch": {"ZoneId": false, "VpcId": false},
+			},
+		}
+	}
+	previous := newTemplate("ALIYUN::ECS::VPC", map[string]interface{}{
+		"CidrBlock": "192.168.0.0/16",
+		"VpcName":   "MyVpc",
+	})
+	previousBytes, _ := previous.Marshal()
+	previousBody := string(previousBytes)
+
+	tests := []struct {
+		name         string
+		template     *Template
+		previousBody string
+		want         error
+	}{
+		{
+			name:         "TestNoPrevious",
+			template:     newTemplate("ALIYUN::ECS::VPC", map[string]interface{}{"CidrBlock": "10.0.0.0/8"}),
+			previousBody: "",
+			want:         nil,
+		},
+		{
+			name:         "TestSame",
+			template:     newTemplate("ALIYUN::ECS::VPC", map[string]interface{}{"CidrBlock": "192.168.0.0/16", "VpcName": "MyVpc"}),
+			previousBody: previousBody,
+			want:         nil,
+		},
+		{
+			name:         "TestUpdatableChanged",
+			template:     newTemplate("ALIYUN::ECS::VPC", map[string]interface{}{"CidrBlock": "192.168.0.0/16", "VpcName": "NewVpc", "Description": "new"}),
+			previousBody: previousBody,
+			want:         nil,
+		},
+		{
+			name:         "TestNonUpdatableChanged",
+			template:     newTemplate("ALIYUN::ECS::VPC", map[string]interface{}{"CidrBlock": "10.0.0.0/8", "ResourceGroupId": "rg"}),
+			previousBody: previousBody,
+			want: &ReplacementError{Properties: []ReplacementProperty{
+				{LogicalId: "Vpc", Property: "CidrBlock"},
+				{LogicalId: "Vpc", Property: "ResourceGroupId"},
+			}},
+		},
+		{
+			name:         "TestTypeChanged",
+			template:     newTemplate("ALIYUN::VPC::VPC", map[string]interface{}{"CidrBlock": "192.168.0.0/16", "VpcName": "MyVpc"}),
+			previousBody: previousBody,
+			want: &ReplacementError{Properties: []ReplacementProperty{
+				{LogicalId: "Vpc", Property: "Type"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateBody, _ := tt.template.Marshal()
+			diff, err := DiffTemplates(tt.previousBody, string(templateBody))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, tt.template.CheckReplacement(diff))
+		})
+	}
+}
+
+func TestReplacementError_Error(t *testing.T) {
+	err := &ReplacementError{Properties: []ReplacementProperty{
+		{LogicalId: "Vpc", Property: "CidrBlock"},
+		{LogicalId: "VSwitch", Property: "ZoneId"},
+	}}
+	assert.Equal(
+		t,
+		`Update is blocked since non-updatable properties are changed: Vpc.CidrBlock, VSwitch.ZoneId. Set annotation 'ros.aliyun.com/allow-replacement: "true"' to allow replacement`,
+		err.Error(),
+	)
+}
+
+func TestIsReplacementAllowed(t *testing.T) {
+	assert.True(t, IsReplacementAllowed(map[string]string{"ros.aliyun.com/allow-replacement": "true"}))
+	assert.False(t, IsReplacementAllowed(map[string]string{"ros.aliyun.com/allow-replacement": "false"}))
+	assert.False(t, IsReplacementAllowed(nil))
+}
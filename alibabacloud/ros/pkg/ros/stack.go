@@ -2,11 +2,16 @@ package ros
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"strings"
 	"time"
 )
 
@@ -21,7 +26,9 @@ const (
 )
 
 type Stack struct {
-	Client        *rosapi.Client
+	Client        rosclient.Interface
+	RegionId      string                   `json:"RegionId"`
+	Namespace     string                   `json:"Namespace"`
 	Id            string                   `json:"Id"`
 	Name          string                   `json:"Name"`
 	Status        string                   `json:"Status"`
@@ -57,6 +64,7 @@ const (
 // stackOption defines secret option
 type stackOption struct {
 	DryRunHandler func(stack *Stack, request requests.AcsRequest) error
+	Creations     int
 }
 
 // StackOption has methods to work with secret option.
@@ -88,6 +96,14 @@ func WithDryRunHandler(dryRunHandler func(stack *Stack, request requests.AcsRequ
 	})
 }
 
+// WithCreations sets number of stacks created for application before, which makes client token of
+// a recreated stack different from that of the deleted one
+func WithCreations(creations int) StackOption {
+	return newStackFuncOption(func(o *stackOption) {
+		o.Creations = creations
+	})
+}
+
 // NewStack parses application configuration and creates a ROS stack
 func NewStack(appContext *appconf.Context, stackName string, template *Template, opts ...StackOption) (stack *Stack, err error) {
 	// init option
@@ -127,9 +143,15 @@ func NewStack(appContext *appconf.Context, stackName string, template *Template,
 	request.DisableRollback = "false"
 	request.Parameters = &parameters
 	request.TemplateBody = string(templateBody)
+	request.ClientToken = ClientTokenOf(appContext, o.Creations)
+	if uid := appUidOf(appContext); uid != "" {
+		request.Tags = &[]rosapi.CreateStackTags{{Key: config.APP_UID_TAG, Value: uid}}
+	}
 
 	stack = &Stack{
 		Client:        appContext.RosClient,
+		RegionId:      appContext.RegionId,
+		Namespace:     NamespaceOf(appContext),
 		Name:          stackName,
 		dryRunHandler: o.DryRunHandler,
 	}
@@ -139,6 +161,17 @@ func NewStack(appContext *appconf.Context, stackName string, template *Template,
 		return stack, stack.dryRunHandler(stack, request)
 	}
 
+	// large template body is passed by its location
+	location, err := stack.storeTemplate(request.TemplateBody)
+	if err != nil {
+		return
+	}
+	if location != nil {
+		request.TemplateBody = ""
+		request.TemplateURL = location.URL
+		request.TemplateId = location.Id
+	}
+
 	response, err := appContext.RosClient.CreateStack(request)
 	if err != nil {
 		return
@@ -148,6 +181,84 @@ func NewStack(appContext *appconf.Context, stackName string, template *Template,
 	return
 }
 
+// FindStack finds the stack of stack name which is not deleted and tagged by uid of application, e.g. a stack created
+// before controller crashes and saves its id. Returns nil stack if there is no such stack, and an error if the stack
+// failed to be deleted, which should not be created again beside it.
+func FindStack(appContext *appconf.Context, stackName string) (stack *Stack, err error) {
+	if appContext.DryRun {
+		return nil, nil
+	}
+	// stacks of other owners are never adopted
+	uid := appUidOf(appContext)
+	if uid == "" {
+		return nil, nil
+	}
+
+	request := rosapi.CreateListStacksRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.StackName = &[]string{stackName}
+	request.Tag = &[]rosapi.ListStacksTag{{Key: config.APP_UID_TAG, Value: uid}}
+	request.PageSize = "50"
+
+	listed := 0
+	for pageNumber := 1; ; pageNumber++ {
+		request.PageNumber = requests.NewInteger(pageNumber)
+		response, err := appContext.RosClient.ListStacks(request)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range response.Stacks {
+			// nested stacks are managed by their parent stacks
+			if s.StackName != stackName || s.ParentStackId != "" {
+				continue
+			}
+			stack = &Stack{
+				Client:       appContext.RosClient,
+				RegionId:     appContext.RegionId,
+				Namespace:    NamespaceOf(appContext),
+				Id:           s.StackId,
+				Name:         s.StackName,
+				Status:       s.Status,
+				StatusReason: s.StatusReason,
+			}
+			if StackStatusType(stack.Status) == DeleteFailed {
+				return nil, errors.New(fmt.Sprintf("Stack %s (%s) of application failed to be deleted: %s",
+					stack.Name, stack.Id, stack.StatusReason))
+			}
+			if !stack.IsInDeleteStatus() {
+				return stack, nil
+			}
+		}
+		listed += len(response.Stacks)
+		if len(response.Stacks) == 0 || listed >= response.TotalCount {
+			return nil, nil
+		}
+	}
+}
+
+// ClientTokenOf returns client token of creating stack for application. It is derived from uid and generation
+// of application, so that ROS creates only one stack for the same generation even if creation is retried, and
+// from number of stacks created before, so that a stack deleted and recreated in the same generation is new.
+func ClientTokenOf(appContext *appconf.Context, creations int) string {
+	if appUidOf(appContext) == "" {
+		return ""
+	}
+	meta := appContext.AppConf.GetObjectMeta()
+	if creations == 0 {
+		return fmt.Sprintf("%s-%d", meta.UID, meta.Generation)
+	}
+	return fmt.Sprintf("%s-%d-%d", meta.UID, meta.Generation, creations)
+}
+
+// appUidOf returns uid of application of context, empty if there is no application
+func appUidOf(appContext *appconf.Context) string {
+	if appContext.AppConf == nil {
+		return ""
+	}
+	return string(appContext.AppConf.GetObjectMeta().UID)
+}
+
 func (s *Stack) Update(template *Template) error {
 	// template body
 	templateBody, err := json.Marshal(template)
@@ -176,6 +287,17 @@ func (s *Stack) Update(template *Template) error {
 		return s.dryRunHandler(s, request)
 	}
 
+	// large template body is passed by its location
+	location, err := s.storeTemplate(request.TemplateBody)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		request.TemplateBody = ""
+		request.TemplateURL = location.URL
+		request.TemplateId = location.Id
+	}
+
 	_, err = s.Client.UpdateStack(request)
 	if err != nil {
 		return err
@@ -197,6 +319,7 @@ func (s *Stack) Delete() error {
 	if err != nil {
 		return err
 	}
+	s.deleteStoredTemplate()
 
 	return nil
 }
@@ -223,6 +346,83 @@ func (s *Stack) Refresh() error {
 	return nil
 }
 
+// NestedStack is a nested stack created by resource of parent stack
+type NestedStack struct {
+	Id           string `json:"Id"`
+	Name         string `json:"Name"`
+	Status       string `json:"Status"`
+	StatusReason string `json:"StatusReason,omitempty"`
+}
+
+// ListNestedStacks lists nested stacks whose parent stack is the stack
+func (s *Stack) ListNestedStacks() (nestedStacks []NestedStack, err error) {
+	if s.Id == DryRunFakeStack {
+		return nil, nil
+	}
+
+	request := rosapi.CreateListStacksRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.ParentStackId = s.Id
+	request.ShowNestedStack = "true"
+	request.PageSize = "50"
+
+	for pageNumber := 1; ; pageNumber++ {
+		request.PageNumber = requests.NewInteger(pageNumber)
+		response, err := s.Client.ListStacks(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, nested := range response.Stacks {
+			nestedStacks = append(nestedStacks, NestedStack{
+				Id:           nested.StackId,
+				Name:         nested.StackName,
+				Status:       nested.Status,
+				StatusReason: nested.StatusReason,
+			})
+		}
+		if len(response.Stacks) == 0 || len(nestedStacks) >= response.TotalCount {
+			return nestedStacks, nil
+		}
+	}
+}
+
+// FailedReasonOf returns status reasons of failed nested stacks, empty if none of them fails
+func FailedReasonOf(nestedStacks []NestedStack) string {
+	var reasons []string
+	for _, nested := range nestedStacks {
+		status := StackStatusType(nested.Status)
+		if strings.HasSuffix(nested.Status, "_FAILED") || status == CreateRollbackComplete || status == RollbackComplete {
+			reasons = append(reasons, fmt.Sprintf("nested stack %s %s: %s", nested.Name, nested.Status, nested.StatusReason))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Operation returns the operation which leads to current stack status, e.g. "create" for CREATE_COMPLETE
+func (s *Stack) Operation() string {
+	switch {
+	case strings.HasPrefix(s.Status, "CREATE_"):
+		return metrics.CreateOperation
+	case strings.HasPrefix(s.Status, "DELETE_"):
+		return metrics.DeleteOperation
+	default:
+		return metrics.UpdateOperation
+	}
+}
+
+// NamespaceOf returns namespace of application in context
+func NamespaceOf(appContext *appconf.Context) string {
+	if appContext.AppConf == nil {
+		return ""
+	}
+	return appContext.AppConf.GetNamespace()
+}
+
+// IsInProgress returns true if stack operation is not done yet
+func (s *Stack) IsInProgress() bool {
+	return strings.HasSuffix(s.Status, "_IN_PROGRESS")
+}
+
 func (s *Stack) IsInDeleteStatus() bool {
 	status := StackStatusType(s.Status)
 	return status == DeleteInProgress || status == DeleteFailed || status == DeleteComplete
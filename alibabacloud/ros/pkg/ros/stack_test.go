@@ -2,11 +2,14 @@ package ros
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 )
 
@@ -271,3 +274,168 @@ func TestStack_WaitUntilDone(t *testing.T) {
 		})
 	}
 }
+
+func TestStack_Operation(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: "CREATE_COMPLETE", want: "create"},
+		{status: "CREATE_ROLLBACK_COMPLETE", want: "create"},
+		{status: "UPDATE_FAILED", want: "update"},
+		{status: "ROLLBACK_COMPLETE", want: "update"},
+		{status: "DELETE_COMPLETE", want: "delete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			stack := &Stack{Status: tt.status}
+			assert.Equal(t, tt.want, stack.Operation())
+		})
+	}
+}
+
+func TestNewStack_ClientToken(t *testing.T) {
+	fake := rosclient.NewFake()
+	appConf := &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "app", UID: "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11", Generation: 2}}
+	appContext := &appconf.Context{AppConf: appConf, RosClient: fake}
+
+	// creating stack again for the same generation returns the same stack
+	stack, err := NewStack(appContext, "MyStack", template)
+	assert.Nil(t, err)
+	stack2, err := NewStack(appContext, "MyStack", template)
+	assert.Nil(t, err)
+	assert.Equal(t, stack.Id, stack2.Id)
+	assert.Equal(t, "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11-2", fake.Stacks[stack.Id].ClientToken)
+
+	// a new generation creates a new stack
+	appConf.Generation = 3
+	stack3, err := NewStack(appContext, "MyStack", template)
+	assert.Nil(t, err)
+	assert.NotEqual(t, stack.Id, stack3.Id)
+
+	// recreating stack deleted in the same generation creates a new stack
+	stack4, err := NewStack(appContext, "MyStack", template, WithCreations(1))
+	assert.Nil(t, err)
+	assert.NotEqual(t, stack3.Id, stack4.Id)
+	assert.Equal(t, "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11-3-1", fake.Stacks[stack4.Id].ClientToken)
+
+	// no client token without application
+	assert.Equal(t, "", ClientTokenOf(&appconf.Context{}, 0))
+}
+
+func TestFindStack(t *testing.T) {
+	const uid = "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11"
+	owned := map[string]string{"ros.aliyun.com/app-uid": uid}
+
+	// a whole page of deleted stacks before the stack
+	var deletedStacks []*rosclient.FakeStack
+	for i := 0; i < 50; i++ {
+		deletedStacks = append(deletedStacks, &rosclient.FakeStack{
+			Id: fmt.Sprintf("stack-1-%02d", i), Name: "MyStack", Status: "DELETE_COMPLETE", Tags: owned})
+	}
+
+	tests := []struct {
+		name        string
+		stacks      []*rosclient.FakeStack
+		dryRun      bool
+		noApp       bool
+		wantStackId string
+		wantErr     string
+	}{
+		{
+			name: "TestFound",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-1", Name: "MyStack", Status: "CREATE_IN_PROGRESS", Tags: owned},
+				{Id: "stack-2", Name: "OtherStack", Status: "CREATE_COMPLETE", Tags: owned},
+			},
+			wantStackId: "stack-1",
+		},
+		{
+			name:        "TestFoundAfterPageOfDeleted",
+			stacks:      append(deletedStacks, &rosclient.FakeStack{Id: "stack-2", Name: "MyStack", Status: "CREATE_IN_PROGRESS", Tags: owned}),
+			wantStackId: "stack-2",
+		},
+		{
+			name: "TestNotFound",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-2", Name: "OtherStack", Status: "CREATE_COMPLETE", Tags: owned},
+			},
+		},
+		{
+			name: "TestSkipDeleted",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-1", Name: "MyStack", Status: "DELETE_COMPLETE", Tags: owned},
+			},
+		},
+		{
+			name: "TestSkipNotOwned",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-1", Name: "MyStack", Status: "CREATE_IN_PROGRESS"},
+				{Id: "stack-2", Name: "MyStack", Status: "CREATE_IN_PROGRESS",
+					Tags: map[string]string{"ros.aliyun.com/app-uid": "other-uid"}},
+			},
+		},
+		{
+			name: "TestDeleteFailed",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-1", Name: "MyStack", Status: "DELETE_FAILED", StatusReason: "resource in use", Tags: owned},
+			},
+			wantErr: "Stack MyStack (stack-1) of application failed to be deleted: resource in use",
+		},
+		{
+			name: "TestNoApplication",
+			stacks: []*rosclient.FakeStack{
+				{Id: "stack-1", Name: "MyStack", Status: "CREATE_IN_PROGRESS", Tags: owned},
+			},
+			noApp: true,
+		},
+		{
+			name:   "TestDryRun",
+			dryRun: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := rosclient.NewFake()
+			for _, stack := range tt.stacks {
+				fake.Stacks[stack.Id] = stack
+			}
+			appContext := &appconf.Context{DryRun: tt.dryRun, RosClient: fake}
+			if !tt.noApp {
+				appContext.AppConf = &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "app", UID: uid}}
+			}
+			stack, err := FindStack(appContext, "MyStack")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			if tt.wantStackId == "" {
+				assert.Nil(t, stack)
+			} else {
+				assert.Equal(t, tt.wantStackId, stack.Id)
+				assert.Equal(t, "MyStack", stack.Name)
+				assert.True(t, stack.IsInProgress())
+			}
+		})
+	}
+}
+
+func TestNewStack_Tags(t *testing.T) {
+	fake := rosclient.NewFake()
+	appConf := &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "app", UID: "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11"}}
+	stack, err := NewStack(&appconf.Context{AppConf: appConf, RosClient: fake}, "MyStack", template)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"ros.aliyun.com/app-uid": "8a6e1b0c-2c7f-4e33-9d55-2f0e8a6f4b11"},
+		fake.Stacks[stack.Id].Tags)
+
+	// created stack is adopted by its application only
+	found, err := FindStack(&appconf.Context{AppConf: appConf, RosClient: fake}, "MyStack")
+	assert.Nil(t, err)
+	assert.Equal(t, stack.Id, found.Id)
+	other := &appconf.AppConf{ObjectMeta: v1.ObjectMeta{Name: "app", UID: "other-uid"}}
+	found, err = FindStack(&appconf.Context{AppConf: other, RosClient: fake}, "MyStack")
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+}
@@ -0,0 +1,319 @@
+package ros
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+)
+
+// TemplateLocation is where template body of stack is stored, which is passed to ROS instead of template body
+type TemplateLocation struct {
+	// URL is url of template body, e.g. oss://bucket/key
+	URL string
+	// Id is id of ROS template whose latest version is template body
+	Id string
+}
+
+// TemplateStore stores template bodies of stacks which are too large to be passed to ROS inline
+type TemplateStore interface {
+	// Store stores template body of stack and returns its location
+	Store(stack *Stack, templateBody string) (*TemplateLocation, error)
+	// Delete deletes template body of stack, and does nothing if it is not stored
+	Delete(stack *Stack) error
+}
+
+var (
+	// templateStore stores template bodies larger than threshold, nil if template bodies are always passed inline
+	templateStore     TemplateStore
+	templateStoreLock sync.RWMutex
+)
+
+// SetTemplateStore sets template store of template bodies larger than config.RosCtrlConf.TemplateUrlThreshold.
+// Nil means template bodies are always passed inline.
+func SetTemplateStore(store TemplateStore) {
+	templateStoreLock.Lock()
+	defer templateStoreLock.Unlock()
+	templateStore = store
+}
+
+// getTemplateStore returns template store, nil if it is not set
+func getTemplateStore() TemplateStore {
+	templateStoreLock.RLock()
+	defer templateStoreLock.RUnlock()
+	return templateStore
+}
+
+// CredentialFunc returns the current credential, e.g. appconf.ControllerCredential
+type CredentialFunc func() (*aliyun.AliyunCredential, error)
+
+// NewTemplateStore returns template store of controller config, nil if no template store is configured. Requests
+// of the oss store are signed by credential.
+func NewTemplateStore(conf config.RosControllerConfig, credential CredentialFunc) (TemplateStore, error) {
+	switch conf.TemplateStore {
+	case "":
+		return nil, nil
+	case config.TEMPLATE_STORE_ROS:
+		return &RosTemplateStore{}, nil
+	case config.TEMPLATE_STORE_OSS:
+		if conf.TemplateOssBucket == "" || conf.TemplateOssEndpoint == "" {
+			return nil, errors.New("Template store oss requires bucket and endpoint of OSS")
+		}
+		return NewOssTemplateStore(conf.TemplateOssEndpoint, conf.TemplateOssBucket, conf.TemplateOssRegionId,
+			credential), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown template store %s, which must be oss or ros", conf.TemplateStore))
+	}
+}
+
+// RosTemplateStore stores template bodies as ROS templates named by stack names in accounts of stacks.
+// A template is updated to a new version every time template body of its stack is stored.
+type RosTemplateStore struct{}
+
+// Store implements TemplateStore
+func (r *RosTemplateStore) Store(stack *Stack, templateBody string) (*TemplateLocation, error) {
+	templateId, err := r.find(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	if templateId == "" {
+		request := rosapi.CreateCreateTemplateRequest()
+		request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+		request.TemplateName = stack.Name
+		request.TemplateBody = templateBody
+		request.Description = fmt.Sprintf("Template of stack %s", stack.Name)
+		response, err := stack.Client.CreateTemplate(request)
+		if err != nil {
+			return nil, err
+		}
+		return &TemplateLocation{Id: response.TemplateId}, nil
+	}
+
+	request := rosapi.CreateUpdateTemplateRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.TemplateId = templateId
+	request.TemplateBody = templateBody
+	if _, err := stack.Client.UpdateTemplate(request); err != nil {
+		return nil, err
+	}
+	return &TemplateLocation{Id: templateId}, nil
+}
+
+// Delete implements TemplateStore
+func (r *RosTemplateStore) Delete(stack *Stack) error {
+	templateId, err := r.find(stack)
+	if err != nil || templateId == "" {
+		return err
+	}
+
+	request := rosapi.CreateDeleteTemplateRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.TemplateId = templateId
+	_, err = stack.Client.DeleteTemplate(request)
+	return err
+}
+
+// find returns id of template of stack, empty if there is not. Templates are listed page by page, as ROS
+// lists templates whose names contain the name of stack too.
+func (r *RosTemplateStore) find(stack *Stack) (string, error) {
+	request := rosapi.CreateListTemplatesRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.TemplateName = stack.Name
+	request.PageSize = "50"
+
+	listed := 0
+	for pageNumber := 1; ; pageNumber++ {
+		request.PageNumber = requests.NewInteger(pageNumber)
+		response, err := stack.Client.ListTemplates(request)
+		if err != nil {
+			return "", err
+		}
+		for _, template := range response.Templates {
+			if template.TemplateName == stack.Name {
+				return template.TemplateId, nil
+			}
+		}
+		listed += len(response.Templates)
+		if len(response.Templates) == 0 || listed >= response.TotalCount {
+			return "", nil
+		}
+	}
+}
+
+// credentialRefreshBefore is how long before its expiration credential of OSS template store is got again
+const credentialRefreshBefore = 5 * time.Minute
+
+// OssTemplateStore stores template bodies as objects of an OSS bucket, which are passed to ROS by oss:// urls.
+// Accounts of stacks must be allowed to read the bucket.
+type OssTemplateStore struct {
+	// Endpoint is endpoint of OSS, e.g. https://oss-cn-hangzhou.aliyuncs.com
+	Endpoint string
+	Bucket   string
+	// RegionId is region of bucket, empty if bucket is in regions of stacks
+	RegionId string
+	// Credential returns credential which signs requests, which is kept until it expires
+	Credential CredentialFunc
+	Client     *http.Client
+
+	credentialLock sync.Mutex
+	credential     *aliyun.AliyunCredential
+}
+
+// NewOssTemplateStore returns template store of OSS bucket
+func NewOssTemplateStore(endpoint, bucket, regionId string, credential CredentialFunc) *OssTemplateStore {
+	return &OssTemplateStore{
+		Endpoint:   endpoint,
+		Bucket:     bucket,
+		RegionId:   regionId,
+		Credential: credential,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Store implements TemplateStore
+func (o *OssTemplateStore) Store(stack *Stack, templateBody string) (*TemplateLocation, error) {
+	key := o.keyOf(stack)
+	if err := o.do(http.MethodPut, key, []byte(templateBody)); err != nil {
+		return nil, err
+	}
+
+	templateURL := fmt.Sprintf("oss://%s/%s", o.Bucket, key)
+	if o.RegionId != "" {
+		templateURL += "?RegionId=" + url.QueryEscape(o.RegionId)
+	}
+	return &TemplateLocation{URL: templateURL}, nil
+}
+
+// Delete implements TemplateStore
+func (o *OssTemplateStore) Delete(stack *Stack) error {
+	return o.do(http.MethodDelete, o.keyOf(stack), nil)
+}
+
+// keyOf returns object key of template body of stack
+func (o *OssTemplateStore) keyOf(stack *Stack) string {
+	return fmt.Sprintf("ros-oam/%s.json", stack.Name)
+}
+
+// getCredential returns the kept credential, or gets credential again if there is none or it expires soon
+func (o *OssTemplateStore) getCredential() (*aliyun.AliyunCredential, error) {
+	o.credentialLock.Lock()
+	defer o.credentialLock.Unlock()
+
+	if o.credential != nil {
+		expiration := aliyun.ParseExpiration(o.credential.Expiration)
+		if expiration.IsZero() || time.Now().Add(credentialRefreshBefore).Before(expiration) {
+			return o.credential, nil
+		}
+	}
+	credential, err := o.Credential()
+	if err != nil {
+		return nil, err
+	}
+	if credential.AccessKeyId == "" || credential.AccessKeySecret == "" {
+		return nil, errors.New("Template store oss requires access key of controller")
+	}
+	o.credential = credential
+	return credential, nil
+}
+
+// do sends request of object to OSS, which is signed by credential
+func (o *OssTemplateStore) do(method, key string, body []byte) error {
+	credential, err := o.getCredential()
+	if err != nil {
+		return err
+	}
+	endpoint, err := url.Parse(o.Endpoint)
+	if err != nil {
+		return err
+	}
+	objectURL := *endpoint
+	objectURL.Path = "/" + key
+
+	request, err := http.NewRequest(method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// bucket is addressed by virtual host
+	request.Host = o.Bucket + "." + endpoint.Host
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+		request.Header.Set("Content-Type", contentType)
+	}
+	date := time.Now().UTC().Format(http.TimeFormat)
+	request.Header.Set("Date", date)
+	if credential.SecurityToken != "" {
+		request.Header.Set(ossSecurityTokenHeader, credential.SecurityToken)
+	}
+	request.Header.Set("Authorization", fmt.Sprintf("OSS %s:%s",
+		credential.AccessKeyId, o.sign(credential, method, contentType, date, key)))
+
+	response, err := o.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := ioutil.ReadAll(response.Body)
+		return errors.New(fmt.Sprintf("%s oss://%s/%s failed with status %d: %s",
+			method, o.Bucket, key, response.StatusCode, message))
+	}
+	return nil
+}
+
+// ossSecurityTokenHeader is header of security token of STS credential
+const ossSecurityTokenHeader = "x-oss-security-token"
+
+// sign returns signature of request of object by credential
+func (o *OssTemplateStore) sign(credential *aliyun.AliyunCredential, method, contentType, date, key string) string {
+	ossHeaders := ""
+	if credential.SecurityToken != "" {
+		ossHeaders = ossSecurityTokenHeader + ":" + credential.SecurityToken + "\n"
+	}
+	stringToSign := fmt.Sprintf("%s\n\n%s\n%s\n%s/%s/%s", method, contentType, date, ossHeaders, o.Bucket, key)
+	mac := hmac.New(sha1.New, []byte(credential.AccessKeySecret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// storeTemplate stores template body of stack by template store if it is larger than threshold, and returns its
+// location. Returns nil location if template body is passed inline.
+func (s *Stack) storeTemplate(templateBody string) (*TemplateLocation, error) {
+	store := getTemplateStore()
+	if store == nil || len(templateBody) <= config.RosCtrlConf.TemplateUrlThreshold {
+		return nil, nil
+	}
+
+	location, err := store.Store(s, templateBody)
+	if err != nil {
+		return nil, err
+	}
+	logging.Default.Info("Template body stored", StackName, s.Name, "Size", len(templateBody),
+		"TemplateURL", location.URL, "TemplateId", location.Id)
+	return location, nil
+}
+
+// deleteStoredTemplate deletes template body of stack stored by template store
+func (s *Stack) deleteStoredTemplate() {
+	store := getTemplateStore()
+	if store == nil {
+		return
+	}
+	if err := store.Delete(s); err != nil {
+		logging.Default.Error(err, "Delete stored template body failed", StackName, s.Name)
+	}
+}
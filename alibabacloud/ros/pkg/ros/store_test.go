@@ -0,0 +1,259 @@
+package ros
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOss is a local stand-in of OSS bucket which keeps objects in memory
+type fakeOss struct {
+	sync.Mutex
+	t *testing.T
+	// store is client of bucket, whose signatures are verified by credential of bucket
+	store      *OssTemplateStore
+	credential *aliyun.AliyunCredential
+	objects    map[string]string
+}
+
+func (f *fakeOss) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	assert.True(f.t, strings.HasPrefix(r.Host, f.store.Bucket+"."))
+	signature := f.store.sign(f.credential, r.Method, r.Header.Get("Content-Type"), r.Header.Get("Date"), key)
+	if r.Header.Get("Authorization") != "OSS "+f.credential.AccessKeyId+":"+signature ||
+		r.Header.Get(ossSecurityTokenHeader) != f.credential.SecurityToken {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("SignatureDoesNotMatch"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = string(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newFakeOss(t *testing.T) (*fakeOss, *httptest.Server) {
+	oss := &fakeOss{
+		t:          t,
+		credential: &aliyun.AliyunCredential{AccessKeyId: "ak", AccessKeySecret: "sk"},
+		objects:    make(map[string]string),
+	}
+	server := httptest.NewServer(oss)
+	oss.store = NewOssTemplateStore(server.URL, "my-bucket", "cn-beijing", func() (*aliyun.AliyunCredential, error) {
+		return oss.credential, nil
+	})
+	return oss, server
+}
+
+func TestOssTemplateStore(t *testing.T) {
+	oss, server := newFakeOss(t)
+	defer server.Close()
+
+	stack := &Stack{Name: "MyStack"}
+	location, err := oss.store.Store(stack, templateBody)
+	assert.Nil(t, err)
+	assert.Equal(t, &TemplateLocation{URL: "oss://my-bucket/ros-oam/MyStack.json?RegionId=cn-beijing"}, location)
+	assert.Equal(t, map[string]string{"ros-oam/MyStack.json": templateBody}, oss.objects)
+
+	assert.Nil(t, oss.store.Delete(stack))
+	assert.Empty(t, oss.objects)
+
+	wrong := NewOssTemplateStore(oss.store.Endpoint, "my-bucket", "", func() (*aliyun.AliyunCredential, error) {
+		return &aliyun.AliyunCredential{AccessKeyId: "ak", AccessKeySecret: "wrong"}, nil
+	})
+	_, err = wrong.Store(stack, templateBody)
+	assert.EqualError(t, err, "PUT oss://my-bucket/ros-oam/MyStack.json failed with status 403: SignatureDoesNotMatch")
+}
+
+func TestOssTemplateStore_StsToken(t *testing.T) {
+	oss, server := newFakeOss(t)
+	defer server.Close()
+
+	// credential is got again before it expires
+	gets := 0
+	expiration := time.Now().Add(time.Hour)
+	oss.store.Credential = func() (*aliyun.AliyunCredential, error) {
+		gets++
+		oss.credential = &aliyun.AliyunCredential{
+			AccessKeyId:     "sts-ak",
+			AccessKeySecret: "sts-sk",
+			SecurityToken:   fmt.Sprintf("token-%d", gets),
+			Expiration:      expiration.UTC().Format(time.RFC3339),
+		}
+		return oss.credential, nil
+	}
+
+	stack := &Stack{Name: "MyStack"}
+	_, err := oss.store.Store(stack, templateBody)
+	assert.Nil(t, err)
+	_, err = oss.store.Store(stack, templateBody)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, gets)
+
+	expiration = time.Now().Add(time.Minute)
+	oss.store.credential.Expiration = expiration.UTC().Format(time.RFC3339)
+	assert.Nil(t, oss.store.Delete(stack))
+	assert.Equal(t, 2, gets)
+	assert.Empty(t, oss.objects)
+
+	// credential without access key is refused
+	oss.store.credential = nil
+	oss.store.Credential = func() (*aliyun.AliyunCredential, error) {
+		return &aliyun.AliyunCredential{}, nil
+	}
+	_, err = oss.store.Store(stack, templateBody)
+	assert.EqualError(t, err, "Template store oss requires access key of controller")
+}
+
+func TestRosTemplateStore(t *testing.T) {
+	fake := rosclient.NewFake()
+	store := &RosTemplateStore{}
+	stack := &Stack{Client: fake, Name: "MyStack"}
+
+	// created at first, and updated to new versions later
+	location, err := store.Store(stack, templateBody)
+	assert.Nil(t, err)
+	location2, err := store.Store(stack, "{}")
+	assert.Nil(t, err)
+	assert.Equal(t, location, location2)
+	assert.Equal(t, &rosclient.FakeTemplate{Id: location.Id, Name: "MyStack", TemplateBody: "{}", Version: 2},
+		fake.Templates[location.Id])
+
+	assert.Nil(t, store.Delete(stack))
+	assert.Empty(t, fake.Templates)
+	// nothing to delete
+	assert.Nil(t, store.Delete(stack))
+}
+
+func TestRosTemplateStore_Find(t *testing.T) {
+	fake := rosclient.NewFake()
+	store := &RosTemplateStore{}
+
+	// templates whose names contain the name of stack are listed before its own template
+	for i := 0; i < 60; i++ {
+		id := fmt.Sprintf("template-%02d", i)
+		fake.Templates[id] = &rosclient.FakeTemplate{Id: id, Name: fmt.Sprintf("MyStack-%d", i)}
+	}
+	fake.Templates["template-99"] = &rosclient.FakeTemplate{Id: "template-99", Name: "MyStack"}
+
+	templateId, err := store.find(&Stack{Client: fake, Name: "MyStack"})
+	assert.Nil(t, err)
+	assert.Equal(t, "template-99", templateId)
+
+	templateId, err = store.find(&Stack{Client: fake, Name: "OtherStack"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", templateId)
+}
+
+func TestNewStack_TemplateStore(t *testing.T) {
+	threshold := config.RosCtrlConf.TemplateUrlThreshold
+	defer func() {
+		config.RosCtrlConf.TemplateUrlThreshold = threshold
+		SetTemplateStore(nil)
+	}()
+
+	oss, server := newFakeOss(t)
+	defer server.Close()
+	SetTemplateStore(oss.store)
+
+	// small template body is passed inline
+	config.RosCtrlConf.TemplateUrlThreshold = len(templateBody)
+	fake := rosclient.NewFake()
+	appContext := &appconf.Context{RosClient: fake, RegionId: "cn-hangzhou"}
+	stack, err := NewStack(appContext, "MyStack", template)
+	assert.Nil(t, err)
+	assert.Equal(t, templateBody, fake.Stacks[stack.Id].TemplateBody)
+	assert.Empty(t, oss.objects)
+
+	// large template body is passed by url
+	config.RosCtrlConf.TemplateUrlThreshold = len(templateBody) - 1
+	assert.Nil(t, stack.Update(template))
+	assert.Equal(t, "", fake.Stacks[stack.Id].TemplateBody)
+	assert.Equal(t, "oss://my-bucket/ros-oam/MyStack.json?RegionId=cn-beijing", fake.Stacks[stack.Id].TemplateURL)
+	assert.Equal(t, templateBody, oss.objects["ros-oam/MyStack.json"])
+
+	// stored template body is deleted with stack
+	assert.Nil(t, stack.Delete())
+	assert.Empty(t, oss.objects)
+
+	// template body is passed by id of ROS template
+	SetTemplateStore(&RosTemplateStore{})
+	stack, err = NewStack(appContext, "OtherStack", template)
+	assert.Nil(t, err)
+	assert.Equal(t, templateBody, fake.Stacks[stack.Id].TemplateBody)
+	assert.Len(t, fake.Templates, 1)
+}
+
+func TestNewTemplateStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.RosControllerConfig
+		want    TemplateStore
+		wantErr string
+	}{
+		{
+			name: "TestNoStore",
+			conf: config.RosControllerConfig{},
+		},
+		{
+			name: "TestRos",
+			conf: config.RosControllerConfig{TemplateStore: "ros"},
+			want: &RosTemplateStore{},
+		},
+		{
+			name: "TestOss",
+			conf: config.RosControllerConfig{
+				TemplateStore: "oss", TemplateOssBucket: "my-bucket", TemplateOssEndpoint: "https://oss-cn-hangzhou.aliyuncs.com",
+			},
+			want: NewOssTemplateStore("https://oss-cn-hangzhou.aliyuncs.com", "my-bucket", "", nil),
+		},
+		{
+			name:    "TestOssWithoutBucket",
+			conf:    config.RosControllerConfig{TemplateStore: "oss"},
+			wantErr: "Template store oss requires bucket and endpoint of OSS",
+		},
+		{
+			name:    "TestUnknown",
+			conf:    config.RosControllerConfig{TemplateStore: "s3"},
+			wantErr: "Unknown template store s3, which must be oss or ros",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential := func() (*aliyun.AliyunCredential, error) {
+				return &aliyun.AliyunCredential{AccessKeyId: "ak", AccessKeySecret: "sk"}, nil
+			}
+			got, err := NewTemplateStore(tt.conf, credential)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			// oss store signs requests by the given credential
+			if oss, ok := got.(*OssTemplateStore); ok {
+				assert.NotNil(t, oss.Credential)
+				oss.Credential = nil
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
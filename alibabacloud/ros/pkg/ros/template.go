@@ -6,20 +6,34 @@ import (
 	"fmt"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
 	"strings"
+	"time"
 
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/component"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/region"
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/workload"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 )
 
 type Template struct {
-	ROSTemplateFormatVersion string               `json:"ROSTemplateFormatVersion"`
-	Description              string               `json:"Description,omitempty"`
-	Parameters               map[string]Parameter `json:"Parameters,omitempty"`
-	Resources                map[string]Resource  `json:"Resources,omitempty"`
-	Outputs                  map[string]Output    `json:"Outputs,omitempty"`
+	ROSTemplateFormatVersion string                 `json:"ROSTemplateFormatVersion"`
+	Description              string                 `json:"Description,omitempty"`
+	Parameters               map[string]Parameter   `json:"Parameters,omitempty"`
+	Mappings                 map[string]interface{} `json:"Mappings,omitempty"`
+	Conditions               map[string]interface{} `json:"Conditions,omitempty"`
+	Resources                map[string]Resource    `json:"Resources,omitempty"`
+	Outputs                  map[string]Output      `json:"Outputs,omitempty"`
+
+	// updateAllowed records whether properties of resources can be updated in place
+	updateAllowed map[string]map[string]bool
+
+	// settingsSchemas records workload settings schemas of resources, which type parameters of nested stacks
+	settingsSchemas map[string]*schema.JsonSchema
 }
 
 type Parameter struct {
@@ -35,16 +49,47 @@ type Resource struct {
 	Properties     map[string]interface{} `json:"Properties,omitempty"`
 	DependsOn      []string               `json:"DependsOn,omitempty"`
 	DeletionPolicy string                 `json:"DeletionPolicy,omitempty"`
+	Condition      string                 `json:"Condition,omitempty"`
+
+	// keys of ROS resources which are only set by raw templates and passed as is
+	Metadata       interface{} `json:"Metadata,omitempty"`
+	Count          interface{} `json:"Count,omitempty"`
+	CreationPolicy interface{} `json:"CreationPolicy,omitempty"`
+	UpdatePolicy   interface{} `json:"UpdatePolicy,omitempty"`
 }
 
 type Output struct {
 	Description string      `json:"Description,omitempty"`
 	Value       interface{} `json:"Value,omitempty"`
+	Condition   string      `json:"Condition,omitempty"`
+}
+
+// PropertyViolation is a schema violation of properties of a component instance
+type PropertyViolation struct {
+	InstanceName string
+	*schema.ValidationError
+}
+
+// InvalidPropertiesError reports every schema violation of properties of components in application configuration
+type InvalidPropertiesError struct {
+	Violations []PropertyViolation
+}
+
+// Error implements error
+func (e *InvalidPropertiesError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.InstanceName, violation.ValidationError))
+	}
+	return "Invalid properties of components: " + strings.Join(messages, "; ")
 }
 
 // templateOption defines template option
 type templateOption struct {
-	CompSchematicGetter func(namespace, name string) (*v1alpha1.ComponentSchematic, error)
+	CompSchematicGetter      func(namespace, name string) (*v1alpha1.ComponentSchematic, error)
+	WorkloadSchemaGetter     func(namespace, kind string) (*schema.JsonSchema, error)
+	WorkloadAttributesGetter func(namespace, kind string) (map[string]interface{}, error)
+	StrictWorkloadType       bool
 }
 
 // TemplateOption has methods to work with template option.
@@ -76,6 +121,29 @@ func WithCompSchematicGetter(compSchematicGetter func(namespace, name string) (*
 	})
 }
 
+// WithWorkloadSchemaGetter sets getter of workload settings JSON Schema in template option
+func WithWorkloadSchemaGetter(workloadSchemaGetter func(namespace, kind string) (*schema.JsonSchema, error)) TemplateOption {
+	return newFuncOption(func(o *templateOption) {
+		o.WorkloadSchemaGetter = workloadSchemaGetter
+	})
+}
+
+// WithWorkloadAttributesGetter sets getter of attributes of workload type in template option, which generates outputs
+// in dry run mode
+func WithWorkloadAttributesGetter(workloadAttributesGetter func(namespace, kind string) (map[string]interface{}, error)) TemplateOption {
+	return newFuncOption(func(o *templateOption) {
+		o.WorkloadAttributesGetter = workloadAttributesGetter
+	})
+}
+
+// WithStrictWorkloadType sets whether components with unsupported workload type of ROS group fail rendering instead of
+// being ignored. Components of other groups are always ignored, as they are not handled by this controller.
+func WithStrictWorkloadType(strict bool) TemplateOption {
+	return newFuncOption(func(o *templateOption) {
+		o.StrictWorkloadType = strict
+	})
+}
+
 // NewTemplate parses application configuration and returns ROS template
 func NewTemplate(appContext *appconf.Context, appConf *appconf.AppConf, opts ...TemplateOption) (*Template, error) {
 	// init option
@@ -88,6 +156,16 @@ func NewTemplate(appContext *appconf.Context, appConf *appconf.AppConf, opts ...
 		o.CompSchematicGetter = component.Get
 	}
 
+	if o.WorkloadSchemaGetter == nil {
+		o.WorkloadSchemaGetter = workload.GetSettingsSchema
+	}
+
+	if o.WorkloadAttributesGetter == nil {
+		o.WorkloadAttributesGetter = workload.GetAttributes
+	}
+
+	defer metrics.ObserveTemplateRender(appContext.RegionId, appConf.Namespace, time.Now())
+
 	// new template
 	template := Template{
 		ROSTemplateFormatVersion: "2015-09-01",
@@ -95,9 +173,22 @@ func NewTemplate(appContext *appconf.Context, appConf *appconf.AppConf, opts ...
 		Resources:                make(map[string]Resource),
 		Outputs:                  make(map[string]Output),
 	}
+	err := region.Validate(appContext.RegionId)
+	if err != nil {
+		return nil, err
+	}
+	err = checkTargetParameters(appContext, appConf)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidProperties := &InvalidPropertiesError{}
+	var rawInstanceNames []string
+	fragments := make(map[string]*Fragment)
 	for _, compConf := range appConf.Spec.Components {
 		// get component
 		instanceName := compConf.InstanceName
+		logicalId := naming.LogicalId(instanceName)
 		compSchematic, err := o.CompSchematicGetter(appConf.Namespace, compConf.ComponentName)
 		if err != nil {
 			return nil, err
@@ -113,6 +204,9 @@ func NewTemplate(appContext *appconf.Context, appConf *appconf.AppConf, opts ...
 		// get resource type
 		resourceType, err := getResourceType(workloadType)
 		if err != nil {
+			if o.StrictWorkloadType && strings.HasPrefix(workloadType, config.ROS_GROUP+"/") {
+				return nil, fmt.Errorf("component instance '%s': %s", instanceName, err)
+			}
 			logging.Default.Error(
 				err, "Ignore component due to invalid workload type",
 				"ComponentName", compConf.ComponentName,
@@ -121,33 +215,218 @@ func NewTemplate(appContext *appconf.Context, appConf *appconf.AppConf, opts ...
 			continue
 		}
 
+		// raw templates are merged after all components, so that they never override components
+		if resourceType == "" {
+			if _, ok := appContext.Parameters[instanceName]; ok {
+				return nil, fmt.Errorf(
+					"parameters of target in region %s override raw template component instance '%s'",
+					appContext.RegionId, instanceName)
+			}
+			fragment, err := parseFragment(compConf, compSchematic.Spec)
+			if err != nil {
+				return nil, err
+			}
+			settingsSchema, err := o.WorkloadSchemaGetter(appConf.Namespace, RawTemplateKind)
+			if err != nil {
+				return nil, err
+			}
+			if settingsSchema != nil {
+				violations, err := validateSettings(instanceName, fragment, settingsSchema)
+				if err != nil {
+					return nil, err
+				}
+				invalidProperties.Violations = append(invalidProperties.Violations, violations...)
+			}
+			rawInstanceNames = append(rawInstanceNames, instanceName)
+			fragments[instanceName] = fragment
+			continue
+		}
+
 		// generate template parts
 		err = template.genResource(resourceType, compConf, compSchematic.Spec, appConf.Spec.Components)
 		if err != nil {
 			return nil, err
 		}
-		// TODO(Prodesire): dry run mode also need to add outputs
-		if !appContext.DryRun {
+		settingsSchema, err := o.WorkloadSchemaGetter(appConf.Namespace, workload.KindOf(workloadType))
+		if err != nil {
+			return nil, err
+		}
+		var coerceViolations []PropertyViolation
+		if settingsSchema != nil {
+			template.setSettingsSchema(logicalId, settingsSchema)
+			coerceViolations = template.coerceParameterValues(compConf, settingsSchema, appContext.Parameters[instanceName])
+		}
+		for name, value := range appContext.Parameters[instanceName] {
+			template.Resources[logicalId].Properties[name] = value
+		}
+		isTemplateRef := workload.KindOf(workloadType) == TemplateRefKind
+		if isTemplateRef {
+			err = template.genTemplateRef(logicalId)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// validate properties by workload settings schema
+		if settingsSchema != nil {
+			violations, err := template.validateResource(instanceName, logicalId, settingsSchema)
+			if err != nil {
+				return nil, err
+			}
+			invalidProperties.Violations = append(invalidProperties.Violations, coerceViolations...)
+			for _, violation := range violations {
+				// parameter values which can not be converted are only reported once
+				if !hasViolation(coerceViolations, violation.Path) {
+					invalidProperties.Violations = append(invalidProperties.Violations, violation)
+				}
+			}
+		}
+
+		if appContext.DryRun {
+			// resource types are not queried in dry run mode, so outputs are generated from attributes shipped with
+			// workload types. Templates of template refs are not read either.
+			if !isTemplateRef {
+				attributes, err := o.WorkloadAttributesGetter(appConf.Namespace, workload.KindOf(workloadType))
+				if err != nil {
+					return nil, err
+				}
+				err = template.genOutputs(logicalId, attributes)
+				if err != nil {
+					return nil, err
+				}
+			}
+		} else {
 			// get resource type detail
-			request := rosapi.CreateGetResourceTypeRequest()
-			request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
-			request.ResourceType = resourceType
-			response, err := appContext.RosClient.GetResourceType(request)
+			response, err := getResourceTypeDetail(appContext, resourceType)
+			if err != nil {
+				return nil, err
+			}
+			if isTemplateRef {
+				err = template.genTemplateRefOutputs(appContext, logicalId)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				err = template.genOutputs(logicalId, response.Attributes)
+				if err != nil {
+					return nil, err
+				}
+			}
+			template.setUpdateAllowed(logicalId, response.Properties)
+		}
+	}
+
+	if len(invalidProperties.Violations) > 0 {
+		return nil, invalidProperties
+	}
+
+	for _, instanceName := range rawInstanceNames {
+		err = template.mergeFragment(instanceName, fragments[instanceName])
+		if err != nil {
+			return nil, err
+		}
+		if appContext.DryRun {
+			continue
+		}
+		for logicalId, resource := range fragments[instanceName].Resources {
+			response, err := getResourceTypeDetail(appContext, resource.Type)
 			if err != nil {
 				return nil, err
 			}
-			template.genOutputs(instanceName, response.Attributes)
+			template.setUpdateAllowed(logicalId, response.Properties)
+		}
+	}
+
+	// group components into nested stacks
+	groups, err := NestedStacksOf(appConf.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > 0 {
+		err = template.nest(groups)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return &template, nil
 }
 
+// checkTargetParameters returns an error if parameters of target override properties of unknown component instances
+func checkTargetParameters(appContext *appconf.Context, appConf *appconf.AppConf) error {
+	for instanceName := range appContext.Parameters {
+		found := false
+		for _, compConf := range appConf.Spec.Components {
+			if compConf.InstanceName == instanceName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("parameters of target in region %s override unknown component instance '%s'",
+				appContext.RegionId, instanceName)
+		}
+	}
+	return nil
+}
+
+// LoadTemplate parses template body applied before with its parameter values
+func LoadTemplate(appContext *appconf.Context, templateBody string, parameterValues map[string]string) (*Template, error) {
+	template := &Template{}
+	err := json.Unmarshal([]byte(templateBody), template)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, parameter := range template.Parameters {
+		parameter.Name = name
+		parameter.Value = parameterValues[name]
+		template.Parameters[name] = parameter
+	}
+
+	if !appContext.DryRun {
+		for logicalId, resource := range template.Resources {
+			response, err := getResourceTypeDetail(appContext, resource.Type)
+			if err != nil {
+				return nil, err
+			}
+			template.setUpdateAllowed(logicalId, response.Properties)
+
+			// resources in nested stacks
+			if resource.Type != NestedStackType {
+				continue
+			}
+			for nestedId, nestedResource := range nestedResourcesOf(resource) {
+				nestedType, _ := nestedResource.(map[string]interface{})["Type"].(string)
+				response, err := getResourceTypeDetail(appContext, nestedType)
+				if err != nil {
+					return nil, err
+				}
+				template.setUpdateAllowed(nestedId, response.Properties)
+			}
+		}
+	}
+
+	return template, nil
+}
+
 // Marshal generates JSON string
 func (t *Template) Marshal() ([]byte, error) {
 	return json.Marshal(t)
 }
 
+// ParameterValues returns values of parameters which are not included in template body
+func (t *Template) ParameterValues() map[string]string {
+	if len(t.Parameters) == 0 {
+		return nil
+	}
+	parameterValues := make(map[string]string)
+	for name, parameter := range t.Parameters {
+		parameterValues[name] = parameter.Value
+	}
+	return parameterValues
+}
+
 // genResource generates Resource in template
 func (t *Template) genResource(
 	resourceType string,
@@ -174,6 +453,10 @@ func (t *Template) genResource(
 	}
 
 	// compConf parameterValues to ROS Properties
+	instanceNames := make(map[string]bool)
+	for _, conf := range compConfs {
+		instanceNames[conf.InstanceName] = true
+	}
 	for _, ParameterValue := range compConf.ParameterValues {
 		name := ParameterValue.Name
 		value := ParameterValue.Value
@@ -207,20 +490,38 @@ func (t *Template) genResource(
 			}
 
 			// set property
-			resource.Properties[name] = map[string][]string{"Fn::GetAtt": {refCompInstanceName, refField}}
+			refLogicalId := naming.LogicalId(refCompInstanceName)
+			resource.Properties[name] = map[string][]string{"Fn::GetAtt": {refLogicalId, refField}}
 
 			// set ROS DependsOn
 			found = false
 			for _, dependOn := range resource.DependsOn {
-				if dependOn == refCompInstanceName {
+				if dependOn == refLogicalId {
 					found = true
 				}
 			}
 			if !found {
-				resource.DependsOn = append(resource.DependsOn, refCompInstanceName)
+				resource.DependsOn = append(resource.DependsOn, refLogicalId)
 			}
 		} else if value != "" {
-			resource.Properties[name] = value
+			compiled, dependsOn, err := compileParameterValue(value, compConf.InstanceName, instanceNames)
+			if err != nil {
+				return err
+			}
+			resource.Properties[name] = compiled
+
+			// set ROS DependsOn
+			for _, refLogicalId := range dependsOn {
+				found := false
+				for _, dependOn := range resource.DependsOn {
+					if dependOn == refLogicalId {
+						found = true
+					}
+				}
+				if !found {
+					resource.DependsOn = append(resource.DependsOn, refLogicalId)
+				}
+			}
 		} else {
 			return errors.New(fmt.Sprintf("Either value or from should be supplied from parameterValues"))
 		}
@@ -245,21 +546,100 @@ func (t *Template) genResource(
 	}
 
 	// set resource
-	logicalId := compConf.InstanceName
+	logicalId := naming.LogicalId(compConf.InstanceName)
+	if _, ok := t.Resources[logicalId]; ok {
+		return errors.New(fmt.Sprintf(
+			"Logical id '%s' of component instance '%s' is used by another component instance", logicalId, compConf.InstanceName))
+	}
 	t.Resources[logicalId] = resource
 
 	return nil
 }
 
-// genOutputs generates Outputs in template
-func (t *Template) genOutputs(instanceName string, resourceAttributes map[string]interface{}) {
-	logicalId := instanceName
+// coerceParameterValues converts string parameter values of component instance to types of their properties in
+// workload settings schema, except parameter values overridden by parameters of target
+func (t *Template) coerceParameterValues(
+	compConf v1alpha1.ComponentConfiguration,
+	settingsSchema *schema.JsonSchema,
+	overrides map[string]interface{},
+) []PropertyViolation {
+	logicalId := naming.LogicalId(compConf.InstanceName)
+	properties := t.Resources[logicalId].Properties
+	var violations []PropertyViolation
+	for _, parameterValue := range compConf.ParameterValues {
+		name := parameterValue.Name
+		if _, ok := overrides[name]; ok {
+			continue
+		}
+		// references and expressions are resolved by ROS
+		value, ok := properties[name].(string)
+		if parameterValue.From != nil || !ok {
+			continue
+		}
+
+		property, ok := settingsSchema.Properties[name]
+		if !ok {
+			property = settingsSchema.AdditionalProperties
+		}
+		coerced, validationError := property.Coerce(schema.RootPath+"."+name, value)
+		if validationError != nil {
+			violations = append(violations, PropertyViolation{InstanceName: compConf.InstanceName, ValidationError: validationError})
+			continue
+		}
+		properties[name] = coerced
+	}
+	return violations
+}
+
+// hasViolation returns true if any of violations is at path
+func hasViolation(violations []PropertyViolation, path string) bool {
+	for _, violation := range violations {
+		if violation.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// validateResource validates properties of resource of component instance against workload settings schema
+func (t *Template) validateResource(instanceName, logicalId string, settingsSchema *schema.JsonSchema) ([]PropertyViolation, error) {
+	return validateSettings(instanceName, t.Resources[logicalId].Properties, settingsSchema)
+}
+
+// validateSettings validates workload settings of component instance against workload settings schema
+func validateSettings(instanceName string, settings interface{}, settingsSchema *schema.JsonSchema) ([]PropertyViolation, error) {
+	// normalize settings to JSON types
+	settingsBytes, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(settingsBytes, &normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []PropertyViolation
+	for _, validationError := range settingsSchema.Validate(normalized) {
+		violations = append(violations, PropertyViolation{InstanceName: instanceName, ValidationError: validationError})
+	}
+	return violations, nil
+}
+
+// genOutputs generates Outputs in template. Returns an error if an attribute is malformed.
+func (t *Template) genOutputs(logicalId string, resourceAttributes map[string]interface{}) error {
 	for name, attribute := range resourceAttributes {
 		var description string
-		attribute := attribute.(map[string]interface{})
-		d := attribute["Description"]
-		if d != nil {
-			description = d.(string)
+		spec, ok := attribute.(map[string]interface{})
+		if !ok {
+			return errors.New(fmt.Sprintf("Attribute '%s' of resource '%s' is not an object", name, logicalId))
+		}
+		if d, ok := spec["Description"]; ok && d != nil {
+			description, ok = d.(string)
+			if !ok {
+				return errors.New(fmt.Sprintf(
+					"Description of attribute '%s' of resource '%s' is not a string", name, logicalId))
+			}
 		}
 		outputName := logicalId + "." + name
 		output := Output{
@@ -268,6 +648,21 @@ func (t *Template) genOutputs(instanceName string, resourceAttributes map[string
 		}
 		t.Outputs[outputName] = output
 	}
+	return nil
+}
+
+// getResourceTypeDetail gets detail of ROS resource type, including attributes and properties
+func getResourceTypeDetail(appContext *appconf.Context, resourceType string) (*rosapi.GetResourceTypeResponse, error) {
+	request := rosapi.CreateGetResourceTypeRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.ResourceType = resourceType
+	return appContext.RosClient.GetResourceType(request)
+}
+
+// ValidateWorkloadType returns an error if workloadType can not be converted to ROS resource type
+func ValidateWorkloadType(workloadType string) error {
+	_, err := getResourceType(workloadType)
+	return err
 }
 
 // getResourceType gets ROS resource type from workloadType
@@ -296,6 +691,13 @@ func getResourceType(workloadType string) (string, error) {
 	}
 
 	type_ := split[1]
+	// components of template workload types are nested stacks or have no resource of their own
+	switch type_ {
+	case TemplateRefKind:
+		return NestedStackType, nil
+	case RawTemplateKind:
+		return "", nil
+	}
 	split = strings.Split(type_, "_")
 	if len(split) != 2 {
 		return "", errors.New(fmt.Sprintf(
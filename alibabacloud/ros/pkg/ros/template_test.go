@@ -1,7 +1,12 @@
 package ros
 
 import (
+	"fmt"
+
 	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/naming"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/workload"
 	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,14 +16,17 @@ import (
 
 func TestNewTemplate(t *testing.T) {
 	type args struct {
-		appContext    *appconf.Context
-		appConf       *appconf.AppConf
-		compSchematic *v1alpha1.ComponentSchematic
+		appContext     *appconf.Context
+		appConf        *appconf.AppConf
+		compSchematic  *v1alpha1.ComponentSchematic
+		settingsSchema string
+		attributes     string
 	}
 	tests := []struct {
-		name string
-		args args
-		want *Template
+		name    string
+		args    args
+		want    *Template
+		wantErr string
 	}{
 		{
 			name: "TestNormal",
@@ -46,6 +54,7 @@ func TestNewTemplate(t *testing.T) {
 							Raw: []byte(`{"VpcName": "MyVpc", "CidrBlock": "192.168.0.0/16", "Description": "My VPC"}`),
 						}},
 				},
+				attributes: `{"VpcId": {"Description": "ID of VPC"}, "VRouterId": {}}`,
 			},
 			want: &Template{
 				ROSTemplateFormatVersion: "2015-09-01",
@@ -61,19 +70,245 @@ func TestNewTemplate(t *testing.T) {
 						DependsOn:      []string{},
 						DeletionPolicy: "Retain",
 					}},
+				Outputs: map[string]Output{
+					"Vpc.VpcId": {
+						Description: "ID of VPC",
+						Value:       map[string][2]string{"Fn::GetAtt": {"Vpc", "VpcId"}},
+					},
+					"Vpc.VRouterId": {
+						Value: map[string][2]string{"Fn::GetAtt": {"Vpc", "VRouterId"}},
+					},
+				},
+			},
+		},
+		{
+			name: "TestInvalidProperties",
+			args: args{
+				appContext: &appconf.Context{DryRun: true},
+				appConf: &appconf.AppConf{
+					ObjectMeta: v1.ObjectMeta{
+						Namespace: "MyNamespace",
+					},
+					Spec: v1alpha1.ApplicationConfigurationSpec{
+						Components: []v1alpha1.ComponentConfiguration{
+							{
+								InstanceName:  "Vpc",
+								ComponentName: "VpcComp",
+								ParameterValues: []v1alpha1.ParameterValue{
+									{
+										Name:  "VpcName",
+										Value: "1vpc",
+									},
+								},
+							},
+						},
+					},
+				},
+				compSchematic: &v1alpha1.ComponentSchematic{
+					Spec: v1alpha1.ComponentSpec{
+						WorkloadType: "ros.aliyun.com/v1alpha1.ECS_VPC",
+						WorkloadSettings: runtime.RawExtension{
+							Raw: []byte(`{"CidrBlok": "192.168.0.0/16", "EnableIpv6": "true"}`),
+						}},
+				},
+				settingsSchema: `{
+					"type": "object",
+					"required": ["CidrBlock"],
+					"properties": {
+						"CidrBlock": {"type": "string"},
+						"EnableIpv6": {"type": "boolean"},
+						"VpcName": {"type": "string", "pattern": "^[a-zA-Z]"}
+					}
+				}`,
+			},
+			want:    nil,
+			wantErr: "Invalid properties of components: Vpc: $.CidrBlock: required property is missing; Vpc: $.EnableIpv6: expected boolean, got string; Vpc: $.VpcName: value '1vpc' does not match pattern '^[a-zA-Z]'",
+		},
+		{
+			name: "TestTypedParameterValues",
+			args: args{
+				appContext: &appconf.Context{DryRun: true},
+				appConf: &appconf.AppConf{
+					ObjectMeta: v1.ObjectMeta{Namespace: "MyNamespace"},
+					Spec: v1alpha1.ApplicationConfigurationSpec{
+						Components: []v1alpha1.ComponentConfiguration{
+							{
+								InstanceName:  "Vpc",
+								ComponentName: "VpcComp",
+								ParameterValues: []v1alpha1.ParameterValue{
+									{Name: "CidrBlock", Value: "192.168.0.0/16"},
+									{Name: "EnableIpv6", Value: "true"},
+									{Name: "Tags", Value: `[{"Key": "env", "Value": "prod"}]`},
+									{Name: "Ttl", Value: "30"},
+								},
+							},
+						},
+					},
+				},
+				compSchematic: &v1alpha1.ComponentSchematic{
+					Spec: v1alpha1.ComponentSpec{
+						WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VPC",
+						WorkloadSettings: runtime.RawExtension{Raw: []byte(`{}`)},
+					},
+				},
+				settingsSchema: `{
+					"type": "object",
+					"properties": {
+						"CidrBlock": {"type": "string"},
+						"EnableIpv6": {"type": "boolean"},
+						"Tags": {"type": "array", "items": {"type": "object"}}
+					},
+					"additionalProperties": {"type": "integer"}
+				}`,
+			},
+			want: &Template{
+				ROSTemplateFormatVersion: "2015-09-01",
+				Parameters:               map[string]Parameter{},
+				Resources: map[string]Resource{
+					"Vpc": {
+						Type: "ALIYUN::ECS::VPC",
+						Properties: map[string]interface{}{
+							"CidrBlock":  "192.168.0.0/16",
+							"EnableIpv6": true,
+							"Tags":       []interface{}{map[string]interface{}{"Key": "env", "Value": "prod"}},
+							"Ttl":        int64(30),
+						},
+						DependsOn:      []string{},
+						DeletionPolicy: "Retain",
+					}},
 				Outputs: map[string]Output{},
 			},
 		},
+		{
+			name: "TestInvalidTypedParameterValues",
+			args: args{
+				appContext: &appconf.Context{DryRun: true},
+				appConf: &appconf.AppConf{
+					ObjectMeta: v1.ObjectMeta{Namespace: "MyNamespace"},
+					Spec: v1alpha1.ApplicationConfigurationSpec{
+						Components: []v1alpha1.ComponentConfiguration{
+							{
+								InstanceName:  "Vpc",
+								ComponentName: "VpcComp",
+								ParameterValues: []v1alpha1.ParameterValue{
+									{Name: "EnableIpv6", Value: "yes"},
+									{Name: "Tags", Value: `{"env": "prod"}`},
+								},
+							},
+						},
+					},
+				},
+				compSchematic: &v1alpha1.ComponentSchematic{
+					Spec: v1alpha1.ComponentSpec{
+						WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VPC",
+						WorkloadSettings: runtime.RawExtension{Raw: []byte(`{"Ttl": "30"}`)},
+					},
+				},
+				settingsSchema: `{
+					"type": "object",
+					"properties": {
+						"EnableIpv6": {"type": "boolean"},
+						"Tags": {"type": "array"},
+						"Ttl": {"type": "integer"}
+					}
+				}`,
+			},
+			want:    nil,
+			wantErr: `Invalid properties of components: Vpc: $.EnableIpv6: can not convert 'yes' to boolean: expected true or false; Vpc: $.Tags: can not convert '{"env": "prod"}' to array: got JSON object; Vpc: $.Ttl: expected integer, got string`,
+		},
+		{
+			name: "TestTargetParameters",
+			args: args{
+				appContext: &appconf.Context{
+					DryRun:     true,
+					RegionId:   "cn-beijing",
+					Parameters: map[string]map[string]interface{}{"Vpc": {"CidrBlock": "10.0.0.0/8"}},
+				},
+				appConf: &appconf.AppConf{
+					ObjectMeta: v1.ObjectMeta{Namespace: "MyNamespace"},
+					Spec: v1alpha1.ApplicationConfigurationSpec{
+						Components: []v1alpha1.ComponentConfiguration{
+							{InstanceName: "Vpc", ComponentName: "VpcComp"},
+						},
+					},
+				},
+				compSchematic: &v1alpha1.ComponentSchematic{
+					Spec: v1alpha1.ComponentSpec{
+						WorkloadType: "ros.aliyun.com/v1alpha1.ECS_VPC",
+						WorkloadSettings: runtime.RawExtension{
+							Raw: []byte(`{"VpcName": "MyVpc", "CidrBlock": "192.168.0.0/16"}`),
+						}},
+				},
+			},
+			want: &Template{
+				ROSTemplateFormatVersion: "2015-09-01",
+				Parameters:               map[string]Parameter{},
+				Resources: map[string]Resource{
+					"Vpc": {
+						Type: "ALIYUN::ECS::VPC",
+						Properties: map[string]interface{}{
+							"VpcName":   "MyVpc",
+							"CidrBlock": "10.0.0.0/8",
+						},
+						DependsOn:      []string{},
+						DeletionPolicy: "Retain",
+					}},
+				Outputs: map[string]Output{},
+			},
+		},
+		{
+			name: "TestTargetParametersOfUnknownInstance",
+			args: args{
+				appContext: &appconf.Context{
+					DryRun:     true,
+					RegionId:   "cn-beijing",
+					Parameters: map[string]map[string]interface{}{"VSwitch": {"CidrBlock": "10.0.0.0/24"}},
+				},
+				appConf: &appconf.AppConf{
+					Spec: v1alpha1.ApplicationConfigurationSpec{
+						Components: []v1alpha1.ComponentConfiguration{
+							{InstanceName: "Vpc", ComponentName: "VpcComp"},
+						},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: "parameters of target in region cn-beijing override unknown component instance 'VSwitch'",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			template, _ := NewTemplate(
+			template, err := NewTemplate(
 				tt.args.appContext,
 				tt.args.appConf,
 				WithCompSchematicGetter(func(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
 					return tt.args.compSchematic, nil
+				}),
+				WithWorkloadSchemaGetter(func(namespace, kind string) (*schema.JsonSchema, error) {
+					assert.Equal(t, "ECS_VPC", kind)
+					if tt.args.settingsSchema == "" {
+						return nil, nil
+					}
+					return schema.Parse(tt.args.settingsSchema)
+				}),
+				WithWorkloadAttributesGetter(func(namespace, kind string) (map[string]interface{}, error) {
+					assert.Equal(t, "ECS_VPC", kind)
+					if tt.args.attributes == "" {
+						return nil, nil
+					}
+					return workload.ParseAttributes(tt.args.attributes)
 				}))
+			if tt.want != nil && tt.args.settingsSchema != "" {
+				settingsSchema, _ := schema.Parse(tt.args.settingsSchema)
+				tt.want.settingsSchemas = map[string]*schema.JsonSchema{"Vpc": settingsSchema}
+			}
 			assert.Equal(t, tt.want, template)
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+			} else {
+				assert.Nil(t, err)
+			}
 		})
 	}
 }
@@ -130,6 +365,20 @@ func TestTemplate_Marshal(t *testing.T) {
 	}
 }
 
+func TestLoadTemplate(t *testing.T) {
+	templateBody := `{"ROSTemplateFormatVersion":"2015-09-01","Parameters":{"VpcName":{"Type":"String"}},"Resources":{"Vpc":{"Type":"ALIYUN::ECS::VPC","Properties":{"CidrBlock":"192.168.0.0/16","VpcName":{"Ref":"VpcName"}}}}}`
+	template, err := LoadTemplate(&appconf.Context{DryRun: true}, templateBody, map[string]string{"VpcName": "MyVpc"})
+	assert.Nil(t, err)
+	assert.Equal(t, Parameter{Name: "VpcName", Type: "String", Value: "MyVpc"}, template.Parameters["VpcName"])
+	assert.Equal(t, map[string]string{"VpcName": "MyVpc"}, template.ParameterValues())
+
+	content, _ := template.Marshal()
+	assert.Equal(t, templateBody, string(content))
+
+	_, err = LoadTemplate(&appconf.Context{DryRun: true}, "{", nil)
+	assert.NotNil(t, err)
+}
+
 func TestTemplate_genResource(t *testing.T) {
 	type args struct {
 		resourceType string
@@ -217,6 +466,46 @@ func TestTemplate_genResource(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "TestExpression",
+			args: args{
+				resourceType: "ALIYUN::ECS::VSwitch",
+				compSpec: v1alpha1.ComponentSpec{
+					WorkloadSettings: runtime.RawExtension{
+						Raw: []byte(`{"CidrBlock": "192.168.0.0/24"}`)}},
+				compConf: v1alpha1.ComponentConfiguration{
+					InstanceName: "VSwitch",
+					ParameterValues: []v1alpha1.ParameterValue{
+						{Name: "VpcId", Value: "${Vpc.VpcId}"},
+						{Name: "VSwitchName", Value: "${Vpc.VpcName}-${ALIYUN::Region}"},
+						{Name: "ZoneId", Value: "${select(0, Zones.ZoneIds)}"},
+					},
+				},
+				compConfs: []v1alpha1.ComponentConfiguration{
+					{InstanceName: "Vpc"},
+					{InstanceName: "VSwitch"},
+					{InstanceName: "Zones"},
+				},
+			},
+			want: map[string]Resource{
+				"VSwitch": {
+					Type: "ALIYUN::ECS::VSwitch",
+					Properties: map[string]interface{}{
+						"CidrBlock": "192.168.0.0/24",
+						"VpcId":     map[string][]string{"Fn::GetAtt": {"Vpc", "VpcId"}},
+						"VSwitchName": map[string]interface{}{"Fn::Sub": []interface{}{
+							"${Var1}-${ALIYUN::Region}",
+							map[string]interface{}{"Var1": map[string][]string{"Fn::GetAtt": {"Vpc", "VpcName"}}},
+						}},
+						"ZoneId": map[string]interface{}{"Fn::Select": []interface{}{
+							"0", map[string][]string{"Fn::GetAtt": {"Zones", "ZoneIds"}},
+						}},
+					},
+					DependsOn:      []string{"Vpc", "Zones"},
+					DeletionPolicy: "Retain",
+				},
+			},
+		},
 		{
 			name: "TestDeletePolicy",
 			args: args{
@@ -294,15 +583,50 @@ func TestTemplate_genResource(t *testing.T) {
 	}
 }
 
+func TestTemplate_genResource_LogicalId(t *testing.T) {
+	compSpec := v1alpha1.ComponentSpec{WorkloadSettings: runtime.RawExtension{Raw: []byte(`{}`)}}
+	compConfs := []v1alpha1.ComponentConfiguration{
+		{InstanceName: "my.vpc"},
+		{InstanceName: "my.vswitch", ParameterValues: []v1alpha1.ParameterValue{
+			{Name: "VpcId", From: &v1alpha1.ParameterFrom{Component: "my.vpc", FieldPath: ".status.VpcId"}},
+			{Name: "VSwitchName", Value: "${my.vpc.VpcName}"},
+		}},
+	}
+	vpcId := naming.LogicalId("my.vpc")
+	vSwitchId := naming.LogicalId("my.vswitch")
+
+	template, _ := NewTemplate(&appconf.Context{DryRun: true}, &appconf.AppConf{})
+	for _, compConf := range compConfs {
+		err := template.genResource("ALIYUN::ECS::VPC", compConf, compSpec, compConfs)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, Resource{
+		Type: "ALIYUN::ECS::VPC",
+		Properties: map[string]interface{}{
+			"VpcId":       map[string][]string{"Fn::GetAtt": {vpcId, "VpcId"}},
+			"VSwitchName": map[string][]string{"Fn::GetAtt": {vpcId, "VpcName"}},
+		},
+		DependsOn:      []string{vpcId},
+		DeletionPolicy: "Retain",
+	}, template.Resources[vSwitchId])
+
+	// logical ids are unique
+	err := template.genResource("ALIYUN::ECS::VPC", compConfs[0], compSpec, compConfs)
+	assert.NotNil(t, err)
+	assert.Equal(t, fmt.Sprintf(
+		"Logical id '%s' of component instance 'my.vpc' is used by another component instance", vpcId), err.Error())
+}
+
 func TestTemplate_genOutputs(t *testing.T) {
 	type args struct {
 		instanceName       string
 		resourceAttributes map[string]interface{}
 	}
 	tests := []struct {
-		name string
-		args args
-		want map[string]Output
+		name    string
+		args    args
+		want    map[string]Output
+		wantErr string
 	}{
 		{
 			name: "TestNormal",
@@ -326,11 +650,35 @@ func TestTemplate_genOutputs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "TestAttributeNotObject",
+			args: args{
+				instanceName:       "Vpc",
+				resourceAttributes: map[string]interface{}{"VpcId": "ID of VPC"},
+			},
+			wantErr: "Attribute 'VpcId' of resource 'Vpc' is not an object",
+		},
+		{
+			name: "TestDescriptionNotString",
+			args: args{
+				instanceName: "Vpc",
+				resourceAttributes: map[string]interface{}{
+					"VpcId": map[string]interface{}{"Description": float64(1)},
+				},
+			},
+			wantErr: "Description of attribute 'VpcId' of resource 'Vpc' is not a string",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			template, _ := NewTemplate(&appconf.Context{DryRun: true}, &appconf.AppConf{})
-			template.genOutputs(tt.args.instanceName, tt.args.resourceAttributes)
+			err := template.genOutputs(tt.args.instanceName, tt.args.resourceAttributes)
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			assert.Nil(t, err)
 			assert.Equal(t, tt.want, template.Outputs)
 		})
 	}
@@ -352,6 +700,18 @@ func Test_getResourceType(t *testing.T) {
 			want:    "ALIYUN::ECS::VPC",
 			wantErr: false,
 		},
+		{
+			name:    "TestTemplateRef",
+			args:    args{workloadType: "ros.aliyun.com/v1alpha1.TemplateRef"},
+			want:    "ALIYUN::ROS::Stack",
+			wantErr: false,
+		},
+		{
+			name:    "TestRawTemplate",
+			args:    args{workloadType: "ros.aliyun.com/v1alpha1.RawTemplate"},
+			want:    "",
+			wantErr: false,
+		},
 		{
 			name:    "TestFormatError",
 			args:    args{workloadType: "invalidformat"},
@@ -0,0 +1,118 @@
+package ros
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateRefKind is kind of workload type ros.aliyun.com/v1alpha1.TemplateRef, whose components refer ROS templates
+// stored by CreateTemplate. Each component is a nested stack of the template, whose parameters are parameter values
+// of the component and outputs are outputs of the template.
+const TemplateRefKind = "TemplateRef"
+
+// properties of nested stacks of template refs, other properties are parameters of templates
+var templateRefProperties = map[string]bool{"TemplateId": true, "TemplateVersion": true, "TimeoutMins": true}
+
+// templateInterface is parameters and outputs of template body in JSON or YAML
+type templateInterface struct {
+	Parameters map[string]struct {
+		Default interface{} `yaml:"Default"`
+	} `yaml:"Parameters"`
+	Outputs map[string]struct {
+		Description string `yaml:"Description"`
+	} `yaml:"Outputs"`
+}
+
+// genTemplateRef moves properties of nested stack resource of template ref, which are not properties of
+// nested stacks, into parameters of the template
+func (t *Template) genTemplateRef(logicalId string) error {
+	resource := t.Resources[logicalId]
+	if templateId, _ := resource.Properties["TemplateId"].(string); templateId == "" {
+		return errors.New(fmt.Sprintf("Component instance '%s' of %s misses required property TemplateId", logicalId, TemplateRefKind))
+	}
+
+	parameters := make(map[string]interface{})
+	if settings, ok := resource.Properties["Parameters"].(map[string]interface{}); ok {
+		for name, value := range settings {
+			parameters[name] = value
+		}
+	}
+	for name, value := range resource.Properties {
+		if templateRefProperties[name] {
+			continue
+		}
+		if name != "Parameters" {
+			parameters[name] = value
+		}
+		delete(resource.Properties, name)
+	}
+	if len(parameters) > 0 {
+		resource.Properties["Parameters"] = parameters
+	}
+	return nil
+}
+
+// genTemplateRefOutputs checks parameters of template ref against the template, and generates outputs of the
+// template in template
+func (t *Template) genTemplateRefOutputs(appContext *appconf.Context, logicalId string) error {
+	resource := t.Resources[logicalId]
+	templateId, _ := resource.Properties["TemplateId"].(string)
+	templateVersion, _ := resource.Properties["TemplateVersion"].(string)
+
+	request := rosapi.CreateGetTemplateRequest()
+	request.AppendUserAgent("Service", config.RosCtrlConf.UserAgent)
+	request.TemplateId = templateId
+	request.TemplateVersion = templateVersion
+	response, err := appContext.RosClient.GetTemplate(request)
+	if err != nil {
+		return err
+	}
+	refTemplate := &templateInterface{}
+	err = yaml.Unmarshal([]byte(response.TemplateBody), refTemplate)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid body of template %s: %s", templateId, err))
+	}
+
+	// check parameters
+	parameters, _ := resource.Properties["Parameters"].(map[string]interface{})
+	for _, name := range sortedNames(parameters) {
+		if _, ok := refTemplate.Parameters[name]; !ok {
+			return errors.New(fmt.Sprintf(
+				"Component instance '%s' passes unknown parameter '%s' of template %s", logicalId, name, templateId))
+		}
+	}
+	required := make(map[string]interface{})
+	for name, parameter := range refTemplate.Parameters {
+		if _, ok := parameters[name]; !ok && parameter.Default == nil {
+			required[name] = nil
+		}
+	}
+	if len(required) > 0 {
+		return errors.New(fmt.Sprintf(
+			"Component instance '%s' misses required parameter '%s' of template %s", logicalId, sortedNames(required)[0], templateId))
+	}
+
+	// outputs of template are attributes of nested stack
+	for name, output := range refTemplate.Outputs {
+		t.Outputs[logicalId+"."+name] = Output{
+			Description: output.Description,
+			Value:       map[string][2]string{"Fn::GetAtt": {logicalId, name}},
+		}
+	}
+	return nil
+}
+
+func sortedNames(values map[string]interface{}) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,150 @@
+package ros
+
+import (
+	"testing"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosclient"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const networkTemplateBody = `
+ROSTemplateFormatVersion: '2015-09-01'
+Parameters:
+  VpcName:
+    Type: String
+  CidrBlock:
+    Type: String
+    Default: 192.168.0.0/16
+Resources:
+  Vpc:
+    Type: ALIYUN::ECS::VPC
+    Properties:
+      VpcName: {Ref: VpcName}
+      CidrBlock: {Ref: CidrBlock}
+Outputs:
+  VpcId:
+    Description: ID of VPC
+    Value: {'Fn::GetAtt': [Vpc, VpcId]}
+`
+
+func TestNewTemplate_TemplateRef(t *testing.T) {
+	tests := []struct {
+		name            string
+		settings        string
+		parameterValues []v1alpha1.ParameterValue
+		dryRun          bool
+		want            *Template
+		wantErr         string
+	}{
+		{
+			name:            "TestNormal",
+			settings:        `{"TemplateId": "fake-template-1", "TemplateVersion": "v1", "Parameters": {"CidrBlock": "10.0.0.0/8"}}`,
+			parameterValues: []v1alpha1.ParameterValue{{Name: "VpcName", Value: "MyVpc"}},
+			want: &Template{
+				ROSTemplateFormatVersion: "2015-09-01",
+				Parameters:               map[string]Parameter{},
+				Resources: map[string]Resource{
+					"Network": {
+						Type: "ALIYUN::ROS::Stack",
+						Properties: map[string]interface{}{
+							"TemplateId":      "fake-template-1",
+							"TemplateVersion": "v1",
+							"Parameters":      map[string]interface{}{"VpcName": "MyVpc", "CidrBlock": "10.0.0.0/8"},
+						},
+						DependsOn:      []string{},
+						DeletionPolicy: "Retain",
+					},
+				},
+				Outputs: map[string]Output{
+					"Network.VpcId": {Description: "ID of VPC", Value: map[string][2]string{"Fn::GetAtt": {"Network", "VpcId"}}},
+				},
+				updateAllowed: map[string]map[string]bool{"Network": {}},
+			},
+		},
+		{
+			name:     "TestDryRun",
+			settings: `{"TemplateId": "fake-template-1"}`,
+			dryRun:   true,
+			want: &Template{
+				ROSTemplateFormatVersion: "2015-09-01",
+				Parameters:               map[string]Parameter{},
+				Resources: map[string]Resource{
+					"Network": {
+						Type:           "ALIYUN::ROS::Stack",
+						Properties:     map[string]interface{}{"TemplateId": "fake-template-1"},
+						DependsOn:      []string{},
+						DeletionPolicy: "Retain",
+					},
+				},
+				Outputs: map[string]Output{},
+			},
+		},
+		{
+			name:     "TestMissingTemplateId",
+			settings: `{"TemplateVersion": "v1"}`,
+			wantErr:  "Component instance 'Network' of TemplateRef misses required property TemplateId",
+		},
+		{
+			name:     "TestMissingRequiredParameter",
+			settings: `{"TemplateId": "fake-template-1"}`,
+			wantErr:  "Component instance 'Network' misses required parameter 'VpcName' of template fake-template-1",
+		},
+		{
+			name:            "TestUnknownParameter",
+			settings:        `{"TemplateId": "fake-template-1"}`,
+			parameterValues: []v1alpha1.ParameterValue{{Name: "VpcName", Value: "MyVpc"}, {Name: "ZoneId", Value: "cn-hangzhou-h"}},
+			wantErr:         "Component instance 'Network' passes unknown parameter 'ZoneId' of template fake-template-1",
+		},
+		{
+			name:     "TestTemplateNotFound",
+			settings: `{"TemplateId": "no-template"}`,
+			wantErr:  "ErrorCode: TemplateNotFound",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := rosclient.NewFake()
+			fake.Templates["fake-template-1"] = &rosclient.FakeTemplate{
+				Id: "fake-template-1", Name: "Network", TemplateBody: networkTemplateBody, Version: 1,
+			}
+			fake.ResourceTypes[NestedStackType] = &rosapi.GetResourceTypeResponse{ResourceType: NestedStackType}
+
+			appConf := &appconf.AppConf{
+				ObjectMeta: v1.ObjectMeta{Namespace: "MyNamespace"},
+				Spec: v1alpha1.ApplicationConfigurationSpec{
+					Components: []v1alpha1.ComponentConfiguration{
+						{InstanceName: "Network", ComponentName: "NetworkComp", ParameterValues: tt.parameterValues},
+					},
+				},
+			}
+			template, err := NewTemplate(
+				&appconf.Context{DryRun: tt.dryRun, RosClient: fake},
+				appConf,
+				WithCompSchematicGetter(func(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
+					return &v1alpha1.ComponentSchematic{
+						Spec: v1alpha1.ComponentSpec{
+							WorkloadType:     "ros.aliyun.com/v1alpha1.TemplateRef",
+							WorkloadSettings: runtime.RawExtension{Raw: []byte(tt.settings)},
+						},
+					}, nil
+				}),
+				WithWorkloadSchemaGetter(func(namespace, kind string) (*schema.JsonSchema, error) {
+					assert.Equal(t, TemplateRefKind, kind)
+					return nil, nil
+				}))
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, template)
+		})
+	}
+}
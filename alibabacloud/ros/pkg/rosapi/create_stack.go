@@ -83,12 +83,15 @@ type CreateStackRequest struct {
 	ClientToken      string                   `position:"Query" name:"ClientToken"`
 	TemplateBody     string                   `position:"Query" name:"TemplateBody"`
 	TemplateURL      string                   `position:"Query" name:"TemplateURL"`
+	TemplateId       string                   `position:"Query" name:"TemplateId"`
+	TemplateVersion  string                   `position:"Query" name:"TemplateVersion"`
 	NotificationURLs *[]string                `position:"Query" name:"NotificationURLs"  type:"Repeated"`
 	StackPolicyBody  string                   `position:"Query" name:"StackPolicyBody"`
 	OrderSource      string                   `position:"Query" name:"OrderSource"`
 	ActivityId       string                   `position:"Query" name:"ActivityId"`
 	StackPolicyURL   string                   `position:"Query" name:"StackPolicyURL"`
 	ChannelId        string                   `position:"Query" name:"ChannelId"`
+	Tags             *[]CreateStackTags       `position:"Query" name:"Tags"  type:"Repeated"`
 }
 
 // CreateStackParameters is a repeated param struct in CreateStackRequest
@@ -97,6 +100,12 @@ type CreateStackParameters struct {
 	ParameterKey   string `name:"ParameterKey"`
 }
 
+// CreateStackTags is a repeated param struct in CreateStackRequest
+type CreateStackTags struct {
+	Value string `name:"Value"`
+	Key   string `name:"Key"`
+}
+
 // CreateStackResponse is the response struct for api CreateStack
 type CreateStackResponse struct {
 	*responses.BaseResponse
@@ -76,7 +76,7 @@ func (client *Client) CreateTemplateWithCallback(request *CreateTemplateRequest,
 // CreateTemplateRequest is the request struct for api CreateTemplate
 type CreateTemplateRequest struct {
 	*requests.RpcRequest
-	TemplateBody string `position:"Query" name:"TemplateBody"`
+	TemplateBody string `position:"Body" name:"TemplateBody"`
 	Description  string `position:"Query" name:"Description"`
 	TemplateURL  string `position:"Query" name:"TemplateURL"`
 	TemplateName string `position:"Query" name:"TemplateName"`
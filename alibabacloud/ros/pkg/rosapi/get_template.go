@@ -76,9 +76,10 @@ func (client *Client) GetTemplateWithCallback(request *GetTemplateRequest, callb
 // GetTemplateRequest is the request struct for api GetTemplate
 type GetTemplateRequest struct {
 	*requests.RpcRequest
-	StackId     string `position:"Query" name:"StackId"`
-	TemplateId  string `position:"Query" name:"TemplateId"`
-	ChangeSetId string `position:"Query" name:"ChangeSetId"`
+	StackId         string `position:"Query" name:"StackId"`
+	TemplateId      string `position:"Query" name:"TemplateId"`
+	TemplateVersion string `position:"Query" name:"TemplateVersion"`
+	ChangeSetId     string `position:"Query" name:"ChangeSetId"`
 }
 
 // GetTemplateResponse is the response struct for api GetTemplate
@@ -85,6 +85,8 @@ type UpdateStackRequest struct {
 	TemplateBody                string                   `position:"Query" name:"TemplateBody"`
 	StackId                     string                   `position:"Query" name:"StackId"`
 	TemplateURL                 string                   `position:"Query" name:"TemplateURL"`
+	TemplateId                  string                   `position:"Query" name:"TemplateId"`
+	TemplateVersion             string                   `position:"Query" name:"TemplateVersion"`
 	StackPolicyBody             string                   `position:"Query" name:"StackPolicyBody"`
 	StackPolicyDuringUpdateURL  string                   `position:"Query" name:"StackPolicyDuringUpdateURL"`
 	UpdateAllowPolicy           string                   `position:"Query" name:"UpdateAllowPolicy"`
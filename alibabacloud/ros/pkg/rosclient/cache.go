@@ -0,0 +1,144 @@
+package rosclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
+)
+
+// CacheKey identifies ROS clients which can be shared
+type CacheKey struct {
+	AliUid   string
+	RegionId string
+	// Namespace of applications, which labels metrics of API calls
+	Namespace string
+	// Source is where credential is read from, e.g. a credential secret
+	Source string
+	// Credential describes how credential is provided, e.g. credential type and role, so that a changed
+	// credential config does not reuse the client of the former one
+	Credential string
+}
+
+// Cache keeps ROS clients by key, so that credential is not read again and HTTP connections are
+// reused by reconciles of applications with the same identity
+type Cache struct {
+	sync.Mutex
+	clients map[CacheKey]*cacheEntry
+	calls   map[CacheKey]*cacheCall
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// cacheEntry is a cached client and the last time it is got
+type cacheEntry struct {
+	client   Interface
+	lastUsed time.Time
+}
+
+// cacheCall is a client being created for a key, which other callers of the key wait for
+type cacheCall struct {
+	done        chan struct{}
+	client      Interface
+	err         error
+	invalidated bool
+}
+
+// CacheOption sets optional properties of cache
+type CacheOption func(c *Cache)
+
+// WithTTL evicts cached clients which are not got for ttl. Clients are kept until invalidated by default.
+func WithTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// NewCache returns an empty cache
+func NewCache(opts ...CacheOption) *Cache {
+	c := &Cache{
+		clients: make(map[CacheKey]*cacheEntry),
+		calls:   make(map[CacheKey]*cacheCall),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the cached client of key, or creates one by newClient and caches it. Clients of
+// different keys are created concurrently, and callers of a key being created wait for it instead
+// of creating another one. Returns an error if client can not be created.
+func (c *Cache) Get(key CacheKey, newClient func() (Interface, error)) (Interface, error) {
+	c.Lock()
+	c.evictExpired()
+	if entry, ok := c.clients[key]; ok {
+		entry.lastUsed = c.now()
+		c.Unlock()
+		metrics.ObserveRosClientCache(true)
+		return entry.client, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.Unlock()
+		metrics.ObserveRosClientCache(true)
+		<-call.done
+		return call.client, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.Unlock()
+	metrics.ObserveRosClientCache(false)
+
+	call.client, call.err = newClient()
+
+	c.Lock()
+	delete(c.calls, key)
+	if call.err == nil && !call.invalidated {
+		c.clients[key] = &cacheEntry{client: call.client, lastUsed: c.now()}
+	}
+	c.Unlock()
+	close(call.done)
+	return call.client, call.err
+}
+
+// evictExpired removes clients which are not got for ttl, which must be called with lock held
+func (c *Cache) evictExpired() {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+	for key, entry := range c.clients {
+		if now.Sub(entry.lastUsed) > c.ttl {
+			delete(c.clients, key)
+		}
+	}
+}
+
+// Invalidate removes cached clients whose credential is read from source, and returns number of them.
+// Clients of source being created are not cached either.
+func (c *Cache) Invalidate(source string) int {
+	c.Lock()
+	defer c.Unlock()
+
+	count := 0
+	for key := range c.clients {
+		if key.Source == source {
+			delete(c.clients, key)
+			count++
+		}
+	}
+	for key, call := range c.calls {
+		if key.Source == source {
+			call.invalidated = true
+		}
+	}
+	return count
+}
+
+// Len returns number of cached clients
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.clients)
+}
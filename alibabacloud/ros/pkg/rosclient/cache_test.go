@@ -0,0 +1,136 @@
+package rosclient
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	created := 0
+	newClient := func() (Interface, error) {
+		created++
+		return NewFake(), nil
+	}
+	hits := testutil.ToFloat64(metrics.RosClientCacheRequests.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(metrics.RosClientCacheRequests.WithLabelValues("miss"))
+
+	key := CacheKey{AliUid: "123", RegionId: "cn-hangzhou", Namespace: "default", Source: "secret/app"}
+	client, err := cache.Get(key, newClient)
+	assert.Nil(t, err)
+	client2, err := cache.Get(key, newClient)
+	assert.Nil(t, err)
+	assert.True(t, client == client2)
+	assert.Equal(t, 1, created)
+
+	// another region
+	other := CacheKey{AliUid: "123", RegionId: "cn-beijing", Namespace: "default", Source: "secret/app"}
+	_, err = cache.Get(other, newClient)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 2, cache.Len())
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.RosClientCacheRequests.WithLabelValues("hit")))
+	assert.Equal(t, misses+2, testutil.ToFloat64(metrics.RosClientCacheRequests.WithLabelValues("miss")))
+
+	// invalidate clients of source
+	assert.Equal(t, 0, cache.Invalidate("secret/other"))
+	assert.Equal(t, 2, cache.Invalidate("secret/app"))
+	client3, err := cache.Get(key, newClient)
+	assert.Nil(t, err)
+	assert.False(t, client == client3)
+	assert.Equal(t, 3, created)
+
+	// error is not cached
+	_, err = cache.Get(other, func() (Interface, error) {
+		return nil, errors.New("secret not found")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	cache := NewCache()
+	key := CacheKey{AliUid: "123", RegionId: "cn-hangzhou", Source: "secret/app"}
+	slow := CacheKey{AliUid: "456", RegionId: "cn-hangzhou", Source: "secret/slow"}
+
+	// client of slow key is being created
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slowDone := make(chan Interface, 2)
+	var created int32
+	newSlowClient := func() (Interface, error) {
+		atomic.AddInt32(&created, 1)
+		close(started)
+		<-release
+		return NewFake(), nil
+	}
+	go func() {
+		client, _ := cache.Get(slow, newSlowClient)
+		slowDone <- client
+	}()
+	<-started
+
+	// other keys are not blocked
+	_, err := cache.Get(key, func() (Interface, error) { return NewFake(), nil })
+	assert.Nil(t, err)
+
+	// callers of slow key wait for the client being created
+	go func() {
+		client, _ := cache.Get(slow, newSlowClient)
+		slowDone <- client
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	client, client2 := <-slowDone, <-slowDone
+	assert.True(t, client == client2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&created))
+}
+
+func TestCache_Invalidate_InFlight(t *testing.T) {
+	cache := NewCache()
+	key := CacheKey{AliUid: "123", RegionId: "cn-hangzhou", Source: "secret/app"}
+
+	// client created with credential read before invalidation is not cached
+	_, err := cache.Get(key, func() (Interface, error) {
+		cache.Invalidate("secret/app")
+		return NewFake(), nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCache_TTL(t *testing.T) {
+	cache := NewCache(WithTTL(time.Hour))
+	now := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	newClient := func() (Interface, error) { return NewFake(), nil }
+
+	key := CacheKey{AliUid: "123", RegionId: "cn-hangzhou", Source: "secret/app"}
+	other := CacheKey{AliUid: "123", RegionId: "cn-beijing", Source: "secret/app"}
+	client, _ := cache.Get(key, newClient)
+	_, _ = cache.Get(other, newClient)
+
+	// used client is kept
+	now = now.Add(40 * time.Minute)
+	client2, _ := cache.Get(key, newClient)
+	assert.True(t, client == client2)
+
+	// idle client is evicted
+	now = now.Add(40 * time.Minute)
+	client3, _ := cache.Get(key, newClient)
+	assert.True(t, client == client3)
+	assert.Equal(t, 1, cache.Len())
+
+	// credential config is part of key
+	ramRole := key
+	ramRole.Credential = `{"credentialType":"ram_role_arn"}`
+	client4, _ := cache.Get(ramRole, newClient)
+	assert.False(t, client == client4)
+}
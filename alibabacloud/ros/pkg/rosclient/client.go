@@ -0,0 +1,430 @@
+package rosclient
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/utils"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/metrics"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"golang.org/x/time/rate"
+)
+
+// Interface has methods of ROS API used by controller. *rosapi.Client implements it.
+type Interface interface {
+	CreateStack(request *rosapi.CreateStackRequest) (response *rosapi.CreateStackResponse, err error)
+	UpdateStack(request *rosapi.UpdateStackRequest) (response *rosapi.UpdateStackResponse, err error)
+	DeleteStack(request *rosapi.DeleteStackRequest) (response *rosapi.DeleteStackResponse, err error)
+	GetStack(request *rosapi.GetStackRequest) (response *rosapi.GetStackResponse, err error)
+	ListStacks(request *rosapi.ListStacksRequest) (response *rosapi.ListStacksResponse, err error)
+	GetResourceType(request *rosapi.GetResourceTypeRequest) (response *rosapi.GetResourceTypeResponse, err error)
+	ListResourceTypes(request *rosapi.ListResourceTypesRequest) (response *rosapi.ListResourceTypesResponse, err error)
+	DescribeRegions(request *rosapi.DescribeRegionsRequest) (response *rosapi.DescribeRegionsResponse, err error)
+	CreateTemplate(request *rosapi.CreateTemplateRequest) (response *rosapi.CreateTemplateResponse, err error)
+	UpdateTemplate(request *rosapi.UpdateTemplateRequest) (response *rosapi.UpdateTemplateResponse, err error)
+	DeleteTemplate(request *rosapi.DeleteTemplateRequest) (response *rosapi.DeleteTemplateResponse, err error)
+	ListTemplates(request *rosapi.ListTemplatesRequest) (response *rosapi.ListTemplatesResponse, err error)
+	GetTemplate(request *rosapi.GetTemplateRequest) (response *rosapi.GetTemplateResponse, err error)
+}
+
+var _ Interface = &rosapi.Client{}
+var _ Interface = &Client{}
+
+// retryable error codes besides server errors with http status 5xx
+var retryableCodes = map[string]bool{
+	"Throttling":               true,
+	"Throttling.User":          true,
+	"Throttling.Api":           true,
+	"ServiceUnavailable":       true,
+	"InternalError":            true,
+	sdkerrors.TimeoutErrorCode: true,
+}
+
+// clientOption defines client option
+type clientOption struct {
+	MaxRetries  int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	RateLimiter *rate.Limiter
+	RegionId    string
+	Namespace   string
+	Refresher   Refresher
+	Expiration  time.Time
+}
+
+// ClientOption has methods to work with client option.
+type ClientOption interface {
+	apply(*clientOption)
+}
+
+// funcOption defines function used for client option
+type funcOption struct {
+	f func(*clientOption)
+}
+
+// apply executes funcOption's func
+func (fdo *funcOption) apply(do *clientOption) {
+	fdo.f(do)
+}
+
+// newFuncOption returns function option
+func newFuncOption(f func(*clientOption)) *funcOption {
+	return &funcOption{
+		f: f,
+	}
+}
+
+// WithMaxRetries sets max retries of retryable errors in client option
+func WithMaxRetries(maxRetries int) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.MaxRetries = maxRetries
+	})
+}
+
+// WithBackoff sets base and max delay of exponential backoff between retries in client option
+func WithBackoff(baseDelay, maxDelay time.Duration) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.BaseDelay = baseDelay
+		o.MaxDelay = maxDelay
+	})
+}
+
+// WithRateLimiter sets rate limiter of API calls in client option
+func WithRateLimiter(rateLimiter *rate.Limiter) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.RateLimiter = rateLimiter
+	})
+}
+
+// WithAccount sets rate limiter shared by clients of the same account in client option
+func WithAccount(account string) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.RateLimiter = AccountRateLimiter(account)
+	})
+}
+
+// WithMetricLabels sets region and namespace labels of API call metrics in client option
+func WithMetricLabels(regionId, namespace string) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.RegionId = regionId
+		o.Namespace = namespace
+	})
+}
+
+// WithRefresher sets refresher of inner client whose credential expires at expiration in client option.
+// Zero expiration means the credential does not expire.
+func WithRefresher(refresher Refresher, expiration time.Time) ClientOption {
+	return newFuncOption(func(o *clientOption) {
+		o.Refresher = refresher
+		o.Expiration = expiration
+	})
+}
+
+// Refresher returns a new ROS API client with refreshed credential and its expiration
+type Refresher func() (client Interface, expiration time.Time, err error)
+
+// credential is refreshed before it expires in refreshMargin
+const refreshMargin = 5 * time.Minute
+
+// Client wraps ROS API client with retries, rate limiting, client tokens and metrics
+type Client struct {
+	sync.RWMutex
+	client      Interface
+	refresher   Refresher
+	expiration  time.Time
+	maxRetries  int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	rateLimiter *rate.Limiter
+	regionId    string
+	namespace   string
+}
+
+// New returns a client wrapping ROS API client
+func New(client Interface, opts ...ClientOption) *Client {
+	o := &clientOption{
+		MaxRetries: config.RosCtrlConf.RosApiMaxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	return &Client{
+		client:      client,
+		refresher:   o.Refresher,
+		expiration:  o.Expiration,
+		maxRetries:  o.MaxRetries,
+		baseDelay:   o.BaseDelay,
+		maxDelay:    o.MaxDelay,
+		rateLimiter: o.RateLimiter,
+		regionId:    o.RegionId,
+		namespace:   o.Namespace,
+	}
+}
+
+// CreateStack creates stack. A client token is generated if absent so that retries are idempotent.
+func (c *Client) CreateStack(request *rosapi.CreateStackRequest) (response *rosapi.CreateStackResponse, err error) {
+	if request.ClientToken == "" {
+		request.ClientToken = utils.GetUUID()
+	}
+	err = c.call("CreateStack", func(client Interface) (e error) {
+		response, e = client.CreateStack(request)
+		return
+	})
+	return
+}
+
+// UpdateStack updates stack. A client token is generated if absent so that retries are idempotent.
+func (c *Client) UpdateStack(request *rosapi.UpdateStackRequest) (response *rosapi.UpdateStackResponse, err error) {
+	if request.ClientToken == "" {
+		request.ClientToken = utils.GetUUID()
+	}
+	err = c.call("UpdateStack", func(client Interface) (e error) {
+		response, e = client.UpdateStack(request)
+		return
+	})
+	return
+}
+
+// DeleteStack deletes stack
+func (c *Client) DeleteStack(request *rosapi.DeleteStackRequest) (response *rosapi.DeleteStackResponse, err error) {
+	err = c.call("DeleteStack", func(client Interface) (e error) {
+		response, e = client.DeleteStack(request)
+		return
+	})
+	return
+}
+
+// GetStack gets stack
+func (c *Client) GetStack(request *rosapi.GetStackRequest) (response *rosapi.GetStackResponse, err error) {
+	err = c.call("GetStack", func(client Interface) (e error) {
+		response, e = client.GetStack(request)
+		return
+	})
+	return
+}
+
+// ListStacks lists stacks
+func (c *Client) ListStacks(request *rosapi.ListStacksRequest) (response *rosapi.ListStacksResponse, err error) {
+	err = c.call("ListStacks", func(client Interface) (e error) {
+		response, e = client.ListStacks(request)
+		return
+	})
+	return
+}
+
+// GetResourceType gets detail of resource type
+func (c *Client) GetResourceType(request *rosapi.GetResourceTypeRequest) (response *rosapi.GetResourceTypeResponse, err error) {
+	err = c.call("GetResourceType", func(client Interface) (e error) {
+		response, e = client.GetResourceType(request)
+		return
+	})
+	return
+}
+
+// ListResourceTypes lists resource types
+func (c *Client) ListResourceTypes(request *rosapi.ListResourceTypesRequest) (response *rosapi.ListResourceTypesResponse, err error) {
+	err = c.call("ListResourceTypes", func(client Interface) (e error) {
+		response, e = client.ListResourceTypes(request)
+		return
+	})
+	return
+}
+
+// DescribeRegions describes regions supported by ROS
+func (c *Client) DescribeRegions(request *rosapi.DescribeRegionsRequest) (response *rosapi.DescribeRegionsResponse, err error) {
+	err = c.call("DescribeRegions", func(client Interface) (e error) {
+		response, e = client.DescribeRegions(request)
+		return
+	})
+	return
+}
+
+// CreateTemplate creates template
+func (c *Client) CreateTemplate(request *rosapi.CreateTemplateRequest) (response *rosapi.CreateTemplateResponse, err error) {
+	err = c.call("CreateTemplate", func(client Interface) (e error) {
+		response, e = client.CreateTemplate(request)
+		return
+	})
+	return
+}
+
+// UpdateTemplate updates template, which creates a new version of it
+func (c *Client) UpdateTemplate(request *rosapi.UpdateTemplateRequest) (response *rosapi.UpdateTemplateResponse, err error) {
+	err = c.call("UpdateTemplate", func(client Interface) (e error) {
+		response, e = client.UpdateTemplate(request)
+		return
+	})
+	return
+}
+
+// DeleteTemplate deletes template
+func (c *Client) DeleteTemplate(request *rosapi.DeleteTemplateRequest) (response *rosapi.DeleteTemplateResponse, err error) {
+	err = c.call("DeleteTemplate", func(client Interface) (e error) {
+		response, e = client.DeleteTemplate(request)
+		return
+	})
+	return
+}
+
+// ListTemplates lists templates
+func (c *Client) ListTemplates(request *rosapi.ListTemplatesRequest) (response *rosapi.ListTemplatesResponse, err error) {
+	err = c.call("ListTemplates", func(client Interface) (e error) {
+		response, e = client.ListTemplates(request)
+		return
+	})
+	return
+}
+
+// GetTemplate gets template body of template, stack or change set
+func (c *Client) GetTemplate(request *rosapi.GetTemplateRequest) (response *rosapi.GetTemplateResponse, err error) {
+	err = c.call("GetTemplate", func(client Interface) (e error) {
+		response, e = client.GetTemplate(request)
+		return
+	})
+	return
+}
+
+// call invokes API with rate limiting, and retries it on retryable errors. Credential of client is
+// refreshed before it expires, or when API call fails due to expired credential.
+func (c *Client) call(action string, f func(client Interface) error) (err error) {
+	for attempt := 0; ; attempt++ {
+		if c.rateLimiter != nil {
+			_ = c.rateLimiter.Wait(context.Background())
+		}
+
+		start := time.Now()
+		err = f(c.getClient(false))
+		metrics.ObserveRosApiCall(action, codeOfCall(err), c.regionId, c.namespace, start)
+		if err == nil || attempt >= c.maxRetries {
+			return
+		}
+		if IsCredentialExpired(err) && c.refresher != nil {
+			logging.Default.Info("Credential expired, refresh ROS client", "Action", action, "Attempt", attempt+1)
+			c.getClient(true)
+			continue
+		}
+		if !IsRetryable(err) {
+			return
+		}
+
+		delay := c.backoff(attempt)
+		logging.Default.Info("Retry ROS API call", "Action", action, "Code", codeOfCall(err), "Attempt", attempt+1, "Delay", delay.String())
+		time.Sleep(delay)
+	}
+}
+
+// getClient returns inner client, which is refreshed first if it is forced or its credential expires soon.
+// The current client is kept if refresh fails.
+func (c *Client) getClient(force bool) Interface {
+	c.RLock()
+	client, expiration := c.client, c.expiration
+	c.RUnlock()
+	if c.refresher == nil || !force && (expiration.IsZero() || time.Until(expiration) > refreshMargin) {
+		return client
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	// refreshed by another call
+	if c.client != client {
+		return c.client
+	}
+	refreshed, expiration, err := c.refresher()
+	if err != nil {
+		logging.Default.Error(err, "Refresh ROS client failed")
+		return c.client
+	}
+	logging.Default.Info("ROS client refreshed", "Expiration", expiration.String())
+	c.client = refreshed
+	c.expiration = expiration
+	return c.client
+}
+
+// backoff returns jittered exponential delay before retry of attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.maxDelay
+	if attempt < 32 && c.baseDelay<<uint(attempt) < c.maxDelay {
+		delay = c.baseDelay << uint(attempt)
+	}
+	if delay <= 1 {
+		return delay
+	}
+	// equal jitter: half of delay is kept and the other half is random
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// CodeOfError returns error code of API error, empty for other errors and nil.
+func CodeOfError(err error) string {
+	if e, ok := err.(sdkerrors.Error); ok {
+		return e.ErrorCode()
+	}
+	return ""
+}
+
+// codeOfCall returns code of result of API call for metrics and logs, "Unknown" for errors without code
+func codeOfCall(err error) string {
+	code := CodeOfError(err)
+	if err != nil && code == "" {
+		return "Unknown"
+	}
+	return code
+}
+
+// IsThrottling returns true if API call is throttled
+func IsThrottling(err error) bool {
+	return strings.HasPrefix(CodeOfError(err), "Throttling")
+}
+
+// IsCredentialExpired returns true if API call fails due to expired STS token
+func IsCredentialExpired(err error) bool {
+	return CodeOfError(err) == "InvalidSecurityToken.Expired"
+}
+
+// IsRetryable returns true if API call fails due to throttling or transient server errors
+func IsRetryable(err error) bool {
+	e, ok := err.(sdkerrors.Error)
+	if !ok {
+		return false
+	}
+	if retryableCodes[e.ErrorCode()] || IsThrottling(err) {
+		return true
+	}
+	_, isServerError := err.(*sdkerrors.ServerError)
+	return isServerError && e.HttpStatus() >= 500
+}
+
+var (
+	accountRateLimiters     = make(map[string]*rate.Limiter)
+	accountRateLimitersLock sync.Mutex
+)
+
+// AccountRateLimiter returns rate limiter shared by API calls of the account, nil if rate limit is disabled
+func AccountRateLimiter(account string) *rate.Limiter {
+	qps := config.RosCtrlConf.RosApiQps
+	if qps <= 0 {
+		return nil
+	}
+
+	accountRateLimitersLock.Lock()
+	defer accountRateLimitersLock.Unlock()
+	rateLimiter, ok := accountRateLimiters[account]
+	if !ok {
+		burst := config.RosCtrlConf.RosApiBurst
+		if burst <= 0 {
+			burst = 1
+		}
+		rateLimiter = rate.NewLimiter(rate.Limit(qps), burst)
+		accountRateLimiters[account] = rateLimiter
+	}
+	return rateLimiter
+}
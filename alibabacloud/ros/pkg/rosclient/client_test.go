@@ -0,0 +1,206 @@
+package rosclient
+
+import (
+	"errors"
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"testing"
+	"time"
+)
+
+func TestClient_Retry(t *testing.T) {
+	throttling := NewFakeServerError(400, "Throttling.User", "Request was denied due to user flow control.")
+	unavailable := NewFakeServerError(503, "ServiceUnavailable", "The request has failed due to a temporary failure of the server.")
+	internal := NewFakeServerError(500, "", "")
+	invalid := NewFakeServerError(400, "InvalidParameter", "invalid")
+
+	tests := []struct {
+		name       string
+		errors     []error
+		maxRetries int
+		wantCalls  int
+		wantErr    error
+	}{
+		{
+			name:       "TestNoError",
+			maxRetries: 3,
+			wantCalls:  1,
+		},
+		{
+			name:       "TestRetryThrottlingAndServerErrors",
+			errors:     []error{throttling, unavailable, internal},
+			maxRetries: 3,
+			wantCalls:  4,
+		},
+		{
+			name:       "TestExceedMaxRetries",
+			errors:     []error{throttling, throttling, throttling},
+			maxRetries: 2,
+			wantCalls:  3,
+			wantErr:    throttling,
+		},
+		{
+			name:       "TestNotRetryable",
+			errors:     []error{invalid},
+			maxRetries: 3,
+			wantCalls:  1,
+			wantErr:    invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake()
+			fake.Stacks["stack-id"] = &FakeStack{Id: "stack-id", Status: "CREATE_COMPLETE"}
+			fake.Errors["GetStack"] = tt.errors
+			client := New(fake, WithMaxRetries(tt.maxRetries), WithBackoff(time.Millisecond, 2*time.Millisecond))
+
+			request := rosapi.CreateGetStackRequest()
+			request.StackId = "stack-id"
+			response, err := client.GetStack(request)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantCalls, len(fake.Calls))
+			if tt.wantErr == nil {
+				assert.Equal(t, "CREATE_COMPLETE", response.Status)
+			}
+		})
+	}
+}
+
+func TestClient_ClientToken(t *testing.T) {
+	fake := NewFake()
+	fake.Errors["CreateStack"] = []error{NewFakeServerError(500, "InternalError", "")}
+	client := New(fake, WithMaxRetries(1), WithBackoff(time.Millisecond, time.Millisecond))
+
+	// generated client token is kept across retries
+	request := rosapi.CreateCreateStackRequest()
+	request.StackName = "MyStack"
+	response, err := client.CreateStack(request)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, request.ClientToken)
+	assert.Equal(t, request.ClientToken, fake.Stacks[response.StackId].ClientToken)
+
+	// the same client token does not create stack again
+	request2 := rosapi.CreateCreateStackRequest()
+	request2.StackName = "MyStack"
+	request2.ClientToken = request.ClientToken
+	response2, err := client.CreateStack(request2)
+	assert.Nil(t, err)
+	assert.Equal(t, response.StackId, response2.StackId)
+	assert.Equal(t, 1, len(fake.Stacks))
+}
+
+func TestClient_RateLimiter(t *testing.T) {
+	fake := NewFake()
+	client := New(fake, WithRateLimiter(rate.NewLimiter(rate.Every(20*time.Millisecond), 1)))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, _ = client.ListResourceTypes(rosapi.CreateListResourceTypesRequest())
+	}
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestClient_backoff(t *testing.T) {
+	client := New(NewFake(), WithBackoff(100*time.Millisecond, time.Second))
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max = max * time.Millisecond
+		delay := client.backoff(attempt)
+		assert.True(t, delay >= max/2 && delay <= max, "attempt %d: %s", attempt, delay)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "TestThrottling", err: NewFakeServerError(400, "Throttling.User", ""), want: true},
+		{name: "TestServiceUnavailable", err: NewFakeServerError(503, "ServiceUnavailable", ""), want: true},
+		{name: "TestServerError", err: NewFakeServerError(502, "", ""), want: true},
+		{name: "TestTimeout", err: sdkerrors.NewClientError(sdkerrors.TimeoutErrorCode, "timeout", nil), want: true},
+		{name: "TestStackNotFound", err: NewFakeServerError(404, "StackNotFound", ""), want: false},
+		{name: "TestOtherError", err: errors.New("other"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestCodeOfError(t *testing.T) {
+	assert.Equal(t, "", CodeOfError(nil))
+	assert.Equal(t, "Throttling.User", CodeOfError(NewFakeServerError(400, "Throttling.User", "")))
+	assert.Equal(t, "", CodeOfError(errors.New("other")))
+	assert.Equal(t, "", codeOfCall(nil))
+	assert.Equal(t, "Throttling.User", codeOfCall(NewFakeServerError(400, "Throttling.User", "")))
+	assert.Equal(t, "Unknown", codeOfCall(errors.New("other")))
+}
+
+func TestClient_Refresh(t *testing.T) {
+	expired := NewFakeServerError(400, "InvalidSecurityToken.Expired", "Specified SecurityToken is expired.")
+
+	tests := []struct {
+		name           string
+		expiration     time.Duration
+		errors         []error
+		refreshErr     error
+		wantRefreshed  bool
+		wantOldCalls   int
+		wantFreshCalls int
+	}{
+		{
+			name:           "TestNotExpiring",
+			expiration:     time.Hour,
+			wantOldCalls:   1,
+			wantFreshCalls: 0,
+		},
+		{
+			name:           "TestRefreshBeforeExpiry",
+			expiration:     time.Minute,
+			wantRefreshed:  true,
+			wantOldCalls:   0,
+			wantFreshCalls: 1,
+		},
+		{
+			name:           "TestRefreshOnExpiredError",
+			expiration:     time.Hour,
+			errors:         []error{expired},
+			wantRefreshed:  true,
+			wantOldCalls:   1,
+			wantFreshCalls: 1,
+		},
+		{
+			name:           "TestKeepClientIfRefreshFails",
+			expiration:     time.Minute,
+			refreshErr:     errors.New("secret not found"),
+			wantOldCalls:   1,
+			wantFreshCalls: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := NewFake()
+			old.Errors["ListResourceTypes"] = tt.errors
+			fresh := NewFake()
+			refreshed := false
+			refresher := func() (Interface, time.Time, error) {
+				if tt.refreshErr != nil {
+					return nil, time.Time{}, tt.refreshErr
+				}
+				refreshed = true
+				return fresh, time.Now().Add(time.Hour), nil
+			}
+			client := New(old, WithMaxRetries(1), WithRefresher(refresher, time.Now().Add(tt.expiration)))
+
+			_, err := client.ListResourceTypes(rosapi.CreateListResourceTypesRequest())
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantRefreshed, refreshed)
+			assert.Equal(t, tt.wantOldCalls, len(old.Calls))
+			assert.Equal(t, tt.wantFreshCalls, len(fresh.Calls))
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package rosclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	sdkerrors "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+)
+
+// FakeStack is a stack kept by Fake
+type FakeStack struct {
+	Id           string
+	Name         string
+	Status       string
+	StatusReason string
+	TemplateBody string
+	// TemplateURL is url of template body which is not read by Fake
+	TemplateURL string
+	Parameters  map[string]string
+	Outputs     []map[string]interface{}
+	ClientToken string
+	// ParentStackId is id of parent stack of nested stack
+	ParentStackId string
+	// Tags are tags of stack by key
+	Tags map[string]string
+}
+
+// FakeTemplate is a template kept by Fake
+type FakeTemplate struct {
+	Id           string
+	Name         string
+	TemplateBody string
+	// Version is increased by every update
+	Version int
+}
+
+// Fake implements Interface in memory for unit tests. Created, updated and deleted stacks
+// are in progress until their Status is changed by test.
+type Fake struct {
+	sync.Mutex
+
+	// Stacks are stacks by id
+	Stacks map[string]*FakeStack
+	// ResourceTypes are details of resource types by type name
+	ResourceTypes map[string]*rosapi.GetResourceTypeResponse
+	// Templates are templates by id
+	Templates map[string]*FakeTemplate
+	// Regions are regions supported by ROS
+	Regions []rosapi.Region
+	// Errors are returned by calls of the action in order before calls succeed
+	Errors map[string][]error
+	// Calls are actions in order of calls
+	Calls []string
+
+	nextId int
+}
+
+// NewFake returns a fake ROS client
+func NewFake() *Fake {
+	return &Fake{
+		Stacks:        make(map[string]*FakeStack),
+		ResourceTypes: make(map[string]*rosapi.GetResourceTypeResponse),
+		Templates:     make(map[string]*FakeTemplate),
+		Errors:        make(map[string][]error),
+	}
+}
+
+// NewFakeServerError returns an error like the one returned by ROS API
+func NewFakeServerError(httpStatus int, code, message string) error {
+	return sdkerrors.NewServerError(httpStatus, fmt.Sprintf(`{"Code": "%s", "Message": "%s"}`, code, message), "")
+}
+
+// CreateStack implements Interface
+func (f *Fake) CreateStack(request *rosapi.CreateStackRequest) (*rosapi.CreateStackResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("CreateStack"); err != nil {
+		return nil, err
+	}
+
+	// the same client token creates stack only once
+	if request.ClientToken != "" {
+		for _, stack := range f.Stacks {
+			if stack.ClientToken == request.ClientToken {
+				return &rosapi.CreateStackResponse{StackId: stack.Id}, nil
+			}
+		}
+	}
+
+	templateBody, err := f.templateBodyOf(request.TemplateBody, request.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+
+	f.nextId++
+	stack := &FakeStack{
+		Id:           fmt.Sprintf("fake-stack-%d", f.nextId),
+		Name:         request.StackName,
+		Status:       "CREATE_IN_PROGRESS",
+		TemplateBody: templateBody,
+		TemplateURL:  request.TemplateURL,
+		Parameters:   make(map[string]string),
+		ClientToken:  request.ClientToken,
+		Tags:         make(map[string]string),
+	}
+	if request.Tags != nil {
+		for _, tag := range *request.Tags {
+			stack.Tags[tag.Key] = tag.Value
+		}
+	}
+	if request.Parameters != nil {
+		for _, parameter := range *request.Parameters {
+			stack.Parameters[parameter.ParameterKey] = parameter.ParameterValue
+		}
+	}
+	f.Stacks[stack.Id] = stack
+	return &rosapi.CreateStackResponse{StackId: stack.Id}, nil
+}
+
+// UpdateStack implements Interface
+func (f *Fake) UpdateStack(request *rosapi.UpdateStackRequest) (*rosapi.UpdateStackResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("UpdateStack"); err != nil {
+		return nil, err
+	}
+
+	stack, err := f.getStack(request.StackId)
+	if err != nil {
+		return nil, err
+	}
+	templateBody, err := f.templateBodyOf(request.TemplateBody, request.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+	if request.ClientToken == "" || stack.ClientToken != request.ClientToken {
+		stack.Status = "UPDATE_IN_PROGRESS"
+		stack.TemplateBody = templateBody
+		stack.TemplateURL = request.TemplateURL
+		stack.ClientToken = request.ClientToken
+		stack.Parameters = make(map[string]string)
+		if request.Parameters != nil {
+			for _, parameter := range *request.Parameters {
+				stack.Parameters[parameter.ParameterKey] = parameter.ParameterValue
+			}
+		}
+	}
+	return &rosapi.UpdateStackResponse{StackId: stack.Id}, nil
+}
+
+// DeleteStack implements Interface
+func (f *Fake) DeleteStack(request *rosapi.DeleteStackRequest) (*rosapi.DeleteStackResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("DeleteStack"); err != nil {
+		return nil, err
+	}
+
+	stack, err := f.getStack(request.StackId)
+	if err != nil {
+		return nil, err
+	}
+	stack.Status = "DELETE_IN_PROGRESS"
+	return &rosapi.DeleteStackResponse{}, nil
+}
+
+// GetStack implements Interface
+func (f *Fake) GetStack(request *rosapi.GetStackRequest) (*rosapi.GetStackResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("GetStack"); err != nil {
+		return nil, err
+	}
+
+	stack, err := f.getStack(request.StackId)
+	if err != nil {
+		return nil, err
+	}
+	return &rosapi.GetStackResponse{
+		StackId:      stack.Id,
+		StackName:    stack.Name,
+		Status:       stack.Status,
+		StatusReason: stack.StatusReason,
+		Outputs:      stack.Outputs,
+	}, nil
+}
+
+// ListStacks implements Interface
+func (f *Fake) ListStacks(request *rosapi.ListStacksRequest) (*rosapi.ListStacksResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("ListStacks"); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	if request.StackName != nil {
+		for _, name := range *request.StackName {
+			names[name] = true
+		}
+	}
+	response := &rosapi.ListStacksResponse{}
+	for _, stack := range f.Stacks {
+		if len(names) > 0 && !names[stack.Name] {
+			continue
+		}
+		if !hasTags(stack.Tags, request.Tag) {
+			continue
+		}
+		// nested stacks are listed only if asked
+		if request.ParentStackId != "" && stack.ParentStackId != request.ParentStackId {
+			continue
+		}
+		if stack.ParentStackId != "" && request.ShowNestedStack != "true" {
+			continue
+		}
+		response.Stacks = append(response.Stacks, rosapi.Stack{
+			StackId:       stack.Id,
+			StackName:     stack.Name,
+			Status:        stack.Status,
+			StatusReason:  stack.StatusReason,
+			ParentStackId: stack.ParentStackId,
+		})
+	}
+	sort.Slice(response.Stacks, func(i, j int) bool {
+		return response.Stacks[i].StackId < response.Stacks[j].StackId
+	})
+	response.TotalCount = len(response.Stacks)
+	start, end := pageOf(response.TotalCount, request.PageNumber, request.PageSize)
+	response.Stacks = response.Stacks[start:end]
+	return response, nil
+}
+
+// GetResourceType implements Interface
+func (f *Fake) GetResourceType(request *rosapi.GetResourceTypeRequest) (*rosapi.GetResourceTypeResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("GetResourceType"); err != nil {
+		return nil, err
+	}
+
+	response, ok := f.ResourceTypes[request.ResourceType]
+	if !ok {
+		return nil, NewFakeServerError(404, "ResourceTypeNotFound", "Resource type not found")
+	}
+	return response, nil
+}
+
+// ListResourceTypes implements Interface
+func (f *Fake) ListResourceTypes(request *rosapi.ListResourceTypesRequest) (*rosapi.ListResourceTypesResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("ListResourceTypes"); err != nil {
+		return nil, err
+	}
+
+	response := &rosapi.ListResourceTypesResponse{}
+	for resourceType := range f.ResourceTypes {
+		response.ResourceTypes = append(response.ResourceTypes, resourceType)
+	}
+	return response, nil
+}
+
+// DescribeRegions implements Interface
+func (f *Fake) DescribeRegions(request *rosapi.DescribeRegionsRequest) (*rosapi.DescribeRegionsResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("DescribeRegions"); err != nil {
+		return nil, err
+	}
+
+	return &rosapi.DescribeRegionsResponse{Regions: f.Regions}, nil
+}
+
+// CreateTemplate implements Interface
+func (f *Fake) CreateTemplate(request *rosapi.CreateTemplateRequest) (*rosapi.CreateTemplateResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("CreateTemplate"); err != nil {
+		return nil, err
+	}
+
+	f.nextId++
+	template := &FakeTemplate{
+		Id:           fmt.Sprintf("fake-template-%d", f.nextId),
+		Name:         request.TemplateName,
+		TemplateBody: request.TemplateBody,
+		Version:      1,
+	}
+	f.Templates[template.Id] = template
+	return &rosapi.CreateTemplateResponse{TemplateId: template.Id}, nil
+}
+
+// UpdateTemplate implements Interface
+func (f *Fake) UpdateTemplate(request *rosapi.UpdateTemplateRequest) (*rosapi.UpdateTemplateResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("UpdateTemplate"); err != nil {
+		return nil, err
+	}
+
+	template, err := f.getTemplate(request.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+	template.TemplateBody = request.TemplateBody
+	template.Version++
+	return &rosapi.UpdateTemplateResponse{TemplateId: template.Id}, nil
+}
+
+// DeleteTemplate implements Interface
+func (f *Fake) DeleteTemplate(request *rosapi.DeleteTemplateRequest) (*rosapi.DeleteTemplateResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("DeleteTemplate"); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.getTemplate(request.TemplateId); err != nil {
+		return nil, err
+	}
+	delete(f.Templates, request.TemplateId)
+	return &rosapi.DeleteTemplateResponse{}, nil
+}
+
+// ListTemplates implements Interface. Like ROS, templates whose names contain the name are listed, and they are
+// paged in order of ids.
+func (f *Fake) ListTemplates(request *rosapi.ListTemplatesRequest) (*rosapi.ListTemplatesResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("ListTemplates"); err != nil {
+		return nil, err
+	}
+
+	response := &rosapi.ListTemplatesResponse{}
+	for _, template := range f.Templates {
+		if !strings.Contains(template.Name, request.TemplateName) {
+			continue
+		}
+		response.Templates = append(response.Templates, rosapi.Template{
+			TemplateId:   template.Id,
+			TemplateName: template.Name,
+		})
+	}
+	sort.Slice(response.Templates, func(i, j int) bool {
+		return response.Templates[i].TemplateId < response.Templates[j].TemplateId
+	})
+	response.TotalCount = len(response.Templates)
+	start, end := pageOf(response.TotalCount, request.PageNumber, request.PageSize)
+	response.Templates = response.Templates[start:end]
+	return response, nil
+}
+
+// GetTemplate implements Interface. Template body of the latest version of template is returned.
+func (f *Fake) GetTemplate(request *rosapi.GetTemplateRequest) (*rosapi.GetTemplateResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.called("GetTemplate"); err != nil {
+		return nil, err
+	}
+
+	template, err := f.getTemplate(request.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+	return &rosapi.GetTemplateResponse{TemplateBody: template.TemplateBody}, nil
+}
+
+// pageOf returns range of items in page of page number and page size, all items if page size is not set
+func pageOf(total int, pageNumber, pageSize requests.Integer) (start, end int) {
+	size, err := pageSize.GetValue()
+	if err != nil || size <= 0 {
+		return 0, total
+	}
+	number, err := pageNumber.GetValue()
+	if err != nil || number <= 0 {
+		number = 1
+	}
+	start = (number - 1) * size
+	if start > total {
+		start = total
+	}
+	end = start + size
+	if end > total {
+		end = total
+	}
+	return
+}
+
+// called records call of action and returns the next error of action if there is
+func (f *Fake) called(action string) error {
+	f.Calls = append(f.Calls, action)
+	errs := f.Errors[action]
+	if len(errs) == 0 {
+		return nil
+	}
+	f.Errors[action] = errs[1:]
+	return errs[0]
+}
+
+// getStack returns stack by id, or StackNotFound error
+func (f *Fake) getStack(stackId string) (*FakeStack, error) {
+	stack, ok := f.Stacks[stackId]
+	if !ok {
+		return nil, NewFakeServerError(404, "StackNotFound", fmt.Sprintf("The Stack (%s) could not be found.", stackId))
+	}
+	return stack, nil
+}
+
+// getTemplate returns template by id, or TemplateNotFound error
+func (f *Fake) getTemplate(templateId string) (*FakeTemplate, error) {
+	template, ok := f.Templates[templateId]
+	if !ok {
+		return nil, NewFakeServerError(404, "TemplateNotFound", fmt.Sprintf("The Template (%s) could not be found.", templateId))
+	}
+	return template, nil
+}
+
+// templateBodyOf returns template body of stack, which is the body of template if template id is set
+func (f *Fake) templateBodyOf(templateBody, templateId string) (string, error) {
+	if templateId == "" {
+		return templateBody, nil
+	}
+	template, err := f.getTemplate(templateId)
+	if err != nil {
+		return "", err
+	}
+	return template.TemplateBody, nil
+}
+
+var _ Interface = &Fake{}
+
+// hasTags returns true if tags include every tag of filter
+func hasTags(tags map[string]string, filter *[]rosapi.ListStacksTag) bool {
+	if filter == nil {
+		return true
+	}
+	for _, tag := range *filter {
+		value, ok := tags[tag.Key]
+		if !ok || (tag.Value != "" && value != tag.Value) {
+			return false
+		}
+	}
+	return true
+}
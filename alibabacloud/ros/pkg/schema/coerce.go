@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Coerce takes a string value of property at path, e.g. a parameter value of a component, and converts it to the
+// type of the schema. Strings of arrays and objects are JSON encoded. Values of untyped or string schemas are kept.
+func (s *JsonSchema) Coerce(path string, value string) (interface{}, *ValidationError) {
+	if s == nil {
+		return value, nil
+	}
+
+	coerceError := func(reason string) *ValidationError {
+		return &ValidationError{Path: path, Message: "can not convert '" + value + "' to " + s.Type + ": " + reason}
+	}
+
+	switch s.Type {
+	case "integer":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, coerceError(numError(err))
+		}
+		return i, nil
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, coerceError(numError(err))
+		}
+		return f, nil
+	case "boolean":
+		switch value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, coerceError("expected true or false")
+	case "array", "object":
+		var decoded interface{}
+		err := json.Unmarshal([]byte(value), &decoded)
+		if err != nil {
+			return nil, coerceError("invalid JSON, " + err.Error())
+		}
+		if !isType(s.Type, decoded) {
+			return nil, coerceError("got JSON " + typeOf(decoded))
+		}
+		return decoded, nil
+	}
+	return value, nil
+}
+
+// numError returns reason of strconv error without the function and input
+func numError(err error) string {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonSchema_Coerce(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  *JsonSchema
+		value   string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name:   "TestNilSchema",
+			schema: nil,
+			value:  "1",
+			want:   "1",
+		},
+		{
+			name:   "TestString",
+			schema: &JsonSchema{Type: "string"},
+			value:  "1",
+			want:   "1",
+		},
+		{
+			name:   "TestInteger",
+			schema: &JsonSchema{Type: "integer"},
+			value:  "-20",
+			want:   int64(-20),
+		},
+		{
+			name:    "TestInvalidInteger",
+			schema:  &JsonSchema{Type: "integer"},
+			value:   "1.5",
+			wantErr: "$.Ttl: can not convert '1.5' to integer: invalid syntax",
+		},
+		{
+			name:    "TestIntegerOutOfRange",
+			schema:  &JsonSchema{Type: "integer"},
+			value:   "99999999999999999999",
+			wantErr: "$.Ttl: can not convert '99999999999999999999' to integer: value out of range",
+		},
+		{
+			name:   "TestNumber",
+			schema: &JsonSchema{Type: "number"},
+			value:  "1.5",
+			want:   1.5,
+		},
+		{
+			name:   "TestBoolean",
+			schema: &JsonSchema{Type: "boolean"},
+			value:  "false",
+			want:   false,
+		},
+		{
+			name:    "TestInvalidBoolean",
+			schema:  &JsonSchema{Type: "boolean"},
+			value:   "yes",
+			wantErr: "$.Ttl: can not convert 'yes' to boolean: expected true or false",
+		},
+		{
+			name:   "TestArray",
+			schema: &JsonSchema{Type: "array"},
+			value:  `["a", 1]`,
+			want:   []interface{}{"a", float64(1)},
+		},
+		{
+			name:    "TestArrayOfObject",
+			schema:  &JsonSchema{Type: "array"},
+			value:   `{"a": 1}`,
+			wantErr: `$.Ttl: can not convert '{"a": 1}' to array: got JSON object`,
+		},
+		{
+			name:   "TestObject",
+			schema: &JsonSchema{Type: "object"},
+			value:  `{"a": [true]}`,
+			want:   map[string]interface{}{"a": []interface{}{true}},
+		},
+		{
+			name:    "TestInvalidJSON",
+			schema:  &JsonSchema{Type: "object"},
+			value:   `{a}`,
+			wantErr: "$.Ttl: can not convert '{a}' to object: invalid JSON, invalid character 'a' looking for beginning of object key string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.schema.Coerce("$.Ttl", tt.value)
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	// Draft07 is the JSON Schema version used for workload settings.
+	Draft07 = "http://json-schema.org/draft-07/schema#"
+
+	// anyKey is the key ROS uses in list and map schemas to describe every element.
+	anyKey = "*"
+)
+
+// JsonSchema is the subset of JSON Schema draft-07 used to describe ROS resource properties
+// as OAM workload settings.
+type JsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Properties           map[string]*JsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *JsonSchema            `json:"additionalProperties,omitempty"`
+	Items                *JsonSchema            `json:"items,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	MinProperties        *int                   `json:"minProperties,omitempty"`
+	MaxProperties        *int                   `json:"maxProperties,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+
+	// UpdateAllowed and Immutable carry the ROS update semantics of a property.
+	UpdateAllowed *bool `json:"updateAllowed,omitempty"`
+	Immutable     *bool `json:"immutable,omitempty"`
+
+	// never is true for the boolean schema false, which no value is valid against,
+	// e.g. "additionalProperties": false
+	never bool
+}
+
+// jsonSchema has the fields of JsonSchema without its JSON methods
+type jsonSchema JsonSchema
+
+// UnmarshalJSON implements json.Unmarshaler. Besides objects, it accepts the boolean schemas true and false.
+func (s *JsonSchema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = JsonSchema{}
+		return nil
+	case "false":
+		*s = JsonSchema{never: true}
+		return nil
+	}
+	return json.Unmarshal(data, (*jsonSchema)(s))
+}
+
+// MarshalJSON implements json.Marshaler
+func (s JsonSchema) MarshalJSON() ([]byte, error) {
+	if s.never {
+		return []byte("false"), nil
+	}
+	return json.Marshal(jsonSchema(s))
+}
+
+// ConvertError describes a ROS property spec which can not be converted to JSON Schema.
+type ConvertError struct {
+	// Path is the dot separated path of the property, e.g. "KeyIndices.*.Name".
+	Path   string
+	Reason string
+}
+
+// Error implements error.
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("property '%s': %s", e.Path, e.Reason)
+}
+
+// Parse takes the JSON Schema string of workload settings and returns the schema.
+func Parse(s string) (*JsonSchema, error) {
+	schema := &JsonSchema{}
+	if err := json.Unmarshal([]byte(s), schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// FromRosProperties converts the Properties returned by ROS GetResourceType to a JSON Schema of an object.
+func FromRosProperties(rosProperties map[string]interface{}) (*JsonSchema, error) {
+	schema, err := objectFromRosProperties("", rosProperties)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = Draft07
+	return schema, nil
+}
+
+// objectFromRosProperties converts ROS property specs to an object JSON Schema
+func objectFromRosProperties(path string, rosProperties map[string]interface{}) (*JsonSchema, error) {
+	schema := &JsonSchema{
+		Type:       "object",
+		Properties: make(map[string]*JsonSchema),
+	}
+
+	for name, rawSpec := range rosProperties {
+		propertyPath := joinPath(path, name)
+		spec, ok := rawSpec.(map[string]interface{})
+		if !ok {
+			return nil, &ConvertError{Path: propertyPath, Reason: "spec must be an object"}
+		}
+
+		if required, _ := spec["Required"].(bool); required {
+			schema.Required = append(schema.Required, name)
+		}
+
+		property, err := fromRosProperty(propertyPath, spec)
+		if err != nil {
+			return nil, err
+		}
+		schema.Properties[name] = property
+	}
+	sort.Strings(schema.Required)
+
+	return schema, nil
+}
+
+// fromRosProperty converts a single ROS property spec to JSON Schema
+func fromRosProperty(path string, spec map[string]interface{}) (*JsonSchema, error) {
+	schema := &JsonSchema{
+		Default: spec["Default"],
+	}
+	schema.Description, _ = spec["Description"].(string)
+	if updateAllowed, ok := spec["UpdateAllowed"].(bool); ok {
+		schema.UpdateAllowed = &updateAllowed
+	}
+	if immutable, ok := spec["Immutable"].(bool); ok {
+		schema.Immutable = &immutable
+	}
+
+	rosType, ok := spec["Type"]
+	if !ok {
+		// untyped property accepts any value
+		return schema, nil
+	}
+	typeName, ok := rosType.(string)
+	if !ok {
+		return nil, &ConvertError{Path: path, Reason: fmt.Sprintf("type must be a string, got %v", rosType)}
+	}
+
+	var err error
+	switch typeName {
+	case "string", "number", "integer", "boolean":
+		schema.Type = typeName
+	case "list":
+		schema.Type = "array"
+		schema.Items, err = itemsFromRosSchema(path, spec["Schema"])
+	case "map":
+		schema.Type = "object"
+		err = propertiesFromRosSchema(path, spec["Schema"], schema)
+	default:
+		return nil, &ConvertError{Path: path, Reason: fmt.Sprintf("unsupported type '%s'", typeName)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyRosConstraints(path, spec["Constraints"], schema)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// itemsFromRosSchema converts the Schema of a ROS list to the JSON Schema of its items
+func itemsFromRosSchema(path string, rosSchema interface{}) (*JsonSchema, error) {
+	if rosSchema == nil {
+		return nil, nil
+	}
+	specs, ok := rosSchema.(map[string]interface{})
+	if !ok {
+		return nil, &ConvertError{Path: path, Reason: "schema must be an object"}
+	}
+
+	// {"*": {...}} describes every item
+	if anySpec, ok := specs[anyKey]; ok && len(specs) == 1 {
+		return anyFromRosSchema(joinPath(path, anyKey), anySpec)
+	}
+
+	// otherwise the keys are the properties of every item
+	items := &JsonSchema{Type: "object"}
+	err := propertiesFromRosSchema(joinPath(path, anyKey), specs, items)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// propertiesFromRosSchema sets properties and additional properties of an object JSON Schema by the Schema of a ROS map
+func propertiesFromRosSchema(path string, rosSchema interface{}, schema *JsonSchema) error {
+	if rosSchema == nil {
+		return nil
+	}
+	specs, ok := rosSchema.(map[string]interface{})
+	if !ok {
+		return &ConvertError{Path: path, Reason: "schema must be an object"}
+	}
+
+	properties := make(map[string]interface{})
+	for name, spec := range specs {
+		if name == anyKey {
+			additional, err := anyFromRosSchema(joinPath(path, anyKey), spec)
+			if err != nil {
+				return err
+			}
+			schema.AdditionalProperties = additional
+			continue
+		}
+		properties[name] = spec
+	}
+
+	if len(properties) > 0 {
+		object, err := objectFromRosProperties(path, properties)
+		if err != nil {
+			return err
+		}
+		schema.Properties = object.Properties
+		schema.Required = object.Required
+	}
+	return nil
+}
+
+// anyFromRosSchema converts the "*" spec of a ROS list or map
+func anyFromRosSchema(path string, rawSpec interface{}) (*JsonSchema, error) {
+	spec, ok := rawSpec.(map[string]interface{})
+	if !ok {
+		return nil, &ConvertError{Path: path, Reason: "spec must be an object"}
+	}
+	return fromRosProperty(path, spec)
+}
+
+// applyRosConstraints converts ROS Constraints of a property to JSON Schema validation keywords
+func applyRosConstraints(path string, rawConstraints interface{}, schema *JsonSchema) error {
+	if rawConstraints == nil {
+		return nil
+	}
+	constraints, ok := rawConstraints.([]interface{})
+	if !ok {
+		return &ConvertError{Path: path, Reason: "constraints must be a list"}
+	}
+
+	for _, rawConstraint := range constraints {
+		constraint, ok := rawConstraint.(map[string]interface{})
+		if !ok {
+			return &ConvertError{Path: path, Reason: "constraint must be an object"}
+		}
+
+		if allowedValues, ok := constraint["AllowedValues"]; ok {
+			enum, ok := allowedValues.([]interface{})
+			if !ok {
+				return &ConvertError{Path: path, Reason: "AllowedValues must be a list"}
+			}
+			schema.Enum = enum
+		}
+
+		if allowedPattern, ok := constraint["AllowedPattern"]; ok {
+			pattern, ok := allowedPattern.(string)
+			if !ok {
+				return &ConvertError{Path: path, Reason: "AllowedPattern must be a string"}
+			}
+			schema.Pattern = pattern
+		}
+
+		if rawRange, ok := constraint["Range"]; ok {
+			min, max, err := rosBounds(path, "Range", rawRange)
+			if err != nil {
+				return err
+			}
+			schema.Minimum, schema.Maximum = min, max
+		}
+
+		if rawLength, ok := constraint["Length"]; ok {
+			min, max, err := rosBounds(path, "Length", rawLength)
+			if err != nil {
+				return err
+			}
+			minInt, maxInt := toIntPtr(min), toIntPtr(max)
+			switch schema.Type {
+			case "array":
+				schema.MinItems, schema.MaxItems = minInt, maxInt
+			case "object":
+				schema.MinProperties, schema.MaxProperties = minInt, maxInt
+			default:
+				schema.MinLength, schema.MaxLength = minInt, maxInt
+			}
+		}
+	}
+	return nil
+}
+
+// rosBounds reads {"Min": x, "Max": y} of a ROS Range or Length constraint
+func rosBounds(path, name string, rawBounds interface{}) (min, max *float64, err error) {
+	bounds, ok := rawBounds.(map[string]interface{})
+	if !ok {
+		return nil, nil, &ConvertError{Path: path, Reason: name + " must be an object"}
+	}
+	for key, rawValue := range bounds {
+		value, ok := rawValue.(float64)
+		if !ok {
+			return nil, nil, &ConvertError{Path: path, Reason: fmt.Sprintf("%s.%s must be a number", name, key)}
+		}
+		switch key {
+		case "Min":
+			min = &value
+		case "Max":
+			max = &value
+		}
+	}
+	return
+}
+
+func toIntPtr(f *float64) *int {
+	if f == nil {
+		return nil
+	}
+	i := int(*f)
+	return &i
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,263 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// rosVpcProperties is captured from GetResourceType of ALIYUN::ECS::VPC
+const rosVpcProperties = `{
+  "CidrBlock": {
+    "Required": false, "Type": "string", "UpdateAllowed": false, "Immutable": false,
+    "Description": "The IP address range of the VPC in the CIDR block form."
+  },
+  "EnableIpv6": {
+    "Default": false, "Required": false, "Type": "boolean", "UpdateAllowed": false, "Immutable": false,
+    "Description": "Whether to enable an IPv6 network cidr."
+  },
+  "Ipv6CidrBlock": {
+    "Required": false, "Type": "string", "UpdateAllowed": true, "Immutable": false,
+    "Constraints": [{"Length": {"Min": 1}}],
+    "Description": "IPv6 network cidr of the VPC."
+  },
+  "VpcName": {
+    "Required": false, "Type": "string", "UpdateAllowed": true, "Immutable": false,
+    "Constraints": [{"AllowedPattern": "^[a-zA-Z][a-zA-Z0-9_.-]{1,127}$"}],
+    "Description": "Display name of the vpc instance."
+  }
+}`
+
+const jsonVpcSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "CidrBlock": {
+      "type": "string",
+      "description": "The IP address range of the VPC in the CIDR block form.",
+      "updateAllowed": false,
+      "immutable": false
+    },
+    "EnableIpv6": {
+      "type": "boolean",
+      "description": "Whether to enable an IPv6 network cidr.",
+      "default": false,
+      "updateAllowed": false,
+      "immutable": false
+    },
+    "Ipv6CidrBlock": {
+      "type": "string",
+      "description": "IPv6 network cidr of the VPC.",
+      "minLength": 1,
+      "updateAllowed": true,
+      "immutable": false
+    },
+    "VpcName": {
+      "type": "string",
+      "description": "Display name of the vpc instance.",
+      "pattern": "^[a-zA-Z][a-zA-Z0-9_.-]{1,127}$",
+      "updateAllowed": true,
+      "immutable": false
+    }
+  }
+}`
+
+// rosSlsIndexProperties is captured from GetResourceType of ALIYUN::SLS::Index
+const rosSlsIndexProperties = `{
+  "FullTextIndex": {
+    "Required": true, "Type": "map", "UpdateAllowed": true, "Immutable": false,
+    "Description": "Full-text index config.",
+    "Schema": {
+      "Enable": {"Default": true, "Required": true, "Type": "boolean", "UpdateAllowed": true, "Immutable": false},
+      "Delimiter": {"Required": false, "Type": "string", "UpdateAllowed": true, "Immutable": false}
+    }
+  },
+  "KeyIndices": {
+    "Required": false, "Type": "list", "UpdateAllowed": true, "Immutable": false,
+    "Constraints": [{"Length": {"Max": 100}}],
+    "Schema": {
+      "*": {
+        "Required": false, "Type": "map", "UpdateAllowed": true, "Immutable": false,
+        "Schema": {
+          "Name": {"Required": true, "Type": "string", "UpdateAllowed": true, "Immutable": false},
+          "Type": {
+            "Required": true, "Type": "string", "UpdateAllowed": true, "Immutable": false,
+            "Constraints": [{"AllowedValues": ["text", "long", "double", "json"]}]
+          }
+        }
+      }
+    }
+  },
+  "LogReduce": {
+    "Required": false, "Type": "boolean", "UpdateAllowed": true, "Immutable": false, "Default": false
+  },
+  "Ttl": {
+    "Required": false, "Type": "integer", "UpdateAllowed": true, "Immutable": false,
+    "Constraints": [{"Range": {"Min": 0, "Max": 3650}}]
+  }
+}`
+
+const jsonSlsIndexSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["FullTextIndex"],
+  "properties": {
+    "FullTextIndex": {
+      "type": "object",
+      "description": "Full-text index config.",
+      "required": ["Enable"],
+      "properties": {
+        "Delimiter": {"type": "string", "updateAllowed": true, "immutable": false},
+        "Enable": {"type": "boolean", "default": true, "updateAllowed": true, "immutable": false}
+      },
+      "updateAllowed": true,
+      "immutable": false
+    },
+    "KeyIndices": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["Name", "Type"],
+        "properties": {
+          "Name": {"type": "string", "updateAllowed": true, "immutable": false},
+          "Type": {"type": "string", "enum": ["text", "long", "double", "json"], "updateAllowed": true, "immutable": false}
+        },
+        "updateAllowed": true,
+        "immutable": false
+      },
+      "maxItems": 100,
+      "updateAllowed": true,
+      "immutable": false
+    },
+    "LogReduce": {"type": "boolean", "default": false, "updateAllowed": true, "immutable": false},
+    "Ttl": {"type": "integer", "minimum": 0, "maximum": 3650, "updateAllowed": true, "immutable": false}
+  }
+}`
+
+// rosTagsProperties is captured from GetResourceType of ALIYUN::CS::ManagedKubernetesCluster
+const rosTagsProperties = `{
+  "Tags": {
+    "Required": false, "Type": "map", "UpdateAllowed": false, "Immutable": false,
+    "Schema": {"*": {"Type": "string", "Constraints": [{"Length": {"Min": 0, "Max": 128}}]}}
+  },
+  "Taints": {
+    "Required": false, "Type": "list", "UpdateAllowed": false, "Immutable": false,
+    "Schema": {
+      "Key": {"Required": true, "Type": "string"},
+      "Value": {"Required": false, "Type": "string"}
+    }
+  }
+}`
+
+const jsonTagsSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "Tags": {
+      "type": "object",
+      "additionalProperties": {"type": "string", "minLength": 0, "maxLength": 128},
+      "updateAllowed": false,
+      "immutable": false
+    },
+    "Taints": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["Key"],
+        "properties": {
+          "Key": {"type": "string"},
+          "Value": {"type": "string"}
+        }
+      },
+      "updateAllowed": false,
+      "immutable": false
+    }
+  }
+}`
+
+func TestFromRosProperties(t *testing.T) {
+	tests := []struct {
+		name          string
+		rosProperties string
+		want          string
+	}{
+		{
+			name:          "TestVpc",
+			rosProperties: rosVpcProperties,
+			want:          jsonVpcSchema,
+		},
+		{
+			name:          "TestNestedObjects",
+			rosProperties: rosSlsIndexProperties,
+			want:          jsonSlsIndexSchema,
+		},
+		{
+			name:          "TestTypedMapAndListProperties",
+			rosProperties: rosTagsProperties,
+			want:          jsonTagsSchema,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rosProperties := make(map[string]interface{})
+			assert.Nil(t, json.Unmarshal([]byte(tt.rosProperties), &rosProperties))
+
+			got, err := FromRosProperties(rosProperties)
+			assert.Nil(t, err)
+
+			gotJson, err := json.Marshal(got)
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.want, string(gotJson))
+
+			// round trip
+			parsed, err := Parse(string(gotJson))
+			assert.Nil(t, err)
+			assert.Equal(t, got, parsed)
+		})
+	}
+}
+
+func TestFromRosPropertiesError(t *testing.T) {
+	tests := []struct {
+		name          string
+		rosProperties string
+		want          *ConvertError
+	}{
+		{
+			name:          "TestUnsupportedType",
+			rosProperties: `{"Foo": {"Type": "unknown"}}`,
+			want:          &ConvertError{Path: "Foo", Reason: "unsupported type 'unknown'"},
+		},
+		{
+			name:          "TestNestedUnsupportedType",
+			rosProperties: `{"Foo": {"Type": "list", "Schema": {"*": {"Type": "map", "Schema": {"Bar": {"Type": "unknown"}}}}}}`,
+			want:          &ConvertError{Path: "Foo.*.Bar", Reason: "unsupported type 'unknown'"},
+		},
+		{
+			name:          "TestInvalidConstraint",
+			rosProperties: `{"Foo": {"Type": "integer", "Constraints": [{"Range": {"Min": "0"}}]}}`,
+			want:          &ConvertError{Path: "Foo", Reason: "Range.Min must be a number"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rosProperties := make(map[string]interface{})
+			assert.Nil(t, json.Unmarshal([]byte(tt.rosProperties), &rosProperties))
+
+			got, err := FromRosProperties(rosProperties)
+			assert.Nil(t, got)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestParseBooleanSchema(t *testing.T) {
+	schema, err := Parse(`{"type": "object", "additionalProperties": false, "properties": {"Any": true}}`)
+	assert.Nil(t, err)
+	assert.True(t, schema.AdditionalProperties.never)
+	assert.Equal(t, &JsonSchema{}, schema.Properties["Any"])
+
+	marshalled, err := json.Marshal(schema)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"type": "object", "additionalProperties": false, "properties": {"Any": {}}}`, string(marshalled))
+}
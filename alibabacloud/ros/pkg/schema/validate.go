@@ -0,0 +1,205 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// RootPath is the JSON path of the validated value itself.
+const RootPath = "$"
+
+// ValidationError describes a value which violates the schema.
+type ValidationError struct {
+	// Path is the JSON path of the value, e.g. "$.KeyIndices[0].Name".
+	Path    string
+	Message string
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate takes a value decoded from JSON and returns every violation against the schema.
+func (s *JsonSchema) Validate(value interface{}) []*ValidationError {
+	var errs []*ValidationError
+	s.validate(RootPath, value, &errs)
+	return errs
+}
+
+// ValidatePartial is like Validate but allows required properties of the value itself to be missing,
+// e.g. for workload settings which are completed by parameter values.
+func (s *JsonSchema) ValidatePartial(value interface{}) []*ValidationError {
+	partial := *s
+	partial.Required = nil
+	return partial.Validate(value)
+}
+
+func (s *JsonSchema) validate(path string, value interface{}, errs *[]*ValidationError) {
+	if s == nil || value == nil {
+		return
+	}
+
+	// intrinsic functions are resolved by ROS, so they can not be checked here
+	if IsIntrinsicFunction(value) {
+		return
+	}
+
+	addError := func(format string, a ...interface{}) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if s.never {
+		addError("value is not allowed")
+		return
+	}
+
+	if s.Type != "" && !isType(s.Type, value) {
+		addError("expected %s, got %s", s.Type, typeOf(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		addError("value %v is not one of %v", value, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			addError("length %d is less than %d", length, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			addError("length %d is greater than %d", length, *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			// ROS patterns which are not valid RE2 are left to ROS
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(v) {
+				addError("value '%s' does not match pattern '%s'", v, s.Pattern)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			addError("value %v is less than %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			addError("value %v is greater than %v", v, *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			addError("%d items are less than %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			addError("%d items are more than %d", len(v), *s.MaxItems)
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case map[string]interface{}:
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			addError("%d properties are less than %d", len(v), *s.MinProperties)
+		}
+		if s.MaxProperties != nil && len(v) > *s.MaxProperties {
+			addError("%d properties are more than %d", len(v), *s.MaxProperties)
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, &ValidationError{Path: path + "." + name, Message: "required property is missing"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok {
+				property = s.AdditionalProperties
+				if property != nil && property.never {
+					*errs = append(*errs, &ValidationError{Path: path + "." + name, Message: "unknown property is not allowed"})
+					continue
+				}
+			}
+			property.validate(path+"."+name, v[name], errs)
+		}
+	}
+}
+
+// IsIntrinsicFunction returns true if value is a ROS intrinsic function such as {"Ref": ...} or {"Fn::GetAtt": ...}.
+func IsIntrinsicFunction(value interface{}) bool {
+	var key string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return false
+		}
+		for k := range v {
+			key = k
+		}
+	case map[string][]string:
+		if len(v) != 1 {
+			return false
+		}
+		for k := range v {
+			key = k
+		}
+	default:
+		return false
+	}
+	return key == "Ref" || strings.HasPrefix(key, "Fn::")
+}
+
+func isType(type_ string, value interface{}) bool {
+	switch type_ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+	}
+	return false
+}
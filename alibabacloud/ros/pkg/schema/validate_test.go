@@ -0,0 +1,93 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestJsonSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []*ValidationError
+	}{
+		{
+			name:   "TestValid",
+			schema: jsonSlsIndexSchema,
+			value:  `{"FullTextIndex": {"Enable": true}, "KeyIndices": [{"Name": "key", "Type": "text"}], "Ttl": 0}`,
+			want:   nil,
+		},
+		{
+			name:   "TestRequired",
+			schema: jsonSlsIndexSchema,
+			value:  `{"KeyIndices": [{"Type": "text"}]}`,
+			want: []*ValidationError{
+				{Path: "$.FullTextIndex", Message: "required property is missing"},
+				{Path: "$.KeyIndices[0].Name", Message: "required property is missing"},
+			},
+		},
+		{
+			name:   "TestTypesAndEnum",
+			schema: jsonSlsIndexSchema,
+			value:  `{"FullTextIndex": {"Enable": "yes"}, "KeyIndices": [{"Name": 1, "Type": "bool"}], "Ttl": 1.5}`,
+			want: []*ValidationError{
+				{Path: "$.FullTextIndex.Enable", Message: "expected boolean, got string"},
+				{Path: "$.KeyIndices[0].Name", Message: "expected string, got number"},
+				{Path: "$.KeyIndices[0].Type", Message: "value bool is not one of [text long double json]"},
+				{Path: "$.Ttl", Message: "expected integer, got number"},
+			},
+		},
+		{
+			name:   "TestRangeAndLength",
+			schema: jsonTagsSchema,
+			value:  `{"Tags": {"a": "` + strings.Repeat("a", 129) + `"}}`,
+			want: []*ValidationError{
+				{Path: "$.Tags.a", Message: "length 129 is greater than 128"},
+			},
+		},
+		{
+			name:   "TestPattern",
+			schema: jsonVpcSchema,
+			value:  `{"VpcName": "1vpc", "Ipv6CidrBlock": ""}`,
+			want: []*ValidationError{
+				{Path: "$.Ipv6CidrBlock", Message: "length 0 is less than 1"},
+				{Path: "$.VpcName", Message: "value '1vpc' does not match pattern '^[a-zA-Z][a-zA-Z0-9_.-]{1,127}$'"},
+			},
+		},
+		{
+			name:   "TestIntrinsicFunction",
+			schema: jsonSlsIndexSchema,
+			value:  `{"FullTextIndex": {"Fn::GetAtt": ["Index", "FullTextIndex"]}, "Ttl": {"Ref": "Ttl"}}`,
+			want:   nil,
+		},
+		{
+			name:   "TestNoAdditionalProperties",
+			schema: `{"type": "object", "additionalProperties": false, "properties": {"Resources": {"type": "object"}}}`,
+			value:  `{"Resources": {}, "Parameters": {}}`,
+			want: []*ValidationError{
+				{Path: "$.Parameters", Message: "unknown property is not allowed"},
+			},
+		},
+		{
+			name:   "TestBooleanSchemas",
+			schema: `{"type": "object", "additionalProperties": true, "properties": {"Never": false, "Any": true}}`,
+			value:  `{"Never": 1, "Any": 1, "Other": "a"}`,
+			want: []*ValidationError{
+				{Path: "$.Never", Message: "value is not allowed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Parse(tt.schema)
+			assert.Nil(t, err)
+
+			var value interface{}
+			assert.Nil(t, json.Unmarshal([]byte(tt.value), &value))
+			assert.Equal(t, tt.want, schema.Validate(value))
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	rosv1alpha1 "github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/aliyun"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/appconf"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/component"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/policy"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/ros"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/workload"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path where the validating webhook is served.
+const ValidatePath = "/validate-ros"
+
+// validatorOption defines validator option
+type validatorOption struct {
+	CompSchematicGetter  func(namespace, name string) (*v1alpha1.ComponentSchematic, error)
+	WorkloadSchemaGetter func(namespace, kind string) (*schema.JsonSchema, error)
+	AccountChecker       func(namespace, aliUid, regionId string) error
+}
+
+// ValidatorOption has methods to work with validator option.
+type ValidatorOption interface {
+	apply(*validatorOption)
+}
+
+// funcOption defines function used for validator option
+type funcOption struct {
+	f func(*validatorOption)
+}
+
+// apply executes funcOption's func
+func (fdo *funcOption) apply(do *validatorOption) {
+	fdo.f(do)
+}
+
+// newFuncOption returns function option
+func newFuncOption(f func(*validatorOption)) *funcOption {
+	return &funcOption{
+		f: f,
+	}
+}
+
+// WithCompSchematicGetter sets component getter in validator option
+func WithCompSchematicGetter(compSchematicGetter func(namespace, name string) (*v1alpha1.ComponentSchematic, error)) ValidatorOption {
+	return newFuncOption(func(o *validatorOption) {
+		o.CompSchematicGetter = compSchematicGetter
+	})
+}
+
+// WithWorkloadSchemaGetter sets getter of workload settings JSON Schema in validator option
+func WithWorkloadSchemaGetter(workloadSchemaGetter func(namespace, kind string) (*schema.JsonSchema, error)) ValidatorOption {
+	return newFuncOption(func(o *validatorOption) {
+		o.WorkloadSchemaGetter = workloadSchemaGetter
+	})
+}
+
+// WithAccountChecker sets checker of accounts and regions allowed for namespaces in validator option
+func WithAccountChecker(accountChecker func(namespace, aliUid, regionId string) error) ValidatorOption {
+	return newFuncOption(func(o *validatorOption) {
+		o.AccountChecker = accountChecker
+	})
+}
+
+// Validator validates ApplicationConfiguration, RosStack and ComponentSchematic on create and update
+// with the same rendering and validation logic used by the handler.
+type Validator struct {
+	decoder              *admission.Decoder
+	compSchematicGetter  func(namespace, name string) (*v1alpha1.ComponentSchematic, error)
+	workloadSchemaGetter func(namespace, kind string) (*schema.JsonSchema, error)
+	accountChecker       func(namespace, aliUid, regionId string) error
+}
+
+// NewValidator returns a validator
+func NewValidator(opts ...ValidatorOption) *Validator {
+	o := &validatorOption{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+
+	if o.CompSchematicGetter == nil {
+		o.CompSchematicGetter = component.Get
+	}
+
+	if o.WorkloadSchemaGetter == nil {
+		o.WorkloadSchemaGetter = workload.GetSettingsSchema
+	}
+
+	if o.AccountChecker == nil {
+		o.AccountChecker = policy.CheckAccount
+	}
+
+	return &Validator{
+		compSchematicGetter:  o.CompSchematicGetter,
+		workloadSchemaGetter: o.WorkloadSchemaGetter,
+		accountChecker:       o.AccountChecker,
+	}
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *Validator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// Handle implements admission.Handler
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	var err error
+	switch req.Kind.Kind {
+	case "ApplicationConfiguration":
+		obj := &v1alpha1.ApplicationConfiguration{}
+		if err = v.decoder.Decode(req, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.validateAppConf(obj)
+	case "RosStack":
+		obj := &rosv1alpha1.RosStack{}
+		if err = v.decoder.Decode(req, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.validateAppConf(obj)
+	case "ComponentSchematic":
+		obj := &v1alpha1.ComponentSchematic{}
+		if err = v.decoder.Decode(req, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.validateCompSchematic(obj)
+	default:
+		return admission.Allowed("")
+	}
+
+	if err != nil {
+		logging.Default.Info("Deny invalid object", "Kind", req.Kind.Kind, "Name", req.Name, "Reason", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// validateAppConf validates scopes, account policies and renders ROS template of ApplicationConfiguration or RosStack
+// for each of its targets
+func (v *Validator) validateAppConf(obj interface{}) error {
+	appConf, _ := appconf.NewAppConf(obj)
+
+	resourceIdentity, err := appconf.GetResourceIdentity(appConf)
+	if err != nil {
+		return err
+	}
+
+	identities := []*aliyun.AliyunResourceIdentity{resourceIdentity}
+	var parameters []map[string]map[string]interface{}
+	if resourceIdentity != nil && len(resourceIdentity.Targets) > 0 {
+		identities = nil
+		for _, target := range resourceIdentity.Targets {
+			identities = append(identities, resourceIdentity.ForTarget(target))
+			parameters = append(parameters, target.Parameters)
+		}
+	}
+
+	for i, identity := range identities {
+		appContext := &appconf.Context{
+			AppName:  appConf.GetName(),
+			AppConf:  appConf,
+			DryRun:   true,
+			RegionId: config.RosCtrlConf.RegionId,
+		}
+		// applications without resource identity use account of controller credential
+		aliUid := config.RosCtrlConf.ControllerAliUid
+		if identity != nil {
+			appContext.AliUid = identity.AliUid
+			aliUid = identity.AliUid
+			if identity.RegionId != "" {
+				appContext.RegionId = identity.RegionId
+			}
+		}
+		err = v.accountChecker(appConf.GetNamespace(), aliUid, appContext.RegionId)
+		if err != nil {
+			return err
+		}
+		if parameters != nil {
+			appContext.Parameters = parameters[i]
+		}
+
+		_, err = ros.NewTemplate(
+			appContext,
+			appConf,
+			ros.WithCompSchematicGetter(v.compSchematicGetter),
+			ros.WithWorkloadSchemaGetter(v.workloadSchemaGetter),
+			// outputs are not validated
+			ros.WithWorkloadAttributesGetter(func(namespace, kind string) (map[string]interface{}, error) {
+				return nil, nil
+			}),
+			ros.WithStrictWorkloadType(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCompSchematic validates workload type and workload settings of ComponentSchematic
+func (v *Validator) validateCompSchematic(compSchematic *v1alpha1.ComponentSchematic) error {
+	workloadType := compSchematic.Spec.WorkloadType
+	if !strings.HasPrefix(workloadType, config.ROS_GROUP+"/") {
+		// not handled by this controller
+		return nil
+	}
+	err := ros.ValidateWorkloadType(workloadType)
+	if err != nil {
+		return err
+	}
+
+	settingsSchema, err := v.workloadSchemaGetter(compSchematic.Namespace, workload.KindOf(workloadType))
+	if err != nil || settingsSchema == nil {
+		return err
+	}
+
+	var settings interface{}
+	if len(compSchematic.Spec.WorkloadSettings.Raw) > 0 {
+		err = json.Unmarshal(compSchematic.Spec.WorkloadSettings.Raw, &settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	// required properties may be supplied by parameter values of application configuration
+	violations := settingsSchema.ValidatePartial(settings)
+	if len(violations) > 0 {
+		messages := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			messages = append(messages, violation.Error())
+		}
+		return fmt.Errorf("Invalid workload settings: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	rosv1alpha1 "github.com/oam-dev/cloud-provider/alibabacloud/ros/apis/ros.alibabacloud.com/v1alpha1"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/config"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/logging"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/policy"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/region"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/rosapi"
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/oam-go-sdk/apis/core.oam.dev/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"net/http"
+	"net/http/httptest"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"testing"
+)
+
+const vpcSettingsSchema = `{
+	"type": "object",
+	"required": ["CidrBlock"],
+	"properties": {
+		"CidrBlock": {"type": "string"},
+		"VpcName": {"type": "string"}
+	}
+}`
+
+var compSchematics = map[string]*v1alpha1.ComponentSchematic{
+	"vpc": {
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VPC",
+			WorkloadSettings: runtime.RawExtension{Raw: []byte(`{"CidrBlock": "192.168.0.0/16"}`)},
+		},
+	},
+	"vswitch": {
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VSwitch",
+			WorkloadSettings: runtime.RawExtension{Raw: []byte(`{"CidrBlock": "192.168.0.0/24"}`)},
+		},
+	},
+	"server": {
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: "core.oam.dev/v1alpha1.Server",
+		},
+	},
+	"invalid": {
+		Spec: v1alpha1.ComponentSpec{
+			WorkloadType: "ros.aliyun.com/v1alpha1.VPC",
+		},
+	},
+}
+
+// newTestWebhookServer starts a local webhook server serving the validator like the API server calls it
+func newTestWebhookServer(t *testing.T) *httptest.Server {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, v1alpha1.AddToScheme(scheme))
+	assert.Nil(t, rosv1alpha1.AddToScheme(scheme))
+
+	validator := NewValidator(
+		WithCompSchematicGetter(func(namespace, name string) (*v1alpha1.ComponentSchematic, error) {
+			compSchematic, ok := compSchematics[name]
+			if !ok {
+				return nil, errors.New("componentschematics.core.oam.dev \"" + name + "\" not found")
+			}
+			return compSchematic, nil
+		}),
+		WithWorkloadSchemaGetter(func(namespace, kind string) (*schema.JsonSchema, error) {
+			if kind != "ECS_VPC" {
+				return nil, nil
+			}
+			return schema.Parse(vpcSettingsSchema)
+		}),
+		WithAccountChecker(func(namespace, aliUid, regionId string) error {
+			if aliUid == "999" {
+				return &policy.AccountDeniedError{Namespace: namespace, AliUid: aliUid, RegionId: regionId}
+			}
+			return nil
+		}),
+	)
+	admission := &ctrlwebhook.Admission{Handler: validator}
+	assert.Nil(t, admission.InjectLogger(logging.Default))
+	assert.Nil(t, admission.InjectScheme(scheme))
+
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, admission)
+	return httptest.NewServer(mux)
+}
+
+// review posts an admission review of obj to the webhook server and returns the response
+func review(t *testing.T, server *httptest.Server, operation admissionv1beta1.Operation, obj runtime.Object) *admissionv1beta1.AdmissionResponse {
+	raw, err := json.Marshal(obj)
+	assert.Nil(t, err)
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	admissionReview := admissionv1beta1.AdmissionReview{
+		TypeMeta: v1.TypeMeta{APIVersion: "admission.k8s.io/v1beta1", Kind: "AdmissionReview"},
+		Request: &admissionv1beta1.AdmissionRequest{
+			UID:       "uid",
+			Kind:      v1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+	body, err := json.Marshal(admissionReview)
+	assert.Nil(t, err)
+
+	resp, err := http.Post(server.URL+ValidatePath, "application/json", bytes.NewReader(body))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	result := &admissionv1beta1.AdmissionReview{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(result))
+	return result.Response
+}
+
+func newAppConf(scopes []v1alpha1.ScopeBinding, components ...v1alpha1.ComponentConfiguration) *v1alpha1.ApplicationConfiguration {
+	return &v1alpha1.ApplicationConfiguration{
+		TypeMeta:   v1.TypeMeta{APIVersion: "core.oam.dev/v1alpha1", Kind: "ApplicationConfiguration"},
+		ObjectMeta: v1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha1.ApplicationConfigurationSpec{
+			Scopes:     scopes,
+			Components: components,
+		},
+	}
+}
+
+func TestValidator_Handle(t *testing.T) {
+	server := newTestWebhookServer(t)
+	defer server.Close()
+
+	vpc := v1alpha1.ComponentConfiguration{ComponentName: "vpc", InstanceName: "Vpc"}
+	vswitch := v1alpha1.ComponentConfiguration{
+		ComponentName: "vswitch",
+		InstanceName:  "VSwitch",
+		ParameterValues: []v1alpha1.ParameterValue{
+			{Name: "VpcId", From: &v1alpha1.ParameterFrom{Component: "Vpc", FieldPath: ".status.VpcId"}},
+		},
+	}
+	invalidRef := v1alpha1.ComponentConfiguration{
+		ComponentName: "vswitch",
+		InstanceName:  "VSwitch",
+		ParameterValues: []v1alpha1.ParameterValue{
+			{Name: "VpcId", From: &v1alpha1.ParameterFrom{Component: "NoVpc", FieldPath: ".status.VpcId"}},
+		},
+	}
+	resourceIdentity := func(properties string) []v1alpha1.ScopeBinding {
+		return []v1alpha1.ScopeBinding{{
+			Name:       "resource-identity",
+			Type:       "oam.alibaba.dev/v1.ResourceIdentity",
+			Properties: runtime.RawExtension{Raw: []byte(properties)},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		operation   admissionv1beta1.Operation
+		obj         runtime.Object
+		wantAllowed bool
+		wantMessage string
+	}{
+		{
+			name:        "TestValidAppConf",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(nil, vpc, vswitch),
+			wantAllowed: true,
+		},
+		{
+			name:        "TestInvalidReference",
+			operation:   admissionv1beta1.Update,
+			obj:         newAppConf(nil, vpc, invalidRef),
+			wantAllowed: false,
+			wantMessage: "Invalid reference 'NoVpc' which refers a no exist component instance",
+		},
+		{
+			name:        "TestOtherWorkloadGroup",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(nil, vpc, v1alpha1.ComponentConfiguration{ComponentName: "server", InstanceName: "Server"}),
+			wantAllowed: true,
+		},
+		{
+			name:        "TestUnsupportedWorkloadType",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(nil, vpc, v1alpha1.ComponentConfiguration{ComponentName: "invalid", InstanceName: "Invalid"}),
+			wantAllowed: false,
+			wantMessage: "component instance 'Invalid': Type 'VPC' in workloadType must be format of {product}_{restype}",
+		},
+		{
+			name:        "TestMissingComponentSchematic",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(nil, v1alpha1.ComponentConfiguration{ComponentName: "nocomp", InstanceName: "NoComp"}),
+			wantAllowed: false,
+			wantMessage: "componentschematics.core.oam.dev \"nocomp\" not found",
+		},
+		{
+			name:        "TestMissingScopeFields",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(resourceIdentity(`{"regionId": "cn-beijing"}`), vpc),
+			wantAllowed: false,
+			wantMessage: "resource identity scope misses required properties: appName, aliyunAccountUid",
+		},
+		{
+			name:        "TestValidScope",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "123"}`), vpc),
+			wantAllowed: true,
+		},
+		{
+			name:        "TestAccountDenied",
+			operation:   admissionv1beta1.Create,
+			obj:         newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "999", "regionId": "cn-beijing"}`), vpc),
+			wantAllowed: false,
+			wantMessage: "Account 999 in region cn-beijing is not allowed for namespace default by account policies",
+		},
+		{
+			name:      "TestValidTargets",
+			operation: admissionv1beta1.Create,
+			obj: newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "123", "targets": [
+				{"regionId": "cn-hangzhou"},
+				{"regionId": "cn-beijing", "aliyunAccountUid": "456", "parameters": {"Vpc": {"CidrBlock": "10.0.0.0/8"}}}]}`), vpc),
+			wantAllowed: true,
+		},
+		{
+			name:      "TestTargetAccountDenied",
+			operation: admissionv1beta1.Create,
+			obj: newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "123", "targets": [
+				{"regionId": "cn-hangzhou"}, {"regionId": "cn-beijing", "aliyunAccountUid": "999"}]}`), vpc),
+			wantAllowed: false,
+			wantMessage: "Account 999 in region cn-beijing is not allowed for namespace default by account policies",
+		},
+		{
+			name:      "TestDuplicatedTargets",
+			operation: admissionv1beta1.Create,
+			obj: newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "123", "targets": [
+				{"regionId": "cn-beijing"}, {"regionId": "cn-beijing", "aliyunAccountUid": "123"}]}`), vpc),
+			wantAllowed: false,
+			wantMessage: "resource identity scope has duplicated targets of region cn-beijing and account 123",
+		},
+		{
+			name:      "TestTargetParametersOfUnknownInstance",
+			operation: admissionv1beta1.Create,
+			obj: newAppConf(resourceIdentity(`{"appName": "myapp", "aliyunAccountUid": "123", "targets": [
+				{"regionId": "cn-beijing", "parameters": {"NoVpc": {"CidrBlock": "10.0.0.0/8"}}}]}`), vpc),
+			wantAllowed: false,
+			wantMessage: "parameters of target in region cn-beijing override unknown component instance 'NoVpc'",
+		},
+		{
+			name:      "TestValidRosStack",
+			operation: admissionv1beta1.Create,
+			obj: &rosv1alpha1.RosStack{
+				TypeMeta:   v1.TypeMeta{APIVersion: "ros.alibabacloud.com/v1alpha1", Kind: "RosStack"},
+				ObjectMeta: v1.ObjectMeta{Name: "mystack", Namespace: "default"},
+				Spec:       v1alpha1.ApplicationConfigurationSpec{Components: []v1alpha1.ComponentConfiguration{vpc}},
+			},
+			wantAllowed: true,
+		},
+		{
+			name:      "TestInvalidWorkloadSettings",
+			operation: admissionv1beta1.Create,
+			obj: &v1alpha1.ComponentSchematic{
+				TypeMeta:   v1.TypeMeta{APIVersion: "core.oam.dev/v1alpha1", Kind: "ComponentSchematic"},
+				ObjectMeta: v1.ObjectMeta{Name: "vpc", Namespace: "default"},
+				Spec: v1alpha1.ComponentSpec{
+					WorkloadType:     "ros.aliyun.com/v1alpha1.ECS_VPC",
+					WorkloadSettings: runtime.RawExtension{Raw: []byte(`{"VpcName": 1}`)},
+				},
+			},
+			wantAllowed: false,
+			wantMessage: "Invalid workload settings: $.VpcName: expected string, got number",
+		},
+		{
+			name:      "TestInvalidWorkloadType",
+			operation: admissionv1beta1.Create,
+			obj: &v1alpha1.ComponentSchematic{
+				TypeMeta:   v1.TypeMeta{APIVersion: "core.oam.dev/v1alpha1", Kind: "ComponentSchematic"},
+				ObjectMeta: v1.ObjectMeta{Name: "vpc", Namespace: "default"},
+				Spec:       v1alpha1.ComponentSpec{WorkloadType: "ros.aliyun.com/v1alpha1.VPC"},
+			},
+			wantAllowed: false,
+			wantMessage: "Type 'VPC' in workloadType must be format of {product}_{restype}",
+		},
+		{
+			name:        "TestDelete",
+			operation:   admissionv1beta1.Delete,
+			obj:         newAppConf(nil, invalidRef),
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := review(t, server, tt.operation, tt.obj)
+			assert.Equal(t, tt.wantAllowed, resp.Allowed)
+			if !tt.wantAllowed {
+				assert.Equal(t, tt.wantMessage, string(resp.Result.Reason))
+			}
+		})
+	}
+}
+
+func TestValidator_UnsupportedRegion(t *testing.T) {
+	server := newTestWebhookServer(t)
+	defer server.Close()
+
+	region.Set([]rosapi.Region{{RegionId: "cn-beijing"}, {RegionId: "cn-hangzhou"}})
+	defer region.Set(nil)
+
+	vpc := v1alpha1.ComponentConfiguration{ComponentName: "vpc", InstanceName: "Vpc"}
+	scope := []v1alpha1.ScopeBinding{{
+		Name:       "resource-identity",
+		Type:       "oam.alibaba.dev/v1.ResourceIdentity",
+		Properties: runtime.RawExtension{Raw: []byte(`{"appName": "myapp", "aliyunAccountUid": "123", "regionId": "cn-beijng"}`)},
+	}}
+	resp := review(t, server, admissionv1beta1.Create, newAppConf(scope, vpc))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Region cn-beijng is not supported by ROS. Supported regions: cn-beijing, cn-hangzhou", string(resp.Result.Reason))
+}
+
+func TestValidator_ControllerAccountDenied(t *testing.T) {
+	server := newTestWebhookServer(t)
+	defer server.Close()
+
+	config.RosCtrlConf.ControllerAliUid = "999"
+	config.RosCtrlConf.RegionId = "cn-hangzhou"
+	defer func() {
+		config.RosCtrlConf.ControllerAliUid = ""
+		config.RosCtrlConf.RegionId = ""
+	}()
+
+	// application without resource identity scope uses account of controller credential
+	vpc := v1alpha1.ComponentConfiguration{ComponentName: "vpc", InstanceName: "Vpc"}
+	resp := review(t, server, admissionv1beta1.Create, newAppConf(nil, vpc))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Account 999 in region cn-hangzhou is not allowed for namespace default by account policies", string(resp.Result.Reason))
+}
@@ -0,0 +1,97 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/oam-dev/oam-go-sdk/pkg/oam"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var workloadTypeGVK = k8sschema.GroupVersionKind{Group: "core.oam.dev", Version: "v1alpha1", Kind: "WorkloadType"}
+
+// TypeName takes kind of workload type (e.g. ECS_VPC) and returns the name of WorkloadType (e.g. ecs-vpc).
+func TypeName(kind string) string {
+	return strings.Replace(strings.ToLower(kind), "_", "-", 1)
+}
+
+// KindOf takes workloadType of format {group}/{version}.{kind} and returns its kind (e.g. ECS_VPC).
+func KindOf(workloadType string) string {
+	return workloadType[strings.LastIndex(workloadType, ".")+1:]
+}
+
+// GetSettingsSchema takes namespace and kind of workload type. Returns the JSON Schema of workload settings,
+// nil if the WorkloadType does not exist or carries no schema, and an error if there is any.
+func GetSettingsSchema(namespace, kind string) (*schema.JsonSchema, error) {
+	workloadType, err := get(namespace, kind)
+	if err != nil || workloadType == nil {
+		return nil, err
+	}
+
+	settings, _, _ := unstructured.NestedString(workloadType.Object, "spec", "workloadSettings")
+	if settings == "" {
+		settings, _, _ = unstructured.NestedString(workloadType.Object, "spec", "settings")
+	}
+	if settings == "" {
+		return nil, nil
+	}
+	return schema.Parse(settings)
+}
+
+// GetAttributes takes namespace and kind of workload type. Returns attributes of the ROS resource type in the format
+// of GetResourceType (e.g. {"VpcId": {"Description": "..."}}), nil if the WorkloadType does not exist or carries no
+// attributes, and an error if there is any.
+func GetAttributes(namespace, kind string) (map[string]interface{}, error) {
+	workloadType, err := get(namespace, kind)
+	if err != nil || workloadType == nil {
+		return nil, err
+	}
+
+	attributes, _, _ := unstructured.NestedString(workloadType.Object, "spec", "workloadAttributes")
+	if attributes == "" {
+		return nil, nil
+	}
+	return ParseAttributes(attributes)
+}
+
+// ParseAttributes parses JSON encoded attributes of ROS resource type
+func ParseAttributes(data string) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &attributes); err != nil {
+		return nil, fmt.Errorf("invalid workload attributes: %s", err)
+	}
+	for name, attribute := range attributes {
+		spec, ok := attribute.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid workload attributes: attribute '%s' is not an object", name)
+		}
+		if description, ok := spec["Description"]; ok {
+			if _, ok := description.(string); !ok {
+				return nil, fmt.Errorf("invalid workload attributes: description of attribute '%s' is not a string", name)
+			}
+		}
+	}
+	return attributes, nil
+}
+
+// get returns WorkloadType of kind in namespace, nil if it does not exist
+func get(namespace, kind string) (*unstructured.Unstructured, error) {
+	// WorkloadTypeSpec of oam-go-sdk does not know workloadSettings and workloadAttributes, so read it unstructured
+	workloadType := &unstructured.Unstructured{}
+	workloadType.SetGroupVersionKind(workloadTypeGVK)
+	background := context.Background()
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: TypeName(kind)}
+	if err := oam.GetMgr().GetClient().Get(background, namespacedName, workloadType); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return workloadType, nil
+}
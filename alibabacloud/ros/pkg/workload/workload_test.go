@@ -0,0 +1,100 @@
+package workload
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/oam-dev/cloud-provider/alibabacloud/ros/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "TestNormal",
+			data: `{"VpcId": {"Description": "ID of VPC"}, "VRouterId": {}}`,
+			want: map[string]interface{}{
+				"VpcId":     map[string]interface{}{"Description": "ID of VPC"},
+				"VRouterId": map[string]interface{}{},
+			},
+		},
+		{
+			name:    "TestInvalidJSON",
+			data:    `{"VpcId"}`,
+			wantErr: "invalid workload attributes: invalid character '}' after object key",
+		},
+		{
+			name:    "TestAttributeNotObject",
+			data:    `{"VpcId": "ID of VPC"}`,
+			wantErr: "invalid workload attributes: attribute 'VpcId' is not an object",
+		},
+		{
+			name:    "TestDescriptionNotString",
+			data:    `{"VpcId": {"Description": 1}}`,
+			wantErr: "invalid workload attributes: description of attribute 'VpcId' is not a string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAttributes(tt.data)
+			if tt.wantErr != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestShippedWorkloadTypes parses every workload type shipped in workloads/
+func TestShippedWorkloadTypes(t *testing.T) {
+	files, err := filepath.Glob("../../workloads/*.yml")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			assert.Nil(t, err)
+			workloadType := struct {
+				ApiVersion string `yaml:"apiVersion"`
+				Kind       string `yaml:"kind"`
+				Metadata   struct {
+					Name string `yaml:"name"`
+				} `yaml:"metadata"`
+				Spec struct {
+					Group   string `yaml:"group"`
+					Version string `yaml:"version"`
+					Names   struct {
+						Kind string `yaml:"kind"`
+					} `yaml:"names"`
+					WorkloadSettings   string `yaml:"workloadSettings"`
+					WorkloadAttributes string `yaml:"workloadAttributes"`
+				} `yaml:"spec"`
+			}{}
+			assert.Nil(t, yaml.UnmarshalStrict(data, &workloadType))
+			assert.Equal(t, workloadTypeGVK.GroupVersion().String(), workloadType.ApiVersion)
+			assert.Equal(t, workloadTypeGVK.Kind, workloadType.Kind)
+			assert.Equal(t, "ros.aliyun.com", workloadType.Spec.Group)
+			assert.Equal(t, "v1alpha1", workloadType.Spec.Version)
+			assert.Equal(t, TypeName(workloadType.Spec.Names.Kind), workloadType.Metadata.Name)
+			assert.Equal(t, workloadType.Spec.Names.Kind+".yml", filepath.Base(file))
+
+			settingsSchema, err := schema.Parse(workloadType.Spec.WorkloadSettings)
+			assert.Nil(t, err)
+			assert.Equal(t, "object", settingsSchema.Type)
+			if workloadType.Spec.WorkloadAttributes != "" {
+				_, err = ParseAttributes(workloadType.Spec.WorkloadAttributes)
+				assert.Nil(t, err)
+			}
+		})
+	}
+}